@@ -0,0 +1,125 @@
+// Package abtest assigns visitors to experiment variants and keeps the
+// assignment in a martini-contrib session, so a visitor stays in the same
+// variant across requests.
+//
+//	m.Get("/checkout", func(s sessions.Session) string {
+//		t := abtest.New(s, "my_session")
+//		if t.Experiment("checkout_v2", abtest.Weights{"control": 90, "v2": 10}) == "v2" {
+//			...
+//		}
+//	})
+package abtest
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"hash/fnv"
+	"sort"
+
+	"github.com/martini-contrib/sessions"
+)
+
+const (
+	seedKey        = "_ab_seed"
+	assignmentsKey = "_ab"
+)
+
+func init() {
+	gob.Register(map[string]string{})
+}
+
+// Weights maps variant names to their relative weight.
+type Weights map[string]int
+
+// Options configures a Tester.
+type Options struct {
+	// OnExposure, when set, is called every time a visitor is exposed to an
+	// experiment, with the variant they were shown.
+	OnExposure func(experiment, variant string)
+}
+
+// Tester buckets the visitor of a session into experiments.
+type Tester struct {
+	session sessions.Session
+	name    string
+	opts    Options
+}
+
+// New returns a Tester keeping assignments in the named session.
+func New(s sessions.Session, name string, options ...Options) *Tester {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return &Tester{session: s, name: name, opts: opts}
+}
+
+// Experiment returns the variant of experiment assigned to the visitor. The
+// first assignment is derived deterministically from a per-session seed and
+// the experiment name, and is then stored so later changes to weights don't
+// move visitors between variants. It returns an empty string when weights
+// holds no positive weight.
+func (t *Tester) Experiment(experiment string, weights Weights) string {
+	assignments, _ := t.session.Get(t.name, assignmentsKey).(map[string]string)
+	variant, ok := assignments[experiment]
+	if !ok {
+		variant = pick(t.seed()+":"+experiment, weights)
+		if variant == "" {
+			return ""
+		}
+		if assignments == nil {
+			assignments = make(map[string]string)
+		}
+		assignments[experiment] = variant
+		t.session.Set(t.name, assignmentsKey, assignments)
+	}
+
+	if t.opts.OnExposure != nil {
+		t.opts.OnExposure(experiment, variant)
+	}
+	return variant
+}
+
+// Assignments returns all experiment assignments of the visitor.
+func (t *Tester) Assignments() map[string]string {
+	assignments, _ := t.session.Get(t.name, assignmentsKey).(map[string]string)
+	return assignments
+}
+
+func (t *Tester) seed() string {
+	seed, _ := t.session.Get(t.name, seedKey).(string)
+	if seed == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		seed = hex.EncodeToString(b)
+		t.session.Set(t.name, seedKey, seed)
+	}
+	return seed
+}
+
+func pick(key string, weights Weights) string {
+	variants := make([]string, 0, len(weights))
+	total := 0
+	for v, w := range weights {
+		if w > 0 {
+			variants = append(variants, v)
+			total += w
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Strings(variants)
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	n := int(h.Sum64() % uint64(total))
+	for _, v := range variants {
+		if n < weights[v] {
+			return v
+		}
+		n -= weights[v]
+	}
+	return variants[len(variants)-1]
+}
@@ -0,0 +1,57 @@
+package abtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+func Test_Experiment(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+
+	var variants []string
+	exposures := 0
+	m.Get("/", func(s sessions.Session) string {
+		tester := New(s, "my_session", Options{OnExposure: func(string, string) { exposures++ }})
+		variants = append(variants, tester.Experiment("checkout_v2", Weights{"control": 50, "v2": 50}))
+		return "OK"
+	})
+
+	cookie := ""
+	for i := 0; i < 5; i++ {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		m.ServeHTTP(res, req)
+		if c := res.Header().Get("Set-Cookie"); c != "" {
+			cookie = c
+		}
+	}
+
+	for _, v := range variants {
+		if v != variants[0] || v == "" {
+			t.Fatal("Variant changed between requests:", variants)
+		}
+	}
+	if exposures != 5 {
+		t.Error("Expected 5 exposures, got", exposures)
+	}
+}
+
+func Test_PickIsDeterministic(t *testing.T) {
+	w := Weights{"a": 1, "b": 1, "c": 0}
+	for i := 0; i < 10; i++ {
+		if pick("seed:exp", w) != pick("seed:exp", w) {
+			t.Fatal("pick is not deterministic")
+		}
+	}
+	if pick("seed:exp", Weights{"c": 0}) != "" {
+		t.Error("Zero weights should not assign a variant")
+	}
+}
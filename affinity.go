@@ -0,0 +1,31 @@
+package sessions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-martini/martini"
+)
+
+// Affinity is a Middleware that sets a response header carrying a short hash
+// of the named session's ID, so L7 load balancers can route a visitor to the
+// same instance consistently. The hash can't be turned back into the ID.
+// Stores that don't assign IDs, like the CookieStore, and sessions not saved
+// yet get no header. header defaults to "X-Session-Affinity". It must be used
+// after Sessions.
+func Affinity(name, header string) martini.Handler {
+	if header == "" {
+		header = "X-Session-Affinity"
+	}
+	return func(res http.ResponseWriter, s Session) {
+		if id := s.ID(name); id != "" {
+			res.Header().Set(header, affinityHash(id))
+		}
+	}
+}
+
+func affinityHash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/gorilla/sessions"
+)
+
+type fixedIDStore struct {
+	CookieStore
+}
+
+func (f fixedIDStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := f.CookieStore.Get(r, name)
+	s.ID = "session-id"
+	return s, err
+}
+
+func Test_Affinity(t *testing.T) {
+	m := martini.Classic()
+
+	m.Use(Sessions(fixedIDStore{NewCookieStore([]byte("secret123"))}))
+	m.Use(Affinity("my_session", ""))
+	m.Get("/", func() string {
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+
+	h := res.Header().Get("X-Session-Affinity")
+	if h != affinityHash("session-id") || h == "session-id" {
+		t.Error("Unexpected affinity header:", h)
+	}
+}
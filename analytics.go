@@ -0,0 +1,192 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+const analyticsKey = "_analytics_id"
+
+// AnalyticsStats is a snapshot of the metrics aggregated by Analytics.
+type AnalyticsStats struct {
+	// Started and Ended count sessions since the aggregator was created.
+	Started int64
+	Ended   int64
+	// Active is the number of sessions seen within the idle timeout.
+	Active int64
+	// AvgDuration and AvgPages are averaged over ended sessions.
+	AvgDuration time.Duration
+	AvgPages    float64
+	// Bounces counts ended sessions that viewed a single page, and
+	// BounceRate is their share of ended sessions.
+	Bounces    int64
+	BounceRate float64
+}
+
+// AnalyticsSink receives periodic snapshots from Analytics.Export.
+type AnalyticsSink interface {
+	Observe(AnalyticsStats)
+}
+
+// Analytics aggregates session durations, pages per session and bounces
+// from the requests passing through its Handler. A session ends once it has
+// been idle for the configured timeout, give or take a sixteenth of it.
+type Analytics struct {
+	width int64
+
+	mu     sync.Mutex
+	visits map[string]*visit
+	// buckets files the visits under the time bucket of their last page
+	// view, so ending idle visits only looks at the buckets leaving the
+	// idle window, as for the memory Capacity tracker.
+	buckets [trackerBuckets + 1]map[string]*visit
+	// current is the number of the newest bucket, counted from the epoch.
+	current       int64
+	started       int64
+	ended         int64
+	totalDuration time.Duration
+	totalPages    int64
+	bounces       int64
+}
+
+type visit struct {
+	start  time.Time
+	last   time.Time
+	pages  int64
+	bucket int64
+}
+
+// NewAnalytics returns an Analytics ending sessions after idle.
+func NewAnalytics(idle time.Duration) *Analytics {
+	width := int64(idle / trackerBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	return &Analytics{width: width, visits: make(map[string]*visit)}
+}
+
+// Handler returns a Middleware counting a page view for the named session on
+// every GET request. It must be used after Sessions.
+func (a *Analytics) Handler(name string) martini.Handler {
+	return func(r *http.Request, s Session) {
+		if r.Method != "GET" {
+			return
+		}
+
+		id, _ := s.Get(name, analyticsKey).(string)
+		now := time.Now()
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.advance(now)
+
+		v, ok := a.visits[id]
+		if ok {
+			delete(a.buckets[v.bucket%int64(len(a.buckets))], id)
+		} else {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+			s.Set(name, analyticsKey, id)
+
+			v = &visit{start: now}
+			a.visits[id] = v
+			a.started++
+		}
+		v.last = now
+		v.pages++
+		v.bucket = a.current
+
+		slot := a.current % int64(len(a.buckets))
+		if a.buckets[slot] == nil {
+			a.buckets[slot] = make(map[string]*visit)
+		}
+		a.buckets[slot][id] = v
+	}
+}
+
+// Stats returns the current metrics.
+func (a *Analytics) Stats() AnalyticsStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.advance(time.Now())
+
+	stats := AnalyticsStats{
+		Started: a.started,
+		Ended:   a.ended,
+		Active:  int64(len(a.visits)),
+		Bounces: a.bounces,
+	}
+	if a.ended > 0 {
+		stats.AvgDuration = a.totalDuration / time.Duration(a.ended)
+		stats.AvgPages = float64(a.totalPages) / float64(a.ended)
+		stats.BounceRate = float64(a.bounces) / float64(a.ended)
+	}
+	return stats
+}
+
+// Export sends a snapshot to sink every interval until stop is called.
+func (a *Analytics) Export(sink AnalyticsSink, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sink.Observe(a.Stats())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// PrometheusHandler serves the metrics in the Prometheus text format.
+func (a *Analytics) PrometheusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := a.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintf(w, "# TYPE sessions_started_total counter\nsessions_started_total %d\n", s.Started)
+		fmt.Fprintf(w, "# TYPE sessions_ended_total counter\nsessions_ended_total %d\n", s.Ended)
+		fmt.Fprintf(w, "# TYPE sessions_bounced_total counter\nsessions_bounced_total %d\n", s.Bounces)
+		fmt.Fprintf(w, "# TYPE sessions_active gauge\nsessions_active %d\n", s.Active)
+		fmt.Fprintf(w, "# TYPE sessions_duration_seconds_avg gauge\nsessions_duration_seconds_avg %g\n", s.AvgDuration.Seconds())
+		fmt.Fprintf(w, "# TYPE sessions_pages_avg gauge\nsessions_pages_avg %g\n", s.AvgPages)
+	}
+}
+
+// advance ends the visits in the buckets that have left the idle window.
+// The caller must hold a.mu.
+func (a *Analytics) advance(now time.Time) {
+	n := now.UnixNano() / a.width
+	if n-a.current > int64(len(a.buckets)) {
+		a.current = n - int64(len(a.buckets))
+	}
+	for a.current < n {
+		a.current++
+		a.end(a.current)
+	}
+}
+
+// end ends the visits in the bucket numbered n and empties it.
+func (a *Analytics) end(n int64) {
+	slot := n % int64(len(a.buckets))
+	for id, v := range a.buckets[slot] {
+		delete(a.visits, id)
+		a.ended++
+		a.totalDuration += v.last.Sub(v.start)
+		a.totalPages += v.pages
+		if v.pages == 1 {
+			a.bounces++
+		}
+	}
+	a.buckets[slot] = nil
+}
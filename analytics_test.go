@@ -0,0 +1,51 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Analytics(t *testing.T) {
+	m := martini.Classic()
+
+	analytics := NewAnalytics(20 * time.Millisecond)
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(analytics.Handler("my_session"))
+	m.Get("/", func() string {
+		return "OK"
+	})
+
+	visit := func(cookie string) string {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		return res.Header().Get("Set-Cookie")
+	}
+
+	cookie := visit("")
+	visit(cookie)
+	visit(cookie)
+	visit("")
+
+	if s := analytics.Stats(); s.Started != 2 || s.Active != 2 {
+		t.Error("Unexpected live stats:", s)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	s := analytics.Stats()
+	if s.Ended != 2 || s.AvgPages != 2 || s.Bounces != 1 || s.BounceRate != 0.5 {
+		t.Error("Unexpected stats after sessions ended:", s)
+	}
+
+	res := httptest.NewRecorder()
+	analytics.PrometheusHandler()(res, nil)
+	if !strings.Contains(res.Body.String(), "sessions_ended_total 2") {
+		t.Error("Unexpected Prometheus output:", res.Body.String())
+	}
+}
@@ -0,0 +1,161 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BackupStore is implemented by persistent stores able to stream a snapshot
+// of all their sessions and to load one back. Server-side stores implement
+// it when their backend is a Scanner.
+type BackupStore interface {
+	// Backup writes a snapshot of every session to w. When key is given, a
+	// 16, 24 or 32 byte AES key, every record is encrypted with AES-GCM.
+	Backup(w io.Writer, key ...[]byte) error
+	// Restore loads a snapshot written by Backup, using the same key.
+	// Sessions that expired since the snapshot was taken are skipped, the
+	// others keep their original expiry.
+	Restore(r io.Reader, key ...[]byte) error
+}
+
+// ErrBackupUnsupported is returned by the Backup of server-side stores whose
+// backend is not a Scanner.
+var ErrBackupUnsupported = errors.New("sessions: store does not support backups")
+
+// maxBackupRecord is the size of the largest record read from a backup, so
+// that a corrupt or hostile stream can't make Restore allocate at will.
+const maxBackupRecord = 16 << 20
+
+// backupRecord is a single session in a backup stream. Expires is zero for
+// sessions without expiry.
+type backupRecord struct {
+	ID      string
+	Data    []byte
+	Expires time.Time
+}
+
+// backupWriter writes length-prefixed, optionally encrypted records.
+type backupWriter struct {
+	w   io.Writer
+	key []byte
+}
+
+func (b *backupWriter) write(rec backupRecord) error {
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if b.key != nil {
+		gcm, err := newGCM(b.key)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		data = gcm.Seal(nonce, nonce, data, nil)
+	}
+
+	if err := binary.Write(b.w, binary.BigEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = b.w.Write(data)
+	return err
+}
+
+// backupReader reads records written by backupWriter. It returns io.EOF
+// after the last record.
+type backupReader struct {
+	r   io.Reader
+	key []byte
+}
+
+func (b *backupReader) read() (backupRecord, error) {
+	var rec backupRecord
+	var n uint32
+	if err := binary.Read(b.r, binary.BigEndian, &n); err != nil {
+		return rec, err
+	}
+	if n > maxBackupRecord {
+		return rec, fmt.Errorf("sessions: backup record of %d bytes exceeds the limit of %d", n, maxBackupRecord)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(b.r, data); err != nil {
+		return rec, err
+	}
+
+	if b.key != nil {
+		gcm, err := newGCM(b.key)
+		if err != nil {
+			return rec, err
+		}
+		if len(data) < gcm.NonceSize() {
+			return rec, errors.New("sessions: corrupt backup record")
+		}
+		data, err = gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+		if err != nil {
+			return rec, err
+		}
+	}
+	return rec, decodeRecord(data, &rec)
+}
+
+func encodeRecord(rec backupRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(rec)
+	return buf.Bytes(), err
+}
+
+func decodeRecord(data []byte, rec *backupRecord) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(rec)
+}
+
+func backupKey(key [][]byte) []byte {
+	if len(key) == 0 {
+		return nil
+	}
+	return key[0]
+}
+
+func (s *ServerStore) Backup(w io.Writer, key ...[]byte) error {
+	sc, ok := s.backend.(Scanner)
+	if !ok {
+		return ErrBackupUnsupported
+	}
+	bw := &backupWriter{w: w, key: backupKey(key)}
+	return sc.Scan(func(id string, data []byte, expires time.Time) error {
+		return bw.write(backupRecord{ID: id, Data: data, Expires: expires})
+	})
+}
+
+func (s *ServerStore) Restore(r io.Reader, key ...[]byte) error {
+	br := &backupReader{r: r, key: backupKey(key)}
+	for {
+		rec, err := br.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !validID(rec.ID) {
+			return errors.New("sessions: corrupt backup record")
+		}
+
+		ttl := defaultTTL
+		if !rec.Expires.IsZero() {
+			if ttl = time.Until(rec.Expires); ttl <= 0 {
+				continue
+			}
+		}
+		if err := s.backend.Save(rec.ID, rec.Data, ttl); err != nil {
+			return err
+		}
+	}
+}
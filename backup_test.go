@@ -0,0 +1,45 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_BackupStream(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	expires := time.Now().Add(time.Hour).Round(0)
+
+	var buf bytes.Buffer
+	bw := &backupWriter{w: &buf, key: key}
+	bw.write(backupRecord{ID: "a", Data: []byte("session a"), Expires: expires})
+	bw.write(backupRecord{ID: "b", Data: []byte("session b")})
+
+	if bytes.Contains(buf.Bytes(), []byte("session a")) {
+		t.Fatal("Encrypted backup contains plain text")
+	}
+
+	br := &backupReader{r: &buf, key: key}
+	a, err := br.read()
+	if err != nil || a.ID != "a" || string(a.Data) != "session a" || !a.Expires.Equal(expires) {
+		t.Error("Unexpected first record:", a, err)
+	}
+	b, err := br.read()
+	if err != nil || b.ID != "b" || !b.Expires.IsZero() {
+		t.Error("Unexpected second record:", b, err)
+	}
+	if _, err := br.read(); err != io.EOF {
+		t.Error("Expected io.EOF after the last record, got", err)
+	}
+}
+
+func Test_BackupRecordLimit(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(maxBackupRecord+1))
+	br := &backupReader{r: &buf}
+	if _, err := br.read(); err == nil || err == io.ErrUnexpectedEOF {
+		t.Error("Oversized record was not rejected:", err)
+	}
+}
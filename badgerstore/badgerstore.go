@@ -0,0 +1,178 @@
+// Package badgerstore provides a BadgerDB based storage for martini-contrib
+// sessions.
+//
+//	store, err := badgerstore.New("/var/lib/app/sessions")
+//	m.Use(sessions.Sessions(store))
+//
+// Importing the package registers the badger:// scheme of
+// sessions.OpenStore, e.g. badger:///var/lib/app/sessions.
+package badgerstore
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/martini-contrib/sessions"
+)
+
+func init() {
+	sessions.RegisterScheme("badger", open)
+}
+
+func open(u *url.URL, keyPairs ...[]byte) (sessions.Store, error) {
+	return New(u.Host + u.Path)
+}
+
+// badgerGCInterval is how often the value log of a Store is garbage
+// collected.
+const badgerGCInterval = 5 * time.Minute
+
+// Store is an interface that represents a BadgerDB based storage for
+// Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+	// Close stops the background goroutine and closes the database.
+	Close() error
+}
+
+// New returns a new Store keeping sessions in the BadgerDB database in the
+// directory path.
+//
+// Sessions are written with Badger's native TTL derived from
+// Options.MaxAge, so expired sessions are never read and are dropped by
+// compaction. A background goroutine garbage collects the value log every
+// five minutes.
+func New(path string) (Store, error) {
+	db, err := badger.Open(badger.DefaultOptions(path).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &badgerBackend{db: db, quit: make(chan struct{})}
+	go backend.run()
+	return &badgerStore{sessions.NewServerStore(backend), backend}, nil
+}
+
+type badgerStore struct {
+	*sessions.ServerStore
+	backend *badgerBackend
+}
+
+func (s *badgerStore) Close() error {
+	close(s.backend.quit)
+	return s.backend.db.Close()
+}
+
+type badgerBackend struct {
+	db   *badger.DB
+	quit chan struct{}
+}
+
+func (b *badgerBackend) Load(id string) ([]byte, error) {
+	var data []byte
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("session_" + id))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		data, err = item.ValueCopy(nil)
+		return err
+	})
+	return data, err
+}
+
+func (b *badgerBackend) Save(id string, data []byte, ttl time.Duration) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte("session_"+id), data).WithTTL(ttl))
+	})
+}
+
+func (b *badgerBackend) Touch(id string, ttl time.Duration) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("session_" + id))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		data, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		// the transaction fails if the session is saved meanwhile
+		return txn.SetEntry(badger.NewEntry([]byte("session_"+id), data).WithTTL(ttl))
+	})
+}
+
+func (b *badgerBackend) Delete(id string) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte("session_" + id))
+	})
+}
+
+func (b *badgerBackend) Scan(fn func(id string, data []byte, expires time.Time) error) error {
+	type entry struct {
+		id      string
+		data    []byte
+		expires time.Time
+	}
+	var entries []entry
+	err := b.db.View(func(txn *badger.Txn) error {
+		prefix := []byte("session_")
+		it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, Prefix: prefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			e := entry{id: strings.TrimPrefix(string(item.Key()), string(prefix)), data: data}
+			if at := item.ExpiresAt(); at > 0 {
+				e.expires = time.Unix(int64(at), 0)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// fn may write, which conflicts with the read transaction
+	for _, e := range entries {
+		if err := fn(e.id, e.data, e.expires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *badgerBackend) DeletePrefix(prefix string) error {
+	return b.db.DropPrefix([]byte("session_" + prefix))
+}
+
+func (b *badgerBackend) run() {
+	ticker := time.NewTicker(badgerGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// collect until there is nothing left worth rewriting
+			for b.db.RunValueLogGC(0.5) == nil {
+			}
+		case <-b.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package badgerstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_BadgerStore(t *testing.T) {
+	store, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, err = store.New(req, "my_session")
+	if err != nil || s.IsNew || s.Values["hello"] != "world" {
+		t.Error("Session was not loaded back:", s.Values, err)
+	}
+}
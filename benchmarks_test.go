@@ -25,7 +25,7 @@ func BenchmarkNoSessionsMiddleware(b *testing.B) {
 func BenchmarkSessionsNoWrites(b *testing.B) {
 	m := testMartini()
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 	m.Get("/foo", func() string {
 		return "Foo"
 	})
@@ -42,9 +42,9 @@ func BenchmarkSessionsNoWrites(b *testing.B) {
 func BenchmarkSessionsWithWrite(b *testing.B) {
 	m := testMartini()
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 	m.Get("/foo", func(s Session) string {
-		s.Set("foo", "bar")
+		s.Set("my_session", "foo", "bar")
 		return "Foo"
 	})
 
@@ -60,9 +60,9 @@ func BenchmarkSessionsWithWrite(b *testing.B) {
 func BenchmarkSessionsWithRead(b *testing.B) {
 	m := testMartini()
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 	m.Get("/foo", func(s Session) string {
-		s.Get("foo")
+		s.Get("my_session", "foo")
 		return "Foo"
 	})
 
@@ -0,0 +1,103 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// bindKey returns the session key of a struct field: the name given in its
+// `session` tag, or the field name. Unexported fields and fields tagged "-"
+// are skipped.
+func bindKey(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	switch tag := f.Tag.Get("session"); tag {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	default:
+		return tag, true
+	}
+}
+
+func (s *session) Store(name string, src interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(src))
+	if rv.Kind() != reflect.Struct {
+		return errors.New("sessions: Store expects a struct")
+	}
+
+	values := make(map[interface{}]interface{})
+	for i := 0; i < rv.NumField(); i++ {
+		if key, ok := bindKey(rv.Type().Field(i)); ok {
+			values[key] = rv.Field(i).Interface()
+		}
+	}
+	s.SetAll(name, values)
+	return nil
+}
+
+func (s *session) Bind(name string, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("sessions: Bind expects a pointer to a struct")
+	}
+	rv = rv.Elem()
+
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		key, ok := bindKey(f)
+		if !ok {
+			continue
+		}
+		val, ok := s.GetOk(name, key)
+		if !ok || val == nil {
+			continue
+		}
+		fv, ok := bindValue(reflect.ValueOf(val), f.Type)
+		if !ok {
+			return fmt.Errorf("sessions: key %s holds %T, not %s", key, val, f.Type)
+		}
+		rv.Field(i).Set(fv)
+	}
+	return nil
+}
+
+// bindValue returns v as a value of type t. Only values assignable to t and
+// numbers converting to other numeric types are accepted, so e.g. an int
+// never turns into a string. Integers must be converted without loss.
+func bindValue(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if v.Type().AssignableTo(t) {
+		return v, true
+	}
+	if !isNumber(v.Kind()) || !isNumber(t.Kind()) || changesSign(v, t) {
+		return reflect.Value{}, false
+	}
+	c := v.Convert(t)
+	if t.Kind() < reflect.Float32 && c.Convert(v.Type()).Interface() != v.Interface() {
+		// overflowed or dropped a fraction
+		return reflect.Value{}, false
+	}
+	return c, true
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// changesSign reports whether converting the number v to t flips its sign,
+// which the round trip check of bindValue misses for same sized integers.
+func changesSign(v reflect.Value, t reflect.Type) bool {
+	unsigned := t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr
+	switch k := v.Kind(); {
+	case k >= reflect.Int && k <= reflect.Int64:
+		return unsigned && v.Int() < 0
+	case k >= reflect.Uint && k <= reflect.Uintptr:
+		return !unsigned && t.Kind() < reflect.Float32 && v.Uint() > math.MaxInt64
+	default:
+		return unsigned && v.Float() < 0
+	}
+}
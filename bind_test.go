@@ -0,0 +1,111 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+type bindAddress struct {
+	City string
+}
+
+type bindProfile struct {
+	Name    string `session:"user_name"`
+	Age     int
+	Address bindAddress
+	Token   string `session:"-"`
+}
+
+func Test_SessionsBind(t *testing.T) {
+	gob.Register(bindAddress{})
+
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		p := bindProfile{Name: "bob", Age: 42, Address: bindAddress{City: "Oslo"}, Token: "secret"}
+		if err := session.Store("my_session", &p); err != nil {
+			t.Error("Storing the struct failed:", err)
+		}
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "user_name") != "bob" {
+			t.Error("Field tag was not honored")
+		}
+		var p bindProfile
+		if err := session.Bind("my_session", &p); err != nil {
+			t.Error("Binding the struct failed:", err)
+		}
+		if p.Name != "bob" || p.Age != 42 || p.Address.City != "Oslo" || p.Token != "" {
+			t.Error("Struct was not bound:", p)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
+
+func Test_SessionsBindConversions(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		var p struct {
+			Count int8
+			Ratio float32
+		}
+		session.Set("my_session", "Count", 42.0)
+		session.Set("my_session", "Ratio", 1)
+		if err := session.Bind("my_session", &p); err != nil || p.Count != 42 || p.Ratio != 1 {
+			t.Error("Numbers were not converted:", p, err)
+		}
+
+		for _, val := range []interface{}{300, 1.5} {
+			session.Set("my_session", "Count", val)
+			if err := session.Bind("my_session", &p); err == nil {
+				t.Error("Lossy conversion was accepted:", val)
+			}
+		}
+
+		var signed struct {
+			Unsigned uint
+			Signed   int64
+		}
+		for key, val := range map[string]interface{}{"Unsigned": -1, "Signed": uint64(math.MaxUint64)} {
+			session.Set("my_session", key, val)
+			if err := session.Bind("my_session", &signed); err == nil {
+				t.Error("Conversion changing the sign was accepted:", key, signed)
+			}
+			session.Delete("my_session", key)
+		}
+
+		var named struct{ Name string }
+		session.Set("my_session", "Name", 65)
+		if err := session.Bind("my_session", &named); err == nil {
+			t.Error("Number was converted to a string:", named.Name)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
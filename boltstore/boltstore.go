@@ -0,0 +1,287 @@
+// Package boltstore provides a BoltDB based storage for martini-contrib
+// sessions.
+//
+//	store, err := boltstore.New("/var/lib/app/sessions.db", "sessions")
+//	m.Use(sessions.Sessions(store))
+//
+// Importing the package registers the bolt:// scheme of sessions.OpenStore,
+// e.g. bolt:///var/lib/app/sessions.db?bucket=sessions.
+package boltstore
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	bolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	sessions.RegisterScheme("bolt", open)
+}
+
+func open(u *url.URL, keyPairs ...[]byte) (sessions.Store, error) {
+	bucket := u.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "sessions"
+	}
+	return New(u.Host+u.Path, bucket)
+}
+
+const (
+	// boltSweepInterval is how often expired sessions are deleted.
+	boltSweepInterval = 5 * time.Minute
+	// boltCompactInterval is how often the database file is compacted.
+	boltCompactInterval = 24 * time.Hour
+)
+
+// Store is an interface that represents a BoltDB based storage for Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+	// Compact rewrites the database file, giving back the space freed by
+	// deleted sessions. It runs daily by itself.
+	Compact() error
+	// Close stops the background goroutine and closes the database.
+	Close() error
+}
+
+// New returns a new Store keeping sessions in bucket of the BoltDB database
+// at path, which is created if it does not exist yet.
+//
+// A background goroutine deletes expired sessions every five minutes and
+// compacts the database once a day.
+func New(path, bucket string) (Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	backend := &boltBackend{db: db, path: path, bucket: []byte(bucket), quit: make(chan struct{})}
+	store := &boltStore{ServerStore: sessions.NewServerStore(backend), backend: backend}
+	store.stop = store.StartCleanup(boltSweepInterval)
+	go backend.run()
+	return store, nil
+}
+
+type boltStore struct {
+	*sessions.ServerStore
+	backend *boltBackend
+	stop    func()
+}
+
+func (s *boltStore) Compact() error {
+	return s.backend.compact()
+}
+
+func (s *boltStore) Close() error {
+	s.stop()
+	close(s.backend.quit)
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+	return s.backend.db.Close()
+}
+
+// boltBackend stores each session as written by WithExpiry.
+type boltBackend struct {
+	// mu guards db, which is swapped out by compact.
+	mu     sync.RWMutex
+	db     *bolt.DB
+	path   string
+	bucket []byte
+	quit   chan struct{}
+}
+
+func (b *boltBackend) Load(id string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var data []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data = sessions.UnlessExpired(tx.Bucket(b.bucket).Get([]byte(id)))
+		return nil
+	})
+	return data, err
+}
+
+func (b *boltBackend) Save(id string, data []byte, ttl time.Duration) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Put([]byte(id), sessions.WithExpiry(data, ttl))
+	})
+}
+
+func (b *boltBackend) Swap(id string, old, data []byte, ttl time.Duration) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	swapped := false
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		if !bytes.Equal(sessions.UnlessExpired(bucket.Get([]byte(id))), old) {
+			return nil
+		}
+		swapped = true
+		return bucket.Put([]byte(id), sessions.WithExpiry(data, ttl))
+	})
+	return swapped && err == nil, err
+}
+
+func (b *boltBackend) Touch(id string, ttl time.Duration) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		data := sessions.UnlessExpired(bucket.Get([]byte(id)))
+		if data == nil {
+			return nil
+		}
+		return bucket.Put([]byte(id), sessions.WithExpiry(data, ttl))
+	})
+}
+
+func (b *boltBackend) Delete(id string) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Delete([]byte(id))
+	})
+}
+
+func (b *boltBackend) DeletePrefix(prefix string) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.bucket).Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBackend) run() {
+	compact := time.NewTicker(boltCompactInterval)
+	defer compact.Stop()
+
+	for {
+		select {
+		case <-compact.C:
+			b.compact()
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+func (b *boltBackend) Scan(fn func(id string, data []byte, expires time.Time) error) error {
+	b.mu.RLock()
+	type entry struct {
+		id      string
+		data    []byte
+		expires time.Time
+	}
+	var entries []entry
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).ForEach(func(k, v []byte) error {
+			if data := sessions.UnlessExpired(v); data != nil {
+				entries = append(entries, entry{string(k), data, sessions.ExpiresAt(v)})
+			}
+			return nil
+		})
+	})
+	b.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// fn may write to the database, so it runs outside of the transaction
+	for _, e := range entries {
+		if err := fn(e.id, e.data, e.expires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *boltBackend) PurgeExpired() ([]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var ids []string
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		ids = nil
+		c := tx.Bucket(b.bucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if sessions.UnlessExpired(v) == nil {
+				id := string(k)
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// compact copies the database into a fresh file and swaps it in.
+func (b *boltBackend) compact() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tmp := b.path + ".compact"
+	dst, err := bolt.Open(tmp, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, b.db, 1<<20); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := b.db.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	renameErr := os.Rename(tmp, b.path)
+	if renameErr != nil {
+		// the original file is untouched, go on using it
+		os.Remove(tmp)
+	}
+	b.db, err = bolt.Open(b.path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	return renameErr
+}
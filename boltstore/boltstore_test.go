@@ -0,0 +1,34 @@
+package boltstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func Test_BoltStore(t *testing.T) {
+	store, err := New(filepath.Join(t.TempDir(), "sessions.db"), "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal("Compact failed:", err)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, err = store.New(req, "my_session")
+	if err != nil || s.IsNew || s.Values["hello"] != "world" {
+		t.Error("Session did not survive compaction:", s.Values, err)
+	}
+}
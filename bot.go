@@ -0,0 +1,60 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+const botKey = "_bot"
+
+func init() {
+	gob.Register(BotVerdict{})
+}
+
+// BotVerdict is the outcome of classifying a client as human or automated.
+type BotVerdict struct {
+	Bot    bool
+	Score  float64
+	Reason string
+	// Classified is when the verdict was made.
+	Classified time.Time
+}
+
+// BotClassifier decides whether a request comes from a bot.
+type BotClassifier interface {
+	Classify(r *http.Request) (BotVerdict, error)
+}
+
+// ClassifyBots is a Middleware that caches the verdict of classifier in the
+// named session and maps it as a BotVerdict into the Martini handler chain,
+// so later handlers can branch on it cheaply. The classifier is only called
+// again once the verdict is older than ttl or after Reclassify. It must be
+// used after Sessions.
+func ClassifyBots(name string, classifier BotClassifier, ttl time.Duration) martini.Handler {
+	return func(r *http.Request, s Session, c martini.Context, l *log.Logger) {
+		verdict, ok := s.Get(name, botKey).(BotVerdict)
+		if !ok || time.Since(verdict.Classified) > ttl {
+			v, err := classifier.Classify(r)
+			if err == nil {
+				v.Classified = time.Now()
+				verdict = v
+				s.Set(name, botKey, verdict)
+			} else {
+				check(err, l)
+			}
+		}
+
+		c.Map(verdict)
+	}
+}
+
+// Reclassify drops the cached verdict of the named session, so the next
+// request passing through ClassifyBots is classified again. Call it when a
+// visitor's behaviour changes, e.g. after solving a CAPTCHA.
+func Reclassify(s Session, name string) {
+	s.Delete(name, botKey)
+}
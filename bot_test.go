@@ -0,0 +1,53 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+type userAgentClassifier int
+
+func (c *userAgentClassifier) Classify(r *http.Request) (BotVerdict, error) {
+	*c++
+	return BotVerdict{Bot: r.UserAgent() == "crawler"}, nil
+}
+
+func Test_ClassifyBots(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	classifier := new(userAgentClassifier)
+	m.Use(Sessions(store))
+	m.Use(ClassifyBots("my_session", classifier, time.Hour))
+
+	m.Get("/", func(v BotVerdict) string {
+		if !v.Bot {
+			t.Error("Crawler was not flagged")
+		}
+		return "OK"
+	})
+	m.Get("/reset", func(s Session) string {
+		Reclassify(s, "my_session")
+		return "OK"
+	})
+
+	cookie := ""
+	for _, path := range []string{"/", "/", "/reset", "/"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "crawler")
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		if c := res.Header().Get("Set-Cookie"); c != "" {
+			cookie = c
+		}
+	}
+
+	if *classifier != 2 {
+		t.Error("Expected 2 classifications, got", *classifier)
+	}
+}
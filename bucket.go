@@ -0,0 +1,56 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"fmt"
+)
+
+func init() {
+	gob.Register(bucketKey{})
+}
+
+// bucketKey is the key a value set through a bucket is stored under.
+type bucketKey struct {
+	Bucket string
+	Key    interface{}
+}
+
+func (k bucketKey) String() string {
+	return k.Bucket + "." + fmt.Sprint(k.Key)
+}
+
+// Bucket returns a copy of s sharing its sessions, that maps all keys into
+// the bucket. Buckets of buckets nest their names.
+func (s *session) Bucket(bucket string) Session {
+	b := *s
+	if s.bucket != "" {
+		bucket = s.bucket + "." + bucket
+	}
+	b.bucket = bucket
+	return &b
+}
+
+// key returns the key k is stored under.
+func (s *session) key(k interface{}) interface{} {
+	if s.bucket == "" {
+		return k
+	}
+	return bucketKey{Bucket: s.bucket, Key: k}
+}
+
+// values returns the stored values of the named session that belong to the
+// bucket of s, keyed as seen from the bucket. Without a bucket these are all
+// values but the metadata of the middleware.
+func (s *session) values(name string) map[interface{}]interface{} {
+	values := make(map[interface{}]interface{})
+	for k, val := range s.Session(name).Values {
+		if s.bucket == "" {
+			if _, ok := k.(metadataKey); !ok {
+				values[k] = val
+			}
+		} else if bk, ok := k.(bucketKey); ok && bk.Bucket == s.bucket {
+			values[bk.Key] = val
+		}
+	}
+	return values
+}
@@ -0,0 +1,53 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_SessionsBucket(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		session.Set("my_session", "id", "root")
+		session.Bucket("cart").Set("my_session", "id", "cart-1")
+		session.Bucket("auth").Set("my_session", "id", "user-1")
+		session.Bucket("auth").AddFlash("my_session", "welcome")
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		cart, auth := session.Bucket("cart"), session.Bucket("auth")
+		if session.Get("my_session", "id") != "root" || cart.Get("my_session", "id") != "cart-1" ||
+			auth.Get("my_session", "id") != "user-1" {
+			t.Error("Buckets were not isolated")
+		}
+		if len(session.Flashes("my_session")) != 0 {
+			t.Error("Bucketed flash leaked into the session")
+		}
+		if flashes := auth.Flashes("my_session"); len(flashes) != 1 || flashes[0] != "welcome" {
+			t.Error("Bucketed flash was lost:", flashes)
+		}
+
+		cart.Clear("my_session")
+		if cart.Len("my_session") != 0 || auth.Len("my_session") != 1 || session.Get("my_session", "id") != "root" {
+			t.Error("Clearing a bucket touched other keys")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
@@ -0,0 +1,217 @@
+package sessions
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// CacheOptions configures a CachedStore.
+type CacheOptions struct {
+	// MaxEntries caps the number of cached sessions, evicting the least
+	// recently used. Defaults to 10000.
+	MaxEntries int
+	// TTL is how long a loaded session is served from the cache. Defaults
+	// to five seconds.
+	TTL time.Duration
+	// Invalidator, if set, announces saved sessions to the other
+	// instances, which drop them from their caches, and receives theirs.
+	Invalidator Invalidator
+}
+
+// CachedStore is an interface that represents a Store keeping recently
+// loaded sessions in process.
+type CachedStore interface {
+	// Store is an embedded interface so that CachedStore can be used
+	// as a session store.
+	Store
+	// Invalidate drops the session with the given ID from the cache, or
+	// every session if id is empty.
+	Invalidate(id string)
+	// Close stops listening to the Invalidator.
+	Close()
+}
+
+// NewCachedStore returns a CachedStore serving repeated loads of a session
+// within opts.TTL from memory instead of store. Saves always go to store
+// and drop the session from the cache.
+//
+// Without an Invalidator, other instances may serve a session saved
+// elsewhere for up to TTL, so keep it short.
+func NewCachedStore(store Store, opts CacheOptions) (CachedStore, error) {
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = 10000
+	}
+	if opts.TTL == 0 {
+		opts.TTL = 5 * time.Second
+	}
+
+	c := &cachedStore{
+		Store:   store,
+		opts:    opts,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		ids:     make(map[string]string),
+		stop:    func() {},
+	}
+	if opts.Invalidator != nil {
+		stop, err := opts.Invalidator.Subscribe(c.Invalidate)
+		if err != nil {
+			return nil, err
+		}
+		c.stop = stop
+	}
+	return c, nil
+}
+
+type cacheEntry struct {
+	key     string
+	id      string
+	values  map[interface{}]interface{}
+	options sessions.Options
+	expires time.Time
+}
+
+type cachedStore struct {
+	Store
+	opts CacheOptions
+	stop func()
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	// ids maps session IDs to their cache keys for invalidation.
+	ids map[string]string
+}
+
+func (c *cachedStore) Unwrap() Store {
+	return c.Store
+}
+
+func (c *cachedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(c, name)
+}
+
+func (c *cachedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return c.Store.New(r, name)
+	}
+	key := name + "=" + cookie.Value
+
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+
+			s := sessions.NewSession(c, name)
+			s.ID = e.id
+			s.Values = copyValues(e.values)
+			opts := e.options
+			s.Options = &opts
+			s.IsNew = false
+			return s, nil
+		}
+		c.remove(el)
+	}
+	c.mu.Unlock()
+
+	s, err := c.Store.New(r, name)
+	if err != nil || s.IsNew {
+		return s, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(c.entries[key])
+	e := &cacheEntry{
+		key:     key,
+		id:      s.ID,
+		values:  copyValues(s.Values),
+		expires: time.Now().Add(c.opts.TTL),
+	}
+	if s.Options != nil {
+		e.options = *s.Options
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	if s.ID != "" {
+		c.ids[s.ID] = key
+	}
+	for c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+	return s, nil
+}
+
+func (c *cachedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if cookie, err := r.Cookie(s.Name()); err == nil {
+		c.mu.Lock()
+		c.remove(c.entries[s.Name()+"="+cookie.Value])
+		c.mu.Unlock()
+	}
+	if s.ID != "" {
+		c.Invalidate(s.ID)
+	}
+
+	if err := c.Store.Save(r, w, s); err != nil {
+		return err
+	}
+	if s.ID != "" && c.opts.Invalidator != nil {
+		return c.opts.Invalidator.Publish(s.ID)
+	}
+	return nil
+}
+
+func (c *cachedStore) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id == "" {
+		c.lru.Init()
+		c.entries = make(map[string]*list.Element)
+		c.ids = make(map[string]string)
+		return
+	}
+	if key, ok := c.ids[id]; ok {
+		c.remove(c.entries[key])
+	}
+}
+
+func (c *cachedStore) Delete(id string) error {
+	c.Invalidate(id)
+	if err := deleteAll(id, c.Store); err != nil {
+		return err
+	}
+	if c.opts.Invalidator != nil {
+		return c.opts.Invalidator.Publish(id)
+	}
+	return nil
+}
+
+func (c *cachedStore) Exists(id string) (bool, error) {
+	return existsAny(id, c.Store)
+}
+
+func (c *cachedStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, c.Store)
+}
+
+func (c *cachedStore) Close() {
+	c.stop()
+}
+
+func (c *cachedStore) remove(el *list.Element) {
+	if el == nil {
+		return
+	}
+	e := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.key)
+	if e.id != "" {
+		delete(c.ids, e.id)
+	}
+}
@@ -0,0 +1,68 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CachedStore(t *testing.T) {
+	inner := NewInstrumentedStore(NewMemoryStore(0))
+	store, _ := NewCachedStore(inner, CacheOptions{TTL: time.Minute})
+	defer store.Close()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	store.Save(req, res, s)
+
+	for i := 0; i < 5; i++ {
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+		if s, _ := store.New(req, "my_session"); s.Values["hello"] != "world" {
+			t.Fatal("Unexpected values:", s.Values)
+		}
+	}
+	if n := inner.Stats().News; n != 2 {
+		t.Error("Repeated loads were not served from the cache, store loads:", n)
+	}
+
+	store.Invalidate(s.ID)
+	store.New(req, "my_session")
+	if n := inner.Stats().News; n != 3 {
+		t.Error("Invalidated session was served from the cache")
+	}
+
+	store.Invalidate("")
+	store.New(req, "my_session")
+	if n := inner.Stats().News; n != 4 {
+		t.Error("Session was served from the cache after invalidating all")
+	}
+}
+
+func Test_CachedStoreOptions(t *testing.T) {
+	store, _ := NewCachedStore(NewMemoryStore(0), CacheOptions{TTL: time.Minute})
+	defer store.Close()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	res := httptest.NewRecorder()
+	store.Save(req, res, s)
+
+	for i := 0; i < 2; i++ {
+		// the second load is a cache hit
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+		s, _ = store.New(req, "my_session")
+	}
+
+	res2 := httptest.NewRecorder()
+	store.Save(req, res2, s)
+	cookie := res2.Header().Get("Set-Cookie")
+	if !strings.Contains(cookie, "Path=/") || !strings.Contains(cookie, "Max-Age=2592000") {
+		t.Error("Options were lost after a cache hit:", cookie)
+	}
+}
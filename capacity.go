@@ -0,0 +1,162 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+const admittedKey = "_admitted"
+
+// ActivityTracker counts active sessions for a Capacity. Implementations
+// backed by a shared store enforce the ceiling across all instances.
+type ActivityTracker interface {
+	// Touch marks the session identified by id as active now.
+	Touch(id string) error
+	// Active returns the number of sessions touched within idle.
+	Active(idle time.Duration) (int, error)
+}
+
+// Capacity is a ceiling on the number of concurrently active sessions. Once
+// reached, visitors without an admitted session are sent to the Overflow
+// handler, a waiting room, while admitted ones keep being served.
+//
+//	capacity := &sessions.Capacity{Max: 10000}
+//	m.Use(sessions.Sessions(store))
+//	m.Use(capacity.Handler("my_session"))
+type Capacity struct {
+	Max int
+	// Idle is how long a session counts as active after its last request.
+	// Defaults to 15 minutes.
+	Idle time.Duration
+	// Overflow writes the reply to visitors over capacity. Defaults to a 503
+	// Service Unavailable with a Retry-After header of one minute.
+	Overflow func(w http.ResponseWriter, r *http.Request)
+	// Tracker counts active sessions. Defaults to an in-memory tracker,
+	// which limits each instance on its own.
+	Tracker ActivityTracker
+
+	once sync.Once
+}
+
+// Handler returns a Middleware enforcing the ceiling for the named session.
+// It must be used after Sessions. Visitors are counted once their session
+// is saved with the admission, so requests whose session is destroyed or
+// cleared do not take a place.
+func (c *Capacity) Handler(name string) martini.Handler {
+	c.once.Do(func() {
+		if c.Idle == 0 {
+			c.Idle = 15 * time.Minute
+		}
+		if c.Tracker == nil {
+			c.Tracker = newMemoryTracker(c.Idle)
+		}
+	})
+
+	return func(res http.ResponseWriter, r *http.Request, s Session) {
+		if id, admitted := s.Get(name, admittedKey).(string); admitted {
+			c.Tracker.Touch(id)
+			return
+		}
+
+		active, err := c.Tracker.Active(c.Idle)
+		if err == nil && active >= c.Max {
+			c.overflow(res, r)
+			return
+		}
+
+		b := make([]byte, 16)
+		rand.Read(b)
+		id := hex.EncodeToString(b)
+		s.Set(name, admittedKey, id)
+		res.(martini.ResponseWriter).Before(func(martini.ResponseWriter) {
+			if s.Get(name, admittedKey) == id && s.GetOptions(name).MaxAge >= 0 {
+				c.Tracker.Touch(id)
+			}
+		})
+	}
+}
+
+func (c *Capacity) overflow(w http.ResponseWriter, r *http.Request) {
+	if c.Overflow != nil {
+		c.Overflow(w, r)
+		return
+	}
+	w.Header().Set("Retry-After", "60")
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// trackerBuckets is the number of time buckets the idle window of the
+// in-memory tracker is split into.
+const trackerBuckets = 16
+
+// memoryTracker files every session under the time bucket of its last
+// touch, keeping a running count, so Active is O(1) and sessions gone idle
+// are dropped a bucket at a time. Sessions count as active for up to a
+// bucket longer than idle.
+type memoryTracker struct {
+	mu      sync.Mutex
+	width   int64
+	buckets [trackerBuckets + 1]map[string]bool
+	// current is the number of the newest bucket, counted from the epoch.
+	current int64
+	bucket  map[string]int64
+	active  int
+}
+
+func newMemoryTracker(idle time.Duration) *memoryTracker {
+	width := int64(idle / trackerBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	return &memoryTracker{width: width, bucket: make(map[string]int64)}
+}
+
+// advance drops the buckets that have left the idle window.
+func (m *memoryTracker) advance(now time.Time) {
+	n := now.UnixNano() / m.width
+	if n-m.current > int64(len(m.buckets)) {
+		m.current = n - int64(len(m.buckets))
+	}
+	for m.current < n {
+		m.current++
+		slot := m.current % int64(len(m.buckets))
+		for id := range m.buckets[slot] {
+			delete(m.bucket, id)
+			m.active--
+		}
+		m.buckets[slot] = nil
+	}
+}
+
+func (m *memoryTracker) Touch(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance(time.Now())
+
+	if b, ok := m.bucket[id]; ok {
+		delete(m.buckets[b%int64(len(m.buckets))], id)
+	} else {
+		m.active++
+	}
+	slot := m.current % int64(len(m.buckets))
+	if m.buckets[slot] == nil {
+		m.buckets[slot] = make(map[string]bool)
+	}
+	m.buckets[slot][id] = true
+	m.bucket[id] = m.current
+	return nil
+}
+
+// Active returns the number of sessions touched within the idle window of
+// the tracker, which is the Idle of the Capacity it was made for.
+func (m *memoryTracker) Active(idle time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance(time.Now())
+	return m.active, nil
+}
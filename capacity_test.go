@@ -0,0 +1,89 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Capacity(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	capacity := &Capacity{Max: 1}
+	m.Use(Sessions(store))
+	m.Use(capacity.Handler("my_session"))
+
+	m.Get("/", func() string {
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+	if res.Code != http.StatusOK || cookie == "" {
+		t.Fatal("First visitor was not admitted:", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusServiceUnavailable {
+		t.Error("Visitor over capacity was admitted:", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Error("Admitted visitor was refused:", res.Code)
+	}
+}
+
+func Test_CapacityDestroyed(t *testing.T) {
+	m := martini.Classic()
+
+	capacity := &Capacity{Max: 1}
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(capacity.Handler("my_session"))
+
+	m.Get("/", func() string {
+		return "OK"
+	})
+	m.Get("/logout", func(session Session) string {
+		session.Destroy("my_session")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/logout", nil)
+	m.ServeHTTP(res, req)
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Error("Destroyed session took a place:", res.Code)
+	}
+}
+
+func Test_MemoryTracker(t *testing.T) {
+	tracker := newMemoryTracker(160 * time.Millisecond)
+	tracker.Touch("a")
+	tracker.Touch("b")
+	tracker.Touch("a")
+	if n, _ := tracker.Active(0); n != 2 {
+		t.Error("Expected 2 active sessions, got", n)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	tracker.Touch("c")
+	if n, _ := tracker.Active(0); n != 1 {
+		t.Error("Idle sessions are still counted:", n)
+	}
+}
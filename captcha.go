@@ -0,0 +1,151 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const captchaKey = "_captcha"
+
+func init() {
+	gob.Register(map[string]challenge{})
+}
+
+// Captcha keeps issued CAPTCHA challenges in the session and validates
+// responses to them once. Only an HMAC of the expected answer is stored, so
+// it can't be read from a signed but unencrypted cookie.
+//
+// The attempts on every challenge are also counted in memory, so replaying
+// an older cookie of a CookieStore can neither answer a challenge twice nor
+// reset its attempts. That record is per process: with several instances
+// behind a load balancer, use a server-side store or sticky sessions.
+type Captcha struct {
+	secret      []byte
+	ttl         time.Duration
+	maxAttempts int
+
+	mu    sync.Mutex
+	tries map[string]*captchaTries
+	swept time.Time
+}
+
+// captchaTries counts the responses to a challenge in memory. Challenges
+// that are done accept no further responses.
+type captchaTries struct {
+	attempts int
+	done     bool
+	expires  time.Time
+}
+
+type challenge struct {
+	Answer   []byte
+	Expires  time.Time
+	Attempts int
+}
+
+// NewCaptcha returns a Captcha whose challenges expire after ttl and are
+// discarded after maxAttempts wrong responses.
+func NewCaptcha(secret []byte, ttl time.Duration, maxAttempts int) *Captcha {
+	return &Captcha{secret: secret, ttl: ttl, maxAttempts: maxAttempts, tries: make(map[string]*captchaTries)}
+}
+
+// Issue stores a challenge expecting answer in the named session and returns
+// its ID, to be rendered alongside the CAPTCHA image.
+func (c *Captcha) Issue(s Session, name, answer string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+
+	challenges := c.challenges(s, name)
+	challenges[id] = challenge{
+		Answer:  c.sign(id, answer),
+		Expires: time.Now().Add(c.ttl),
+	}
+	s.Set(name, captchaKey, challenges)
+	return id
+}
+
+// Verify reports whether response answers the challenge id. A challenge is
+// removed once answered correctly, once expired or after too many wrong
+// responses, so it can't be replayed.
+func (c *Captcha) Verify(s Session, name, id, response string) bool {
+	challenges := c.challenges(s, name)
+	ch, ok := challenges[id]
+	if !ok {
+		return false
+	}
+
+	valid := time.Now().Before(ch.Expires) && hmac.Equal(ch.Answer, c.sign(id, response))
+	attempts, spent := c.try(id, ch.Expires, valid)
+	if ch.Attempts = attempts; spent {
+		delete(challenges, id)
+	} else {
+		challenges[id] = ch
+	}
+	s.Set(name, captchaKey, challenges)
+	return valid && attempts > 0
+}
+
+// try records a response to the challenge id in memory and returns the
+// number of responses so far, or 0 if the challenge was already done, and
+// whether it is done now.
+func (c *Captcha) try(id string, expires time.Time, valid bool) (int, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+
+	t, ok := c.tries[id]
+	if !ok {
+		t = &captchaTries{expires: expires}
+		c.tries[id] = t
+	}
+	if t.done {
+		return 0, true
+	}
+	t.attempts++
+	t.done = valid || t.attempts >= c.maxAttempts || now.After(expires)
+	return t.attempts, t.done
+}
+
+// sweep forgets the challenges that expired, at most once per ttl. The
+// caller must hold c.mu.
+func (c *Captcha) sweep(now time.Time) {
+	if now.Sub(c.swept) < c.ttl {
+		return
+	}
+	c.swept = now
+	for id, t := range c.tries {
+		if now.After(t.expires) {
+			delete(c.tries, id)
+		}
+	}
+}
+
+// challenges returns the pending challenges of the named session, dropping
+// expired ones.
+func (c *Captcha) challenges(s Session, name string) map[string]challenge {
+	challenges, _ := s.Get(name, captchaKey).(map[string]challenge)
+	if challenges == nil {
+		return make(map[string]challenge)
+	}
+	for id, ch := range challenges {
+		if time.Now().After(ch.Expires) {
+			delete(challenges, id)
+		}
+	}
+	return challenges
+}
+
+func (c *Captcha) sign(id, answer string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(id))
+	mac.Write([]byte{0})
+	mac.Write([]byte(answer))
+	return mac.Sum(nil)
+}
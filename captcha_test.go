@@ -0,0 +1,84 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Captcha(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	captcha := NewCaptcha([]byte("captcha-secret"), time.Minute, 2)
+	m.Get("/", func(session Session) string {
+		id := captcha.Issue(session, "my_session", "x7k2")
+		if captcha.Verify(session, "my_session", id, "nope") {
+			t.Error("Wrong answer was accepted")
+		}
+		if !captcha.Verify(session, "my_session", id, "x7k2") {
+			t.Error("Right answer was rejected")
+		}
+		if captcha.Verify(session, "my_session", id, "x7k2") {
+			t.Error("Challenge was replayed")
+		}
+
+		id = captcha.Issue(session, "my_session", "abcd")
+		captcha.Verify(session, "my_session", id, "1")
+		captcha.Verify(session, "my_session", id, "2")
+		if captcha.Verify(session, "my_session", id, "abcd") {
+			t.Error("Challenge survived too many attempts")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_CaptchaReplayedCookie(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	captcha := NewCaptcha([]byte("captcha-secret"), time.Minute, 3)
+	id := ""
+	m.Get("/issue", func(session Session) string {
+		id = captcha.Issue(session, "my_session", "x7k2")
+		return "OK"
+	})
+	m.Get("/verify/:answer", func(session Session, params martini.Params) string {
+		if captcha.Verify(session, "my_session", id, params["answer"]) {
+			return "valid"
+		}
+		return "invalid"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/issue", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	verify := func(answer string) string {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/verify/"+answer, nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		return res.Body.String()
+	}
+
+	if verify("x7k2") != "valid" {
+		t.Fatal("Right answer was rejected")
+	}
+	// the cookie from before the answer still holds the challenge
+	if verify("x7k2") != "invalid" {
+		t.Error("Challenge was replayed with an old cookie")
+	}
+}
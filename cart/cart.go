@@ -0,0 +1,181 @@
+// Package cart provides a shopping cart kept in a martini-contrib session.
+//
+//	m.Get("/cart/add/:sku", func(s sessions.Session, p martini.Params) string {
+//	  c := cart.New(s, "my_session")
+//	  c.Add(cart.Item{SKU: p["sku"], Quantity: 1, Price: 499})
+//	  return "OK"
+//	})
+//
+// Carts larger than Options.MaxInline bytes are moved to an Overflow backend
+// and only a reference is left in the session, so they don't push a cookie
+// based session past the browser size limit.
+package cart
+
+import (
+	"encoding/gob"
+	"errors"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/overflow"
+)
+
+const key = "_cart"
+
+// ErrNoOverflow is returned when the session references an overflowed cart
+// but the Cart was created without an Overflow backend.
+var ErrNoOverflow = errors.New("cart: cart is in overflow storage but no Overflow is configured")
+
+// ErrInvalidQuantity is returned by Add for items without a positive
+// quantity.
+var ErrInvalidQuantity = errors.New("cart: quantity must be positive")
+
+func init() {
+	gob.Register([]Item{})
+}
+
+// Item is a single cart line.
+type Item struct {
+	SKU      string
+	Name     string
+	Quantity int
+	// Price is the unit price in the smallest currency unit.
+	Price int64
+}
+
+// Overflow persists carts that are too large to be kept in the session.
+type Overflow = overflow.Store
+
+// Options configures a Cart.
+type Options struct {
+	// Overflow receives carts whose encoding exceeds MaxInline bytes.
+	// Carts are always kept in the session when it is nil.
+	Overflow Overflow
+	// MaxInline is the largest encoded cart kept in the session.
+	// Defaults to 1024.
+	MaxInline int
+}
+
+// Cart is a shopping cart stored in a named session.
+type Cart struct {
+	session sessions.Session
+	name    string
+	value   overflow.Value
+}
+
+// New returns the cart stored in the named session.
+func New(s sessions.Session, name string, options ...Options) *Cart {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.MaxInline == 0 {
+		opts.MaxInline = 1024
+	}
+	return &Cart{
+		session: s,
+		name:    name,
+		value:   overflow.Value{Key: key, Store: opts.Overflow, MaxInline: opts.MaxInline},
+	}
+}
+
+// Items returns the cart lines.
+func (c *Cart) Items() ([]Item, error) {
+	var items []Item
+	err := c.value.Load(c.session, c.name, &items)
+	if err == overflow.ErrNoStore {
+		return nil, ErrNoOverflow
+	}
+	return items, err
+}
+
+// Add adds item to the cart, increasing the quantity of an existing line
+// with the same SKU. It returns ErrInvalidQuantity unless item.Quantity is
+// positive.
+func (c *Cart) Add(item Item) error {
+	if item.Quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	items, err := c.Items()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if items[i].SKU == item.SKU {
+			items[i].Quantity += item.Quantity
+			return c.save(items)
+		}
+	}
+	return c.save(append(items, item))
+}
+
+// Update sets the quantity of the line with the given SKU. A quantity of
+// zero or less removes the line.
+func (c *Cart) Update(sku string, quantity int) error {
+	if quantity <= 0 {
+		return c.Remove(sku)
+	}
+	items, err := c.Items()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if items[i].SKU == sku {
+			items[i].Quantity = quantity
+		}
+	}
+	return c.save(items)
+}
+
+// Remove removes the line with the given SKU.
+func (c *Cart) Remove(sku string) error {
+	items, err := c.Items()
+	if err != nil {
+		return err
+	}
+	kept := items[:0]
+	for _, item := range items {
+		if item.SKU != sku {
+			kept = append(kept, item)
+		}
+	}
+	return c.save(kept)
+}
+
+// Total returns the sum of all lines in the smallest currency unit.
+func (c *Cart) Total() (int64, error) {
+	items, err := c.Items()
+	var total int64
+	for _, item := range items {
+		total += int64(item.Quantity) * item.Price
+	}
+	return total, err
+}
+
+// Clear empties the cart.
+func (c *Cart) Clear() error {
+	return c.save(nil)
+}
+
+// Merge adds all lines of guest to c and empties guest. It is meant to be
+// called at login, before the guest session is merged or destroyed.
+func (c *Cart) Merge(guest *Cart) error {
+	items, err := guest.Items()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := c.Add(item); err != nil {
+			return err
+		}
+	}
+	return guest.Clear()
+}
+
+// save stores items. Overflow entries are written before the session
+// references them and deleted once it no longer does.
+func (c *Cart) save(items []Item) error {
+	if len(items) == 0 {
+		return c.value.Delete(c.session, c.name)
+	}
+	return c.value.Save(c.session, c.name, items)
+}
@@ -0,0 +1,162 @@
+package cart
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+type memOverflow map[string][]byte
+
+func (m memOverflow) Load(id string) ([]byte, error) {
+	data, ok := m[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (m memOverflow) Save(id string, data []byte) error {
+	m[id] = data
+	return nil
+}
+
+func (m memOverflow) Delete(id string) error {
+	delete(m, id)
+	return nil
+}
+
+func serve(t *testing.T, h martini.Handler) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+	m.Get("/", h, func() string { return "OK" })
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_Cart(t *testing.T) {
+	serve(t, func(s sessions.Session) {
+		c := New(s, "my_session")
+		c.Add(Item{SKU: "apple", Quantity: 2, Price: 50})
+		c.Add(Item{SKU: "pear", Quantity: 1, Price: 75})
+		c.Add(Item{SKU: "apple", Quantity: 1, Price: 50})
+		if total, _ := c.Total(); total != 225 {
+			t.Error("Unexpected total:", total)
+		}
+
+		if err := c.Add(Item{SKU: "pear", Quantity: -1}); err != ErrInvalidQuantity {
+			t.Error("Expected ErrInvalidQuantity, got", err)
+		}
+
+		c.Update("pear", 0)
+		items, _ := c.Items()
+		if len(items) != 1 || items[0].Quantity != 3 {
+			t.Error("Unexpected items after update:", items)
+		}
+	})
+}
+
+func Test_CartOverflow(t *testing.T) {
+	overflow := memOverflow{}
+	serve(t, func(s sessions.Session) {
+		c := New(s, "my_session", Options{Overflow: overflow, MaxInline: 64})
+		for _, sku := range []string{"a", "b", "c", "d", "e"} {
+			c.Add(Item{SKU: sku, Name: "a rather long product name", Quantity: 1, Price: 10})
+		}
+		if len(overflow) == 0 {
+			t.Fatal("Large cart was not moved to the overflow store")
+		}
+		if total, err := c.Total(); total != 50 || err != nil {
+			t.Error("Unexpected total from overflow:", total, err)
+		}
+	})
+}
+
+func Test_CartOverflowReplaced(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+
+	overflow := memOverflow{}
+	m.Get("/add/:sku", func(s sessions.Session, p martini.Params) string {
+		c := New(s, "my_session", Options{Overflow: overflow, MaxInline: 64})
+		c.Add(Item{SKU: p["sku"], Name: "a rather long product name", Quantity: 1, Price: 10})
+		return "OK"
+	})
+
+	cookie := ""
+	add := func(sku string) {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/add/"+sku, nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		cookie = res.Header().Get("Set-Cookie")
+	}
+
+	add("a")
+	if len(overflow) != 1 {
+		t.Fatal("Large cart was not moved to the overflow store:", len(overflow))
+	}
+	add("b")
+	if len(overflow) != 2 {
+		t.Error("Replaced cart was deleted before the session was saved:", len(overflow))
+	}
+	add("c")
+	if len(overflow) != 2 {
+		t.Error("Replaced cart was not deleted once the session was saved:", len(overflow))
+	}
+}
+
+// brokenOverflow fails all saves once full is set.
+type brokenOverflow struct {
+	memOverflow
+	full bool
+}
+
+func (b *brokenOverflow) Save(id string, data []byte) error {
+	if b.full {
+		return errors.New("disk full")
+	}
+	return b.memOverflow.Save(id, data)
+}
+
+func Test_CartOverflowSaveFailure(t *testing.T) {
+	overflow := &brokenOverflow{memOverflow: memOverflow{}}
+	serve(t, func(s sessions.Session) {
+		c := New(s, "my_session", Options{Overflow: overflow, MaxInline: 64})
+		for _, sku := range []string{"a", "b", "c", "d", "e"} {
+			c.Add(Item{SKU: sku, Name: "a rather long product name", Quantity: 1, Price: 10})
+		}
+
+		overflow.full = true
+		if err := c.Add(Item{SKU: "f", Name: "a rather long product name", Quantity: 1, Price: 10}); err == nil {
+			t.Error("Failed overflow save was not reported")
+		}
+		if total, err := c.Total(); total != 50 || err != nil {
+			t.Error("Cart was lost by a failed save:", total, err)
+		}
+	})
+}
+
+func Test_CartMerge(t *testing.T) {
+	serve(t, func(s sessions.Session) {
+		guest := New(s, "guest")
+		guest.Add(Item{SKU: "apple", Quantity: 1, Price: 50})
+		user := New(s, "user")
+		user.Add(Item{SKU: "apple", Quantity: 2, Price: 50})
+
+		user.Merge(guest)
+		items, _ := user.Items()
+		if len(items) != 1 || items[0].Quantity != 3 {
+			t.Error("Unexpected items after merge:", items)
+		}
+		if items, _ := guest.Items(); len(items) != 0 {
+			t.Error("Guest cart was not emptied")
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+// CleanupStats counts the work of the expired session cleanup of a store.
+type CleanupStats struct {
+	// Runs is the number of cleanups so far and Purged the number of
+	// expired sessions they deleted.
+	Runs   int64
+	Purged int64
+	// LastRun is when the last cleanup finished, and LastError what it
+	// failed with, if anything.
+	LastRun   time.Time
+	LastError error
+}
+
+// Cleaner is implemented by stores keeping expired sessions until they are
+// cleaned up. Stores whose backend expires sessions by itself, such as
+// Redis, implement it as a no-op.
+type Cleaner interface {
+	// StartCleanup deletes expired sessions every interval until stop is
+	// called.
+	StartCleanup(interval time.Duration) (stop func())
+	// CleanupStats returns the counters of all cleanups so far.
+	CleanupStats() CleanupStats
+}
+
+// Purger is implemented by backends of server-side stores that keep
+// expired sessions.
+type Purger interface {
+	// PurgeExpired deletes all expired sessions and returns their IDs.
+	PurgeExpired() ([]string, error)
+}
+
+// ExpiryNotifier is implemented by stores that can tell when their cleanup
+// deletes an expired session, or when they evict a session to make room.
+type ExpiryNotifier interface {
+	// OnExpire registers f to be called with the ID of every session
+	// deleted by a cleanup or evicted. f is called from the cleanup
+	// goroutine, or from the save that evicted the session.
+	OnExpire(f func(id string))
+}
+
+// cleanup runs the purges of a ServerStore and counts them.
+type cleanup struct {
+	mu       sync.Mutex
+	stats    CleanupStats
+	onExpire []func(id string)
+}
+
+func (s *ServerStore) StartCleanup(interval time.Duration) func() {
+	p, ok := s.backend.(Purger)
+	if !ok {
+		return func() {}
+	}
+
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.purge(p)
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(quit) }) }
+}
+
+func (s *ServerStore) CleanupStats() CleanupStats {
+	s.cleanup.mu.Lock()
+	defer s.cleanup.mu.Unlock()
+	return s.cleanup.stats
+}
+
+func (s *ServerStore) OnExpire(f func(id string)) {
+	s.cleanup.mu.Lock()
+	defer s.cleanup.mu.Unlock()
+	s.cleanup.onExpire = append(s.cleanup.onExpire, f)
+}
+
+// Purge runs a single cleanup right away, if the backend is a Purger.
+func (s *ServerStore) Purge() error {
+	if p, ok := s.backend.(Purger); ok {
+		return s.purge(p)
+	}
+	return nil
+}
+
+// purge runs a single cleanup.
+func (s *ServerStore) purge(p Purger) error {
+	ids, err := p.PurgeExpired()
+
+	s.cleanup.mu.Lock()
+	s.cleanup.stats.Runs++
+	s.cleanup.stats.Purged += int64(len(ids))
+	s.cleanup.stats.LastRun = time.Now()
+	s.cleanup.stats.LastError = err
+	s.cleanup.mu.Unlock()
+
+	s.expired(ids)
+	return err
+}
+
+// expired calls the OnExpire listeners with ids.
+func (s *ServerStore) expired(ids []string) {
+	s.cleanup.mu.Lock()
+	listeners := s.cleanup.onExpire
+	s.cleanup.mu.Unlock()
+
+	for _, id := range ids {
+		for _, f := range listeners {
+			f(id)
+		}
+	}
+}
+
+// StartCleanup does nothing, Redis expires sessions by itself.
+func (c *rediStore) StartCleanup(interval time.Duration) func() {
+	return func() {}
+}
+
+// CleanupStats returns zero counters, as no cleanup ever runs.
+func (c *rediStore) CleanupStats() CleanupStats {
+	return CleanupStats{}
+}
@@ -0,0 +1,38 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Cleanup(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer store.Close()
+	backend := store.(*memoryStore).backend
+	backend.Save("expired", []byte{}, -time.Minute)
+	backend.Save("active", []byte{}, time.Minute)
+
+	stop := store.(Cleaner).StartCleanup(10 * time.Millisecond)
+	defer stop()
+	time.Sleep(50 * time.Millisecond)
+
+	stats := store.(Cleaner).CleanupStats()
+	if stats.Runs == 0 || stats.Purged != 1 || stats.LastError != nil {
+		t.Errorf("Unexpected cleanup stats: %+v", stats)
+	}
+	if store.Stats().Entries != 1 {
+		t.Error("Active session was purged")
+	}
+}
+
+func Test_RedisCleaner(t *testing.T) {
+	var store Store = &rediStore{}
+	c, ok := StoreAs[Cleaner](store)
+	if !ok {
+		t.Fatal("Redis store is not a Cleaner")
+	}
+	c.StartCleanup(time.Minute)()
+	if stats := c.CleanupStats(); stats.Runs != 0 {
+		t.Errorf("Unexpected cleanup stats: %+v", stats)
+	}
+}
@@ -0,0 +1,133 @@
+package sessions
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/snappy"
+	"github.com/gorilla/sessions"
+)
+
+// compressedKey holds the compressed values of a session saved by a
+// compressing store. The first byte records the Compression used.
+const compressedKey = "_compressed"
+
+// Compression selects the algorithm of NewCompressedStore.
+type Compression byte
+
+const (
+	// Gzip compresses best and suits cookies, where every byte counts.
+	Gzip Compression = iota + 1
+	// Snappy is much faster and suits large server-side sessions.
+	Snappy
+)
+
+// NewCompressedStore returns a Store compressing the values of sessions
+// whose gob encoding exceeds threshold bytes before they reach store, in
+// the cookie or a backend alike. Smaller sessions are saved as is.
+//
+// When combined with NewEncryptedStore, compress first: ciphertext does not
+// compress.
+//
+//	encrypted, _ := sessions.NewEncryptedStore(store, key)
+//	m.Use(sessions.Sessions(sessions.NewCompressedStore(encrypted, sessions.Gzip, 512)))
+func NewCompressedStore(store Store, algorithm Compression, threshold int) Store {
+	return &compressedStore{Store: store, algorithm: algorithm, threshold: threshold}
+}
+
+type compressedStore struct {
+	Store
+	algorithm Compression
+	threshold int
+}
+
+func (c *compressedStore) Unwrap() Store {
+	return c.Store
+}
+
+func (c *compressedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(c, name)
+}
+
+func (c *compressedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := c.Store.New(r, name)
+	if s == nil {
+		return s, err
+	}
+	data, ok := s.Values[compressedKey].([]byte)
+	if !ok {
+		return s, err
+	}
+
+	values, decErr := decompress(data)
+	if decErr != nil {
+		s.Values = make(map[interface{}]interface{})
+		s.IsNew = true
+		return s, decErr
+	}
+	s.Values = values
+	return s, err
+}
+
+func (c *compressedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.Values); err != nil {
+		return err
+	}
+	if buf.Len() <= c.threshold {
+		return c.Store.Save(r, w, s)
+	}
+
+	data := []byte{byte(c.algorithm)}
+	switch c.algorithm {
+	case Snappy:
+		data = append(data, snappy.Encode(nil, buf.Bytes())...)
+	default:
+		var out bytes.Buffer
+		out.WriteByte(byte(Gzip))
+		zw := gzip.NewWriter(&out)
+		zw.Write(buf.Bytes())
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = out.Bytes()
+	}
+
+	values := s.Values
+	s.Values = map[interface{}]interface{}{compressedKey: data}
+	defer func() { s.Values = values }()
+	return c.Store.Save(r, w, s)
+}
+
+func decompress(data []byte) (map[interface{}]interface{}, error) {
+	if len(data) == 0 {
+		return nil, errors.New("sessions: empty compressed session")
+	}
+
+	var plain []byte
+	var err error
+	switch Compression(data[0]) {
+	case Gzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(data[1:])); err == nil {
+			plain, err = ioutil.ReadAll(zr)
+		}
+	case Snappy:
+		plain, err = snappy.Decode(nil, data[1:])
+	default:
+		err = errors.New("sessions: unknown session compression")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[interface{}]interface{})
+	if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
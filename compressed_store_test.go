@@ -0,0 +1,29 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_CompressedStore(t *testing.T) {
+	for _, algorithm := range []Compression{Gzip, Snappy} {
+		store := NewCompressedStore(NewCookieStore([]byte("secret123")), algorithm, 256)
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["blob"] = strings.Repeat("campaign ", 1000)
+		res := httptest.NewRecorder()
+		if err := store.Save(req, res, s); err != nil {
+			t.Fatal("Compressed session did not fit into a cookie:", err)
+		}
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+		s, err := store.New(req, "my_session")
+		if err != nil || s.Values["blob"] != strings.Repeat("campaign ", 1000) {
+			t.Error("Session was not decompressed:", algorithm, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/sessions"
+)
+
+// ErrConflict is returned by a ConflictStore using OptimisticLock when a
+// concurrent request changed the same keys of a session.
+var ErrConflict = errors.New("sessions: session was modified concurrently")
+
+// ConflictStrategy selects how a ConflictStore resolves concurrent writes.
+type ConflictStrategy int
+
+const (
+	// LastWriterWins saves the session as is, discarding changes made by
+	// requests that saved it in the meantime.
+	LastWriterWins ConflictStrategy = iota
+	// MergeKeys only writes the keys changed by the request on top of the
+	// currently stored values.
+	MergeKeys
+	// OptimisticLock tracks a version counter in the session. If another
+	// request saved it in the meantime the changes are rebased onto the
+	// stored values as long as no key was changed by both, otherwise
+	// ErrConflict is returned. Stores whose backend is a Swapper, such as
+	// the memory, SQL and Bolt stores, check and replace the version with a
+	// compare-and-swap, which also detects conflicts between processes.
+	// Other stores check it under a lock of this process only.
+	OptimisticLock
+)
+
+const versionKey = "_version"
+
+type conflictKey int
+
+const originalsKey conflictKey = 0
+
+// NewConflictStore returns a Store resolving overlapping saves of the same
+// session, such as those of parallel XHRs, with strategy. OptimisticLock
+// rebases onto at most retries concurrent saves before giving up.
+//
+// Resolution reads the currently stored values, so it only has an effect
+// with server-side stores: a cookie only ever holds the values the client
+// sent with the request. Saves of a session are serialised within this
+// process. Only OptimisticLock over a Swapper backend also resolves saves
+// of different processes, with the other strategies and backends requests
+// of one session served by different processes can still overwrite each
+// other, unless a load balancer pins sessions to a process.
+func NewConflictStore(store Store, strategy ConflictStrategy, retries int) Store {
+	return &conflictStore{Store: store, strategy: strategy, retries: retries}
+}
+
+// versionSaver is implemented by stores able to save a session only if the
+// stored session still holds a version, see ServerStore.saveVersion. It's
+// only used on the wrapped store itself, as saving beneath other wrappers
+// would skip them.
+type versionSaver interface {
+	saveVersion(r *http.Request, w http.ResponseWriter, s *sessions.Session, version interface{}) (bool, error)
+}
+
+type conflictStore struct {
+	Store
+	strategy ConflictStrategy
+	retries  int
+
+	// locks serialise the read and write of a save of a session within
+	// this process.
+	locks idLocks
+}
+
+func (c *conflictStore) Unwrap() Store {
+	return c.Store
+}
+
+func (c *conflictStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := c.Store.Get(r, name)
+	if s != nil && c.strategy != LastWriterWins {
+		originals, _ := context.Get(r, originalsKey).(map[*sessions.Session]map[interface{}]interface{})
+		if originals == nil {
+			originals = make(map[*sessions.Session]map[interface{}]interface{})
+			context.Set(r, originalsKey, originals)
+		}
+		if _, ok := originals[s]; !ok {
+			originals[s] = copyValues(s.Values)
+		}
+	}
+	return s, err
+}
+
+func (c *conflictStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	originals, _ := context.Get(r, originalsKey).(map[*sessions.Session]map[interface{}]interface{})
+	base, ok := originals[s]
+	if !ok || s.IsNew || (s.Options != nil && s.Options.MaxAge < 0) {
+		return c.Store.Save(r, w, s)
+	}
+
+	defer c.locks.lock(s.ID)()
+	vs, cas := c.Store.(versionSaver)
+	cas = cas && c.strategy == OptimisticLock
+
+	changed, deleted := changes(base, s.Values)
+	for attempt := 0; ; {
+		stored, err := c.Store.New(r, s.Name())
+		if err != nil {
+			return err
+		}
+
+		if c.strategy == OptimisticLock && !reflect.DeepEqual(stored.Values[versionKey], base[versionKey]) {
+			theirs, theirsDeleted := changes(base, stored.Values)
+			if attempt >= c.retries || overlaps(changed, theirs, theirsDeleted) || overlaps(deleted, theirs, theirsDeleted) {
+				return ErrConflict
+			}
+			// rebase onto the concurrent save and check the version again
+			base = copyValues(stored.Values)
+			attempt++
+			continue
+		}
+
+		values := stored.Values
+		for key := range deleted {
+			delete(values, key)
+		}
+		for key, val := range changed {
+			values[key] = val
+		}
+		version := values[versionKey]
+		if c.strategy == OptimisticLock {
+			n, _ := version.(int64)
+			values[versionKey] = n + 1
+		}
+		s.Values = values
+
+		if cas {
+			saved, err := vs.saveVersion(r, w, s, version)
+			if err != nil {
+				return err
+			}
+			if !saved {
+				// another process saved in the meantime, the next load
+				// rebases onto it
+				continue
+			}
+			originals[s] = copyValues(s.Values)
+			return nil
+		}
+		err = c.Store.Save(r, w, s)
+		if err == nil {
+			originals[s] = copyValues(s.Values)
+		}
+		return err
+	}
+}
+
+// idLocks hands out a mutex per session ID, dropping it once unused.
+type idLocks struct {
+	mu    sync.Mutex
+	locks map[string]*idLock
+}
+
+type idLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock locks the mutex of id and returns a func unlocking it.
+func (l *idLocks) lock(id string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*idLock)
+	}
+	m := l.locks[id]
+	if m == nil {
+		m = &idLock{}
+		l.locks[id] = m
+	}
+	m.users++
+	l.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		l.mu.Lock()
+		if m.users--; m.users == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// changes returns the keys set and removed in current compared to base.
+func changes(base, current map[interface{}]interface{}) (changed map[interface{}]interface{}, deleted map[interface{}]interface{}) {
+	changed = make(map[interface{}]interface{})
+	deleted = make(map[interface{}]interface{})
+	for key, val := range current {
+		if key == versionKey {
+			continue
+		}
+		if old, ok := base[key]; !ok || !reflect.DeepEqual(old, val) {
+			changed[key] = val
+		}
+	}
+	for key := range base {
+		if _, ok := current[key]; !ok && key != versionKey {
+			deleted[key] = nil
+		}
+	}
+	return changed, deleted
+}
+
+func overlaps(keys map[interface{}]interface{}, others ...map[interface{}]interface{}) bool {
+	for key := range keys {
+		for _, o := range others {
+			if _, ok := o[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyValues returns a deep copy of values made by a gob round trip, so
+// changes to slices and maps held in the session are detected. Values that
+// can not be encoded are copied shallowly.
+func copyValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	var buf bytes.Buffer
+	cp := make(map[interface{}]interface{})
+	if gob.NewEncoder(&buf).Encode(values) == nil && gob.NewDecoder(&buf).Decode(&cp) == nil {
+		return cp
+	}
+	cp = make(map[interface{}]interface{}, len(values))
+	for key, val := range values {
+		cp[key] = val
+	}
+	return cp
+}
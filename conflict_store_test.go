@@ -0,0 +1,124 @@
+package sessions
+
+import (
+	"container/list"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+// sharedStore keeps a single server-side session shared by all requests.
+type sharedStore struct {
+	values map[interface{}]interface{}
+}
+
+func (m *sharedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return m.New(r, name)
+}
+
+func (m *sharedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s := sessions.NewSession(m, name)
+	s.IsNew = m.values == nil
+	for key, val := range m.values {
+		s.Values[key] = val
+	}
+	return s, nil
+}
+
+func (m *sharedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	m.values = make(map[interface{}]interface{})
+	for key, val := range s.Values {
+		m.values[key] = val
+	}
+	return nil
+}
+
+func Test_ConflictStore(t *testing.T) {
+	for _, strategy := range []ConflictStrategy{MergeKeys, OptimisticLock} {
+		inner := &sharedStore{values: map[interface{}]interface{}{}}
+		store := NewConflictStore(inner, strategy, 1)
+
+		r1, _ := http.NewRequest("GET", "/", nil)
+		r2, _ := http.NewRequest("GET", "/", nil)
+		s1, _ := store.Get(r1, "my_session")
+		s2, _ := store.Get(r2, "my_session")
+
+		s1.Values["theme"] = "dark"
+		s2.Values["locale"] = "de"
+		if err := store.Save(r1, httptest.NewRecorder(), s1); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Save(r2, httptest.NewRecorder(), s2); err != nil {
+			t.Fatal(err)
+		}
+		if inner.values["theme"] != "dark" || inner.values["locale"] != "de" {
+			t.Error("Concurrent writes were clobbered:", strategy, inner.values)
+		}
+	}
+
+	inner := &sharedStore{values: map[interface{}]interface{}{}}
+	store := NewConflictStore(inner, OptimisticLock, 1)
+	r1, _ := http.NewRequest("GET", "/", nil)
+	r2, _ := http.NewRequest("GET", "/", nil)
+	s1, _ := store.Get(r1, "my_session")
+	s2, _ := store.Get(r2, "my_session")
+	s1.Values["theme"] = "dark"
+	s2.Values["theme"] = "light"
+	store.Save(r1, httptest.NewRecorder(), s1)
+	if err := store.Save(r2, httptest.NewRecorder(), s2); err != ErrConflict {
+		t.Error("Expected ErrConflict, got", err)
+	}
+}
+
+// hookedBackend runs hook once after the next load.
+type hookedBackend struct {
+	*memoryBackend
+	hook func()
+}
+
+func (b *hookedBackend) Load(id string) ([]byte, error) {
+	data, err := b.memoryBackend.Load(id)
+	if hook := b.hook; hook != nil {
+		b.hook = nil
+		hook()
+	}
+	return data, err
+}
+
+func Test_ConflictStoreProcesses(t *testing.T) {
+	backend := &hookedBackend{memoryBackend: &memoryBackend{lru: list.New(), entries: make(map[string]*list.Element)}}
+	shared := NewServerStore(backend)
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := shared.New(req, "my_session")
+	if err := shared.Save(req, httptest.NewRecorder(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	save := func(store Store, key string) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: "my_session", Value: s.ID})
+		s, _ := store.Get(req, "my_session")
+		s.Values[key] = true
+		if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+			t.Error(err)
+		}
+	}
+	// each ConflictStore stands for a process with its own locks
+	a, b := NewConflictStore(shared, OptimisticLock, 1), NewConflictStore(shared, OptimisticLock, 1)
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "my_session", Value: s.ID})
+	sa, _ := a.Get(req, "my_session")
+	sa.Values["a"] = true
+	// b saves while a is saving
+	backend.hook = func() { save(b, "b") }
+	if err := a.Save(req, httptest.NewRecorder(), sa); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := shared.New(req, "my_session")
+	if stored.Values["a"] != true || stored.Values["b"] != true {
+		t.Error("Save of another process was clobbered:", stored.Values)
+	}
+}
@@ -0,0 +1,99 @@
+package sessions
+
+import (
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+type consentKey int
+
+const grantedKey consentKey = 0
+
+// ConsentStore is an interface that represents a Store which only persists
+// sessions once the visitor has consented to cookies. Until then the only
+// cookie set is a signed one recording the visitor's decision.
+type ConsentStore interface {
+	// Store is an embedded interface so that ConsentStore can be used
+	// as a session store.
+	Store
+	// Granted reports whether the visitor has consented.
+	Granted(r *http.Request) bool
+	// Grant records the visitor's consent. Sessions modified earlier in the
+	// same request are persisted when the response is written.
+	Grant(w http.ResponseWriter, r *http.Request) error
+	// Decline records that the visitor refused consent.
+	Decline(w http.ResponseWriter, r *http.Request) error
+}
+
+// NewConsentStore returns a new ConsentStore gating store.
+//
+// The consent cookie is named cookieName and signed with keyPairs, which
+// follow the same rules as for NewCookieStore.
+func NewConsentStore(store Store, cookieName string, keyPairs ...[]byte) ConsentStore {
+	return &consentStore{
+		Store:  store,
+		cookie: cookieName,
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+	}
+}
+
+type consentStore struct {
+	Store
+	cookie string
+	codecs []securecookie.Codec
+}
+
+func (c *consentStore) Unwrap() Store {
+	return c.Store
+}
+
+func (c *consentStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if !c.Granted(r) {
+		// provisional values only live for the current request
+		return nil
+	}
+	return c.Store.Save(r, w, s)
+}
+
+func (c *consentStore) Granted(r *http.Request) bool {
+	if granted, ok := context.GetOk(r, grantedKey); ok {
+		return granted.(bool)
+	}
+
+	granted := false
+	if cookie, err := r.Cookie(c.cookie); err == nil {
+		var decision string
+		if securecookie.DecodeMulti(c.cookie, cookie.Value, &decision, c.codecs...) == nil {
+			granted = decision == "granted"
+		}
+	}
+	context.Set(r, grantedKey, granted)
+	return granted
+}
+
+func (c *consentStore) Grant(w http.ResponseWriter, r *http.Request) error {
+	return c.decide(w, r, "granted")
+}
+
+func (c *consentStore) Decline(w http.ResponseWriter, r *http.Request) error {
+	return c.decide(w, r, "declined")
+}
+
+func (c *consentStore) decide(w http.ResponseWriter, r *http.Request, decision string) error {
+	encoded, err := securecookie.EncodeMulti(c.cookie, decision, c.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.cookie,
+		Value:    encoded,
+		Path:     "/",
+		MaxAge:   86400 * 365,
+		HttpOnly: true,
+	})
+	context.Set(r, grantedKey, decision == "granted")
+	return nil
+}
@@ -0,0 +1,55 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_ConsentStore(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewConsentStore(NewCookieStore([]byte("secret123")), "consent", []byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/browse", func(session Session) string {
+		session.Set("my_session", "cart", "apples")
+		return "OK"
+	})
+	m.Get("/accept", func(session Session, res http.ResponseWriter, req *http.Request) string {
+		session.Set("my_session", "cart", "pears")
+		store.Grant(res, req)
+		return "OK"
+	})
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "cart") != "pears" {
+			t.Error("Provisional value was not migrated after consent")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/browse", nil)
+	m.ServeHTTP(res, req)
+	if c := res.Header()["Set-Cookie"]; len(c) != 0 {
+		t.Fatal("Cookies were set without consent:", c)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/accept", nil)
+	m.ServeHTTP(res, req)
+	cookies := res.Header()["Set-Cookie"]
+	if len(cookies) != 2 {
+		t.Fatal("Expected consent and session cookies, got", cookies)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/show", nil)
+	for _, c := range cookies {
+		req.Header.Add("Cookie", strings.Split(c, ";")[0])
+	}
+	m.ServeHTTP(res, req)
+}
@@ -0,0 +1,127 @@
+// Package consulstore provides a Consul KV based storage for
+// martini-contrib sessions.
+//
+//	store := consulstore.New(client, consulstore.Options{Prefix: "app/sessions/"})
+//	stop := store.StartCleanup(5 * time.Minute)
+//	m.Use(sessions.Sessions(store))
+package consulstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/martini-contrib/sessions"
+)
+
+// Store is an interface that represents a Consul KV based storage for
+// Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Cleaner is an embedded interface so that expired keys can be
+	// deleted, Consul does not expire them by itself.
+	sessions.Cleaner
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// Options configures where a Store keeps its sessions.
+type Options struct {
+	// Prefix is prepended to the keys of sessions. Defaults to "sessions/".
+	Prefix string
+	// Token is the ACL token used for all requests, overriding the one of
+	// the client.
+	Token string
+	// Datacenter selects the datacenter to store sessions in, instead of
+	// the one of the agent.
+	Datacenter string
+}
+
+// New returns a new Store keeping sessions in the Consul KV store.
+//
+// Consul KV has no expiry of its own, so each value carries the expiry of
+// Options.MaxAge. Expired sessions are ignored when read and deleted by
+// StartCleanup.
+func New(client *api.Client, opts Options) Store {
+	if opts.Prefix == "" {
+		opts.Prefix = "sessions/"
+	}
+	return sessions.NewServerStore(&consulBackend{client, opts})
+}
+
+type consulBackend struct {
+	client *api.Client
+	opts   Options
+}
+
+func (c *consulBackend) query() *api.QueryOptions {
+	return &api.QueryOptions{Token: c.opts.Token, Datacenter: c.opts.Datacenter}
+}
+
+func (c *consulBackend) write() *api.WriteOptions {
+	return &api.WriteOptions{Token: c.opts.Token, Datacenter: c.opts.Datacenter}
+}
+
+func (c *consulBackend) Load(id string) ([]byte, error) {
+	pair, _, err := c.client.KV().Get(c.opts.Prefix+id, c.query())
+	if err != nil || pair == nil {
+		return nil, err
+	}
+	return sessions.UnlessExpired(pair.Value), nil
+}
+
+func (c *consulBackend) Save(id string, data []byte, ttl time.Duration) error {
+	_, err := c.client.KV().Put(&api.KVPair{Key: c.opts.Prefix + id, Value: sessions.WithExpiry(data, ttl)}, c.write())
+	return err
+}
+
+func (c *consulBackend) Touch(id string, ttl time.Duration) error {
+	pair, _, err := c.client.KV().Get(c.opts.Prefix+id, c.query())
+	if err != nil || pair == nil {
+		return err
+	}
+	data := sessions.UnlessExpired(pair.Value)
+	if data == nil {
+		return nil
+	}
+	// a session saved since it was read keeps its new expiry
+	pair.Value = sessions.WithExpiry(data, ttl)
+	_, _, err = c.client.KV().CAS(pair, c.write())
+	return err
+}
+
+func (c *consulBackend) Delete(id string) error {
+	_, err := c.client.KV().Delete(c.opts.Prefix+id, c.write())
+	return err
+}
+
+func (c *consulBackend) PurgeExpired() ([]string, error) {
+	pairs, _, err := c.client.KV().List(c.opts.Prefix, c.query())
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, pair := range pairs {
+		if sessions.UnlessExpired(pair.Value) != nil {
+			continue
+		}
+		// skip keys saved again since they were listed
+		ok, _, err := c.client.KV().DeleteCAS(pair, c.write())
+		if err != nil {
+			return ids, err
+		}
+		if ok {
+			ids = append(ids, strings.TrimPrefix(pair.Key, c.opts.Prefix))
+		}
+	}
+	return ids, nil
+}
+
+func (c *consulBackend) Ping(ctx context.Context) error {
+	_, err := c.client.Status().LeaderWithQueryOptions(c.query().WithContext(ctx))
+	return err
+}
@@ -0,0 +1,32 @@
+package consulstore
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+)
+
+// Test_Conformance runs against the agent at SESSIONS_TEST_CONSUL, e.g.
+//
+//	SESSIONS_TEST_CONSUL=localhost:8500 go test
+func Test_Conformance(t *testing.T) {
+	addr := os.Getenv("SESSIONS_TEST_CONSUL")
+	if addr == "" {
+		t.Skip("SESSIONS_TEST_CONSUL is not set")
+	}
+	client, err := api.NewClient(&api.Config{Address: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.KV().DeleteTree("sessions_test/", nil)
+
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		return New(client, Options{Prefix: "sessions_test/" + strconv.Itoa(n) + "/"})
+	}, storetest.Options{})
+}
@@ -0,0 +1,76 @@
+package sessions
+
+import (
+	"fmt"
+)
+
+// Counter is an interface that represents a Store keeping counters beside
+// the session values and changing them atomically, so concurrent requests of
+// one session don't lose updates. It is implemented by the Redis store and
+// the server-side stores whose backend is a CounterBackend.
+//
+// When the store passed to Sessions is a Counter, Session.Increment uses it,
+// saving new sessions first. Such counters are not part of the session
+// values saved by the store, Session.Get and GetInt read them from the store
+// instead.
+type Counter interface {
+	// Store is an embedded interface so that Counter can be used
+	// as a session store.
+	Store
+	// Increment adds delta to the counter key of the session with the given
+	// ID and returns the new value. ok is false if the session is not stored
+	// or the store can not keep counters for it.
+	Increment(id, key string, delta int64) (n int64, ok bool, err error)
+}
+
+func (s *session) Increment(name string, key string, delta int64) int64 {
+	session := s.Session(name)
+	if c, ok := StoreAs[Counter](s.store); ok {
+		if session.ID == "" {
+			// the store needs a record to keep counters with
+			check(s.Save(name), s.logger)
+		}
+		ck := fmt.Sprint(s.key(key))
+		n, ok, err := c.Increment(session.ID, ck, delta)
+		check(err, s.logger)
+		if ok {
+			if _, marked := session.Values[counterMarker(ck)]; !marked {
+				session.Values[counterMarker(ck)] = true
+				s.markDirty(name, counterMarker(ck))
+			}
+			return n
+		}
+	}
+
+	n, _ := s.Get(name, key).(int64)
+	n += delta
+	s.Set(name, key, n)
+	return n
+}
+
+// counterMarker is the key marking in the session that the store keeps the
+// counter key, so that reads of the key go to the store.
+func counterMarker(key string) metadataKey {
+	return metadataKey("counter:" + key)
+}
+
+// storedCounter returns the counter key of the named session if the store
+// keeps it.
+func (s *session) storedCounter(name string, key interface{}) (int64, bool) {
+	session := s.Session(name)
+	ck := fmt.Sprint(s.key(key))
+	if _, ok := session.Values[counterMarker(ck)]; !ok {
+		return 0, false
+	}
+	c, ok := StoreAs[Counter](s.store)
+	if !ok {
+		return 0, false
+	}
+	n, ok, err := c.Increment(session.ID, ck, 0)
+	check(err, s.logger)
+	return n, ok
+}
+
+func (s *session) Decrement(name string, key string, delta int64) int64 {
+	return s.Increment(name, key, -delta)
+}
@@ -0,0 +1,76 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_SessionsIncrement(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMemoryStore(0)
+	defer store.Close()
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		n := session.Increment("my_session", "requests", 1)
+		if _, ok := session.Raw("my_session").Values["requests"]; ok {
+			t.Error("Store counter was mirrored into the session values")
+		}
+		if got, _ := session.Get("my_session", "requests").(int64); got < n {
+			t.Error("Store counter was not read by Get:", got, n)
+		}
+		return "OK"
+	})
+
+	m.Get("/count", func(session Session) string {
+		if n, _ := session.GetInt("my_session", "requests"); n != 11 {
+			t.Error("Expected 11 counted requests, got", n)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Cookie", cookie)
+			m.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	wg.Wait()
+
+	req, _ = http.NewRequest("GET", "/count", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func Test_SessionsDecrement(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		session.Increment("my_session", "credits", 5)
+		if n := session.Decrement("my_session", "credits", 2); n != 3 {
+			t.Error("Expected 3 credits left, got", n)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
@@ -0,0 +1,216 @@
+// Package csrf provides cross-site request forgery protection for Martini
+// applications.
+//
+// DoubleSubmit implements the stateless double-submit cookie pattern: a
+// random token is kept in a cookie and every unsafe request must echo it in
+// a header or form field. No server-side state is needed, which suits
+// applications using the pure sessions.CookieStore.
+//
+//	m.Use(csrf.DoubleSubmit(csrf.Options{Secret: []byte("secret123")}))
+//
+// The cookie is HttpOnly, so scripts sending the token in the header read it
+// from the page, e.g. from a meta tag rendered with Token.
+//
+// Applications with sessions should bind the tokens to the session, so a
+// token obtained with one session is worthless with another:
+//
+//	m.Use(sessions.Sessions(store))
+//	m.Use(csrf.DoubleSubmit(csrf.Options{Secret: []byte("secret123"), Session: "my_session"}))
+//
+//	m.Get("/form", func(x csrf.CSRF) string {
+//		return `<input type="hidden" name="csrf_token" value="` + x.Token() + `">`
+//	})
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+// secretKey holds the random secret of a session that its tokens are bound
+// to.
+const secretKey = "_csrf_secret"
+
+// CSRF is the service mapped by DoubleSubmit.
+type CSRF interface {
+	// Token returns the token to embed in forms or send in the header.
+	Token() string
+}
+
+// Options configures DoubleSubmit.
+type Options struct {
+	// Cookie is the name of the token cookie. Defaults to "_csrf".
+	Cookie string
+	// Header is the request header carrying the token. Defaults to
+	// "X-CSRF-Token".
+	Header string
+	// Field is the form field carrying the token. Defaults to "csrf_token".
+	Field string
+	// Path and Domain of the token cookie. Path defaults to "/".
+	Path   string
+	Domain string
+	Secure bool
+	// SameSite of the token cookie. Defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// Secret, when set, signs the token so that a cookie planted by a
+	// sibling subdomain is rejected.
+	Secret []byte
+	// Session, when set, names the session the token is bound to: a random
+	// secret kept in the session is part of the signature, so a token
+	// issued with one session is rejected with any other. Sessions must be
+	// used before DoubleSubmit.
+	Session string
+	// ErrorFunc writes the response for rejected requests. Defaults to a
+	// plain 403 Forbidden.
+	ErrorFunc func(w http.ResponseWriter)
+}
+
+type csrf struct {
+	token string
+}
+
+func (c *csrf) Token() string {
+	return c.token
+}
+
+// DoubleSubmit is a Middleware that maps a CSRF service and rejects POST,
+// PUT, PATCH and DELETE requests whose header or form token doesn't match
+// the token cookie.
+func DoubleSubmit(options ...Options) martini.Handler {
+	opts := prepareOptions(options)
+
+	return func(res http.ResponseWriter, r *http.Request, c martini.Context) {
+		bind := opts.binding(c)
+		token := ""
+		if cookie, err := r.Cookie(opts.Cookie); err == nil && opts.valid(cookie.Value, bind) {
+			token = cookie.Value
+		}
+
+		if token == "" {
+			token = opts.generate(bind)
+			http.SetCookie(res, &http.Cookie{
+				Name:     opts.Cookie,
+				Value:    token,
+				Path:     opts.Path,
+				Domain:   opts.Domain,
+				Secure:   opts.Secure,
+				HttpOnly: true,
+				SameSite: opts.SameSite,
+			})
+			// a fresh token can't have been submitted yet
+			if unsafe(r.Method) {
+				opts.ErrorFunc(res)
+				return
+			}
+		}
+		c.MapTo(&csrf{token}, (*CSRF)(nil))
+
+		if !unsafe(r.Method) {
+			return
+		}
+		sent := r.Header.Get(opts.Header)
+		if sent == "" {
+			sent = r.FormValue(opts.Field)
+		}
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			opts.ErrorFunc(res)
+		}
+	}
+}
+
+func prepareOptions(options []Options) Options {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = "_csrf"
+	}
+	if opts.Header == "" {
+		opts.Header = "X-CSRF-Token"
+	}
+	if opts.Field == "" {
+		opts.Field = "csrf_token"
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	if opts.ErrorFunc == nil {
+		opts.ErrorFunc = func(w http.ResponseWriter) {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		}
+	}
+	return opts
+}
+
+// binding returns the secret of the session the tokens are bound to,
+// creating it on first use, or "" if they are not bound.
+func (o Options) binding(c martini.Context) string {
+	if o.Session == "" {
+		return ""
+	}
+	v := c.Get(reflect.TypeOf((*sessions.Session)(nil)).Elem())
+	if !v.IsValid() {
+		panic("csrf: Options.Session is set but no sessions.Session is mapped")
+	}
+	s := v.Interface().(sessions.Session)
+	secret, _ := s.Get(o.Session, secretKey).(string)
+	if secret == "" {
+		secret = random()
+		s.Set(o.Session, secretKey, secret)
+	}
+	return secret
+}
+
+func (o Options) generate(bind string) string {
+	token := random()
+	if o.Secret == nil && bind == "" {
+		return token
+	}
+	return token + "." + o.sign(token, bind)
+}
+
+func (o Options) valid(value, bind string) bool {
+	if o.Secret == nil && bind == "" {
+		return value != ""
+	}
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return false
+	}
+	return hmac.Equal([]byte(value[i+1:]), []byte(o.sign(value[:i], bind)))
+}
+
+func (o Options) sign(token, bind string) string {
+	mac := hmac.New(sha256.New, o.Secret)
+	mac.Write([]byte(bind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func random() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func unsafe(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+func Test_DoubleSubmit(t *testing.T) {
+	m := martini.Classic()
+	m.Use(DoubleSubmit(Options{Secret: []byte("secret123")}))
+
+	token := ""
+	m.Get("/form", func(x CSRF) string {
+		token = x.Token()
+		return "OK"
+	})
+	m.Post("/form", func() string {
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/form", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+	if token == "" || !strings.Contains(cookie, token) {
+		t.Fatal("Token cookie was not issued:", cookie)
+	}
+	if !strings.Contains(cookie, "HttpOnly") || !strings.Contains(cookie, "SameSite=Lax") {
+		t.Error("Token cookie lacks HttpOnly or SameSite:", cookie)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/form", strings.NewReader("csrf_token="+token))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Error("Valid form token was rejected:", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/form", nil)
+	req.Header.Set("X-CSRF-Token", "forged")
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusForbidden {
+		t.Error("Forged token was accepted:", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/form", nil)
+	req.Header.Set("X-CSRF-Token", "planted")
+	req.Header.Set("Cookie", "_csrf=planted")
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusForbidden {
+		t.Error("Unsigned cookie was accepted:", res.Code)
+	}
+}
+
+func Test_DoubleSubmitSession(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+	m.Use(DoubleSubmit(Options{Secret: []byte("secret123"), Session: "my_session"}))
+
+	token := ""
+	m.Get("/form", func(x CSRF) string {
+		token = x.Token()
+		return "OK"
+	})
+	m.Post("/form", func() string {
+		return "OK"
+	})
+
+	// visit returns the token and cookies of a fresh session
+	visit := func() (string, []string) {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/form", nil)
+		m.ServeHTTP(res, req)
+		var cookies []string
+		for _, c := range res.Header()["Set-Cookie"] {
+			cookies = append(cookies, strings.Split(c, ";")[0])
+		}
+		return token, cookies
+	}
+	post := func(token string, cookies []string) int {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/form", nil)
+		req.Header.Set("X-CSRF-Token", token)
+		req.Header.Set("Cookie", strings.Join(cookies, "; "))
+		m.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	victimToken, victimCookies := visit()
+	attackerToken, attackerCookies := visit()
+	if code := post(victimToken, victimCookies); code != http.StatusOK {
+		t.Error("Valid token was rejected:", code)
+	}
+
+	// the attacker plants their own token cookie next to the victim session
+	var planted []string
+	for _, c := range victimCookies {
+		if !strings.HasPrefix(c, "_csrf=") {
+			planted = append(planted, c)
+		}
+	}
+	for _, c := range attackerCookies {
+		if strings.HasPrefix(c, "_csrf=") {
+			planted = append(planted, c)
+		}
+	}
+	if code := post(attackerToken, planted); code != http.StatusForbidden {
+		t.Error("Token of another session was accepted:", code)
+	}
+}
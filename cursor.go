@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"net/url"
+	"time"
+)
+
+const (
+	cursorsKey = "_cursors"
+	// maxCursors bounds the number of list views remembered per session.
+	maxCursors = 10
+)
+
+func init() {
+	gob.Register(map[string]Cursor{})
+}
+
+// Cursor is the position and filters of a paginated list view, so a visitor
+// returning to the list lands where they left it.
+type Cursor struct {
+	// Token is an opaque pagination cursor, for keyset pagination.
+	Token string
+	// Page is the page number, for offset pagination.
+	Page    int
+	Filters url.Values
+	Updated time.Time
+}
+
+// SetCursor stores the cursor of the list view in the named session. Only
+// the most recently updated views are kept.
+func SetCursor(s Session, name, view string, c Cursor) {
+	cursors, _ := s.Get(name, cursorsKey).(map[string]Cursor)
+	if cursors == nil {
+		cursors = make(map[string]Cursor)
+	}
+
+	c.Updated = time.Now()
+	cursors[view] = c
+	for len(cursors) > maxCursors {
+		oldest := ""
+		for v, other := range cursors {
+			if oldest == "" || other.Updated.Before(cursors[oldest].Updated) {
+				oldest = v
+			}
+		}
+		delete(cursors, oldest)
+	}
+	s.Set(name, cursorsKey, cursors)
+}
+
+// GetCursor returns the cursor stored for the list view in the named
+// session.
+func GetCursor(s Session, name, view string) (Cursor, bool) {
+	cursors, _ := s.Get(name, cursorsKey).(map[string]Cursor)
+	c, ok := cursors[view]
+	return c, ok
+}
+
+// ClearCursor forgets the cursor of the list view in the named session.
+func ClearCursor(s Session, name, view string) {
+	cursors, _ := s.Get(name, cursorsKey).(map[string]Cursor)
+	if _, ok := cursors[view]; ok {
+		delete(cursors, view)
+		s.Set(name, cursorsKey, cursors)
+	}
+}
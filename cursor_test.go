@@ -0,0 +1,42 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Cursor(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/orders", func(session Session) string {
+		SetCursor(session, "my_session", "orders", Cursor{Page: 7, Filters: url.Values{"status": {"open"}}})
+		return "OK"
+	})
+	m.Get("/back", func(session Session) string {
+		c, ok := GetCursor(session, "my_session", "orders")
+		if !ok || c.Page != 7 || c.Filters.Get("status") != "open" {
+			t.Error("Unexpected cursor:", c, ok)
+		}
+		ClearCursor(session, "my_session", "orders")
+		if _, ok := GetCursor(session, "my_session", "orders"); ok {
+			t.Error("Cursor was not cleared")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/orders", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/back", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
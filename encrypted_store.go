@@ -0,0 +1,114 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// sealedKey holds the ciphertext of all values of a session encrypted by an
+// EncryptedStore.
+const sealedKey = "_sealed"
+
+// ErrUndecryptable is returned when a session was encrypted with a key that
+// is no longer configured, or was tampered with.
+var ErrUndecryptable = errors.New("sessions: session values can not be decrypted")
+
+// NewEncryptedStore returns a Store encrypting the values of every session
+// with AES-GCM before they reach store, so server-side backends only ever
+// hold ciphertext.
+//
+// keys must be 16, 24 or 32 bytes long. The first one encrypts; all of them
+// are tried for decryption, so keys are rotated by prepending a new one and
+// dropping the old one once its sessions have expired. Sessions saved
+// before the store was wrapped are read as is and encrypted on their next
+// save.
+func NewEncryptedStore(store Store, keys ...[]byte) (Store, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("sessions: NewEncryptedStore needs at least one key")
+	}
+	e := &encryptedStore{Store: store}
+	for _, key := range keys {
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		e.aeads = append(e.aeads, aead)
+	}
+	return e, nil
+}
+
+type encryptedStore struct {
+	Store
+	aeads []cipher.AEAD
+}
+
+func (e *encryptedStore) Unwrap() Store {
+	return e.Store
+}
+
+func (e *encryptedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(e, name)
+}
+
+func (e *encryptedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := e.Store.New(r, name)
+	if s == nil {
+		return s, err
+	}
+	sealed, ok := s.Values[sealedKey].([]byte)
+	if !ok {
+		return s, err
+	}
+
+	values, openErr := e.open(name, sealed)
+	if openErr != nil {
+		s.Values = make(map[interface{}]interface{})
+		s.IsNew = true
+		return s, openErr
+	}
+	s.Values = values
+	return s, err
+}
+
+func (e *encryptedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.Values); err != nil {
+		return err
+	}
+	nonce := make([]byte, e.aeads[0].NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	// bind the ciphertext to the session name
+	sealed := e.aeads[0].Seal(nonce, nonce, buf.Bytes(), []byte(s.Name()))
+
+	values := s.Values
+	s.Values = map[interface{}]interface{}{sealedKey: sealed}
+	defer func() { s.Values = values }()
+	return e.Store.Save(r, w, s)
+}
+
+func (e *encryptedStore) open(name string, sealed []byte) (map[interface{}]interface{}, error) {
+	for _, aead := range e.aeads {
+		n := aead.NonceSize()
+		if len(sealed) < n {
+			continue
+		}
+		plain, err := aead.Open(nil, sealed[:n], sealed[n:], []byte(name))
+		if err != nil {
+			continue
+		}
+		values := make(map[interface{}]interface{})
+		if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&values); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	return nil, ErrUndecryptable
+}
@@ -0,0 +1,42 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_EncryptedStore(t *testing.T) {
+	inner := NewMemoryStore(0)
+	defer inner.Close()
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("fedcba9876543210")
+
+	store, _ := NewEncryptedStore(inner, oldKey)
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["card"] = "4111"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Values["card"] != "4111" {
+		t.Error("Values were not restored after save")
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	if raw, _ := inner.New(req, "my_session"); raw.Values["card"] != nil || raw.Values[sealedKey] == nil {
+		t.Error("Backend holds plaintext values:", raw.Values)
+	}
+
+	rotated, _ := NewEncryptedStore(inner, newKey, oldKey)
+	if s, err := rotated.New(req, "my_session"); err != nil || s.Values["card"] != "4111" {
+		t.Error("Session was not decrypted with the old key:", s.Values, err)
+	}
+
+	dropped, _ := NewEncryptedStore(inner, newKey)
+	if _, err := dropped.New(req, "my_session"); err != ErrUndecryptable {
+		t.Error("Expected ErrUndecryptable, got", err)
+	}
+}
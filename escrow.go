@@ -0,0 +1,101 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+const escrowVersion = 1
+
+// escrowBundle is the serialized form of an exported key set. The key pairs
+// are sealed with a random AES-256-GCM key, which is itself encrypted to the
+// escrow public key with RSA-OAEP.
+type escrowBundle struct {
+	Version    int       `json:"version"`
+	Created    time.Time `json:"created"`
+	WrappedKey []byte    `json:"wrapped_key"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// ExportKeys writes keyPairs, the keys passed to NewCookieStore or a
+// server-side store, encrypted to the escrow public key pub. Keep the output
+// with the disaster recovery material; ImportKeys restores the keys in a
+// failover region so existing session cookies stay valid there.
+func ExportKeys(w io.Writer, pub *rsa.PublicKey, keyPairs ...[]byte) error {
+	plain, err := json.Marshal(keyPairs)
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(escrowBundle{
+		Version:    escrowVersion,
+		Created:    time.Now().UTC(),
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain, nil),
+	})
+}
+
+// ImportKeys reads a key set written by ExportKeys and decrypts it with the
+// escrow private key. The returned key pairs are passed as is to the store
+// constructor.
+func ImportKeys(r io.Reader, priv *rsa.PrivateKey) ([][]byte, error) {
+	var bundle escrowBundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Version != escrowVersion {
+		return nil, errors.New("sessions: unsupported key escrow version")
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, bundle.WrappedKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, bundle.Nonce, bundle.Ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyPairs [][]byte
+	err = json.Unmarshal(plain, &keyPairs)
+	return keyPairs, err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
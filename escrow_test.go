@@ -0,0 +1,31 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func Test_KeyEscrow(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportKeys(&buf, &priv.PublicKey, []byte("auth-key"), []byte("encryption-key-1")); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("auth-key")) {
+		t.Fatal("Exported keys are readable in plain text")
+	}
+
+	keys, err := ImportKeys(&buf, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[0]) != "auth-key" || string(keys[1]) != "encryption-key-1" {
+		t.Error("Unexpected imported keys:", keys)
+	}
+}
@@ -0,0 +1,121 @@
+// Package etcdstore provides an etcd based storage for martini-contrib
+// sessions.
+//
+//	store := etcdstore.New(client, "/sessions/")
+//	m.Use(sessions.Sessions(store))
+package etcdstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Store is an interface that represents an etcd based storage for Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// New returns a new Store keeping sessions in etcd under keys starting with
+// prefix.
+//
+// Every save attaches the key to a new lease lasting Options.MaxAge, so etcd
+// deletes sessions once they expire, and revokes the lease of the previous
+// save.
+func New(client *clientv3.Client, prefix string) Store {
+	return sessions.NewServerStore(&etcdBackend{client, prefix})
+}
+
+type etcdBackend struct {
+	client *clientv3.Client
+	prefix string
+}
+
+func (e *etcdBackend) Load(id string) ([]byte, error) {
+	resp, err := e.client.Get(context.Background(), e.prefix+id)
+	if err != nil || len(resp.Kvs) == 0 {
+		return nil, err
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (e *etcdBackend) Save(id string, data []byte, ttl time.Duration) error {
+	ctx := context.Background()
+	lease, err := e.client.Grant(ctx, int64(ttl/time.Second))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Put(ctx, e.prefix+id, string(data), clientv3.WithLease(lease.ID), clientv3.WithPrevKV())
+	if err != nil {
+		return err
+	}
+	if resp.PrevKv != nil {
+		return e.revoke(ctx, clientv3.LeaseID(resp.PrevKv.Lease))
+	}
+	return nil
+}
+
+func (e *etcdBackend) Touch(id string, ttl time.Duration) error {
+	ctx := context.Background()
+	lease, err := e.client.Grant(ctx, int64(ttl/time.Second))
+	if err != nil {
+		return err
+	}
+	// attach the key to the new lease, keeping its value
+	resp, err := e.client.Put(ctx, e.prefix+id, "", clientv3.WithIgnoreValue(), clientv3.WithLease(lease.ID), clientv3.WithPrevKV())
+	if err == rpctypes.ErrKeyNotFound {
+		return e.revoke(ctx, lease.ID)
+	} else if err != nil {
+		return err
+	}
+	if resp.PrevKv != nil {
+		return e.revoke(ctx, clientv3.LeaseID(resp.PrevKv.Lease))
+	}
+	return nil
+}
+
+func (e *etcdBackend) Delete(id string) error {
+	ctx := context.Background()
+	resp, err := e.client.Delete(ctx, e.prefix+id, clientv3.WithPrevKV())
+	if err != nil {
+		return err
+	}
+	for _, kv := range resp.PrevKvs {
+		if err := e.revoke(ctx, clientv3.LeaseID(kv.Lease)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revoke drops a lease that no key is attached to anymore, so leases don't
+// pile up until they expire.
+func (e *etcdBackend) revoke(ctx context.Context, lease clientv3.LeaseID) error {
+	if lease == clientv3.NoLease {
+		return nil
+	}
+	_, err := e.client.Revoke(ctx, lease)
+	if err == rpctypes.ErrLeaseNotFound {
+		// expired meanwhile
+		return nil
+	}
+	return err
+}
+
+func (e *etcdBackend) DeletePrefix(prefix string) error {
+	_, err := e.client.Delete(context.Background(), e.prefix+prefix, clientv3.WithPrefix())
+	return err
+}
+
+func (e *etcdBackend) Ping(ctx context.Context) error {
+	_, err := e.client.Get(ctx, e.prefix, clientv3.WithCountOnly())
+	return err
+}
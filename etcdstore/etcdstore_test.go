@@ -0,0 +1,41 @@
+package etcdstore
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Test_Conformance runs against the cluster at SESSIONS_TEST_ETCD, a comma
+// separated list of endpoints, e.g.
+//
+//	SESSIONS_TEST_ETCD=localhost:2379 go test
+func Test_Conformance(t *testing.T) {
+	endpoints := os.Getenv("SESSIONS_TEST_ETCD")
+	if endpoints == "" {
+		t.Skip("SESSIONS_TEST_ETCD is not set")
+	}
+	client, err := clientv3.New(clientv3.Config{Endpoints: strings.Split(endpoints, ","), DialTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Delete(context.Background(), "/sessions_test/", clientv3.WithPrefix())
+
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		return New(client, "/sessions_test/"+strconv.Itoa(n)+"/")
+	}, storetest.Options{
+		// etcd rounds leases up to its minimum TTL, which is longer than
+		// the MaxAge of a second used by the test
+		SkipExpiry: true,
+	})
+}
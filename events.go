@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// Session lifecycle event types.
+const (
+	EventCreated   = "created"
+	EventDestroyed = "destroyed"
+	EventExpired   = "expired"
+)
+
+// Event describes a change in a session's lifecycle.
+type Event struct {
+	Type string    `json:"type"`
+	Name string    `json:"name"`
+	ID   string    `json:"id,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// EventSink receives session lifecycle events. Send must not block.
+type EventSink interface {
+	Send(Event)
+}
+
+// NewEventStore returns a Store that reports sessions being created,
+// destroyed and expired to sink. A session counts as created when it is
+// first saved and as destroyed when saved with a negative MaxAge. Expiry is
+// reported when the cleanup of an ExpiryNotifier, such as the memory,
+// filesystem and SQL stores, deletes a session; those events have no Name.
+// Stores whose backend expires sessions by itself never report expiry.
+func NewEventStore(store Store, sink EventSink) Store {
+	if n, ok := StoreAs[ExpiryNotifier](store); ok {
+		n.OnExpire(func(id string) {
+			sink.Send(Event{Type: EventExpired, ID: id, Time: time.Now()})
+		})
+	}
+	return &eventStore{Store: store, sink: sink}
+}
+
+type eventStore struct {
+	Store
+	sink EventSink
+}
+
+func (e *eventStore) Unwrap() Store {
+	return e.Store
+}
+
+func (e *eventStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if err := e.Store.Save(r, w, s); err != nil {
+		return err
+	}
+
+	switch {
+	case s.Options != nil && s.Options.MaxAge < 0:
+		e.sink.Send(Event{Type: EventDestroyed, Name: s.Name(), ID: s.ID, Time: time.Now()})
+	case s.IsNew:
+		e.sink.Send(Event{Type: EventCreated, Name: s.Name(), ID: s.ID, Time: time.Now()})
+		s.IsNew = false
+	}
+	return nil
+}
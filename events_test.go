@@ -0,0 +1,56 @@
+package sessions
+
+import (
+	"container/list"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+type sliceSink []Event
+
+func (s *sliceSink) Send(e Event) {
+	*s = append(*s, e)
+}
+
+// agedBackend is a memory backend whose sessions expire as soon as a
+// cleanup runs.
+type agedBackend struct {
+	*memoryBackend
+}
+
+func (a agedBackend) PurgeExpired() ([]string, error) {
+	a.mu.Lock()
+	for _, el := range a.entries {
+		el.Value.(*memoryEntry).expires = time.Now().Add(-time.Second)
+	}
+	a.mu.Unlock()
+	return a.memoryBackend.PurgeExpired()
+}
+
+func Test_EventStoreExpired(t *testing.T) {
+	backend := agedBackend{&memoryBackend{lru: list.New(), entries: make(map[string]*list.Element)}}
+	store := NewServerStore(backend)
+	var sink sliceSink
+
+	m := martini.Classic()
+	m.Use(Sessions(NewEventStore(store, &sink)))
+	m.Get("/login", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+
+	if err := store.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink) != 2 || sink[1].Type != EventExpired || sink[1].ID != sink[0].ID || sink[0].ID == "" {
+		t.Error("Unexpected events:", sink)
+	}
+}
@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"time"
+)
+
+func init() {
+	gob.Register(expiringValue{})
+}
+
+// expiringValue is the stored form of a value set with SetWithTTL.
+type expiringValue struct {
+	Value   interface{}
+	Expires time.Time
+}
+
+func (s *session) SetWithTTL(name string, key interface{}, val interface{}, ttl time.Duration) {
+	s.Set(name, key, expiringValue{Value: val, Expires: time.Now().Add(ttl)})
+}
+
+// unwrap returns the value stored at key, removing it if it has expired.
+func (s *session) unwrap(name string, key interface{}) (interface{}, bool) {
+	val, ok := s.Session(name).Values[s.key(key)]
+	if !ok {
+		if n, stored := s.storedCounter(name, key); stored {
+			return n, true
+		}
+	}
+	e, expiring := val.(expiringValue)
+	if !expiring {
+		return val, ok
+	}
+	if time.Now().After(e.Expires) {
+		s.Delete(name, key)
+		return nil, false
+	}
+	return e.Value, true
+}
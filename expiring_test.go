@@ -0,0 +1,50 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_SessionsSetWithTTL(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		session.SetWithTTL("my_session", "otp", "123456", time.Hour)
+		session.SetWithTTL("my_session", "grant", "file.zip", time.Millisecond)
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "otp") != "123456" {
+			t.Error("Value was not returned before it expired")
+		}
+		if otp, ok := session.GetString("my_session", "otp"); !ok || otp != "123456" {
+			t.Error("Typed getter did not unwrap the value")
+		}
+		if _, ok := session.GetOk("my_session", "grant"); ok {
+			t.Error("Expired value was returned")
+		}
+		if session.Len("my_session") != 1 {
+			t.Error("Expired value was not removed on read")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	time.Sleep(5 * time.Millisecond)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
@@ -0,0 +1,98 @@
+package sessions
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// NewFailoverStore returns a Store reading and writing primary, falling
+// back to secondary whenever primary can't be reached. Once primary was
+// unreachable it is only tried again after probeInterval, and used again as
+// soon as a probe succeeds. Other errors of primary, such as a session
+// failing to decode, are returned as is.
+//
+// Sessions saved to secondary during an outage are found there after
+// primary recovers and move back to primary on their next save. Both stores
+// must share the same cookie format, e.g. two server-side stores.
+func NewFailoverStore(primary, secondary Store, probeInterval time.Duration) Store {
+	return &failoverStore{primary: primary, secondary: secondary, interval: probeInterval}
+}
+
+type failoverStore struct {
+	primary   Store
+	secondary Store
+	interval  time.Duration
+
+	mu     sync.Mutex
+	downAt time.Time
+}
+
+// usePrimary reports whether primary is healthy or due for a probe.
+func (f *failoverStore) usePrimary() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.downAt.IsZero() || time.Since(f.downAt) >= f.interval
+}
+
+// report records the outcome of a call to primary. Only errors reaching the
+// backend mark it down, any answer marks it up again.
+func (f *failoverStore) report(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if unreachable(err) {
+		f.downAt = time.Now()
+	} else {
+		f.downAt = time.Time{}
+	}
+}
+
+func (f *failoverStore) Delete(id string) error {
+	return deleteAll(id, f.primary, f.secondary)
+}
+
+func (f *failoverStore) Exists(id string) (bool, error) {
+	return existsAny(id, f.primary, f.secondary)
+}
+
+func (f *failoverStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, f.primary, f.secondary)
+}
+
+func (f *failoverStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(f, name)
+}
+
+func (f *failoverStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	if f.usePrimary() {
+		s, err := f.primary.New(r, name)
+		f.report(err)
+		if err != nil && !unreachable(err) {
+			return s, err
+		}
+		if err == nil && !s.IsNew {
+			return s, nil
+		}
+		if err == nil {
+			if _, cookieErr := r.Cookie(name); cookieErr != nil {
+				return s, nil
+			}
+			// the session may have been saved during an outage
+		}
+	}
+
+	return f.secondary.New(r, name)
+}
+
+func (f *failoverStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if f.usePrimary() {
+		err := f.primary.Save(r, w, s)
+		f.report(err)
+		if !unreachable(err) {
+			return err
+		}
+	}
+	return f.secondary.Save(r, w, s)
+}
@@ -0,0 +1,56 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_FailoverStore(t *testing.T) {
+	primary := &flakyStore{Store: NewCookieStore([]byte("secret123"))}
+	secondary := NewCookieStore([]byte("secret123"))
+	store := NewFailoverStore(primary, secondary, time.Hour)
+
+	primary.err = errRefused
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, err := store.New(req, "my_session")
+	if err != nil {
+		t.Fatal("Read did not fall back to the secondary:", err)
+	}
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal("Write did not fall back to the secondary:", err)
+	}
+
+	// primary is not probed again before the interval has passed
+	primary.err = nil
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	if s, err := store.New(req, "my_session"); err != nil || s.Values["hello"] != "world" {
+		t.Error("Session saved during the outage was lost:", err)
+	}
+}
+
+func Test_FailoverStoreOtherErrors(t *testing.T) {
+	primary := &flakyStore{Store: NewCookieStore([]byte("secret123"))}
+	secondary := NewInstrumentedStore(NewCookieStore([]byte("secret123")))
+	store := NewFailoverStore(primary, secondary, time.Hour)
+
+	primary.err = ErrQuotaExceeded
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	if err := store.Save(req, httptest.NewRecorder(), s); err != ErrQuotaExceeded {
+		t.Error("Error of the primary was not returned:", err)
+	}
+	if n := secondary.Stats().Saves; n != 0 {
+		t.Error("Secondary was used although the primary answered:", n)
+	}
+
+	// the primary was not marked down
+	primary.err = nil
+	if err := store.Save(req, httptest.NewRecorder(), s); err != nil || secondary.Stats().Saves != 0 {
+		t.Error("Primary was marked down by an error not about reaching it:", err)
+	}
+}
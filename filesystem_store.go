@@ -0,0 +1,191 @@
+package sessions
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FilesystemStore is an interface that represents a file based storage for
+// Sessions.
+type FilesystemStore interface {
+	// Store is an embedded interface so that FilesystemStore can be used
+	// as a session store.
+	Store
+	// Options sets the default options for each session stored in this
+	// FilesystemStore.
+	Options(Options)
+	// GC deletes the files of all expired sessions.
+	GC() error
+	// Close stops the background garbage collection.
+	Close()
+}
+
+// NewFilesystemStore returns a new FilesystemStore keeping a file per
+// session below dir, sharded into two levels of subdirectories by the first
+// characters of the session ID so no directory grows too large.
+//
+// Every gcInterval a background goroutine deletes the files of sessions
+// past Options.MaxAge. A gcInterval of 0 disables it, leaving GC to the
+// caller.
+func NewFilesystemStore(dir string, gcInterval time.Duration) (FilesystemStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	backend := &filesystemBackend{dir: dir}
+	store := &filesystemStore{ServerStore: NewServerStore(backend), backend: backend, stop: func() {}}
+	if gcInterval > 0 {
+		store.stop = store.StartCleanup(gcInterval)
+	}
+	return store, nil
+}
+
+type filesystemStore struct {
+	*ServerStore
+	backend *filesystemBackend
+	stop    func()
+}
+
+func (s *filesystemStore) GC() error {
+	return s.purge(s.backend)
+}
+
+func (s *filesystemStore) Close() {
+	s.stop()
+}
+
+// filesystemBackend stores each session as written by WithExpiry.
+type filesystemBackend struct {
+	dir string
+}
+
+// path returns the file of id, which is validated by ServerStore and can
+// not escape dir. Files are sharded by the random part of the ID.
+func (f *filesystemBackend) path(id string) string {
+	random := id[len(id)-64:]
+	return filepath.Join(f.dir, random[:2], random[2:4], id)
+}
+
+func (f *filesystemBackend) Load(id string) ([]byte, error) {
+	v, err := ioutil.ReadFile(f.path(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return UnlessExpired(v), nil
+}
+
+func (f *filesystemBackend) Save(id string, data []byte, ttl time.Duration) error {
+	path := f.path(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see partial data,
+	// with a unique name so concurrent saves do not write the same file
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(WithExpiry(data, ttl)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Touch rewrites the expiry in front of the data in place.
+func (f *filesystemBackend) Touch(id string, ttl time.Duration) error {
+	file, err := os.OpenFile(f.path(id), os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	expiry := make([]byte, 8)
+	if _, err := file.ReadAt(expiry, 0); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if ExpiresAt(expiry).Unix() < time.Now().Unix() {
+		return nil
+	}
+	_, err = file.WriteAt(WithExpiry(nil, ttl), 0)
+	return err
+}
+
+func (f *filesystemBackend) Delete(id string) error {
+	err := os.Remove(f.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (f *filesystemBackend) Scan(fn func(id string, data []byte, expires time.Time) error) error {
+	return filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !validID(info.Name()) {
+			return nil
+		}
+
+		v, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if data := UnlessExpired(v); data != nil {
+			return fn(info.Name(), data, ExpiresAt(v))
+		}
+		return nil
+	})
+}
+
+func (f *filesystemBackend) PurgeExpired() ([]string, error) {
+	var ids []string
+	err := filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !validID(info.Name()) {
+			return nil
+		}
+
+		v, err := ioutil.ReadFile(path)
+		if err == nil && UnlessExpired(v) == nil {
+			if err = os.Remove(path); err == nil {
+				ids = append(ids, info.Name())
+			}
+		}
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	})
+	return ids, err
+}
@@ -0,0 +1,64 @@
+package sessions
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_FilesystemStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFilesystemStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, s.ID[:2], s.ID[2:4], s.ID)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("Session file was not sharded:", err)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	if s, _ := store.New(req, "my_session"); s.Values["hello"] != "world" {
+		t.Error("Session was not loaded back:", s.Values)
+	}
+
+	// concurrent saves of the same session must not clash on a temporary file
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if files, _ := ioutil.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Error("Temporary files were left behind:", len(files))
+	}
+
+	ioutil.WriteFile(path, WithExpiry([]byte{}, -time.Minute), 0600)
+	if err := store.GC(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expired session file was not collected")
+	}
+}
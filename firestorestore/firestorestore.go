@@ -0,0 +1,109 @@
+// Package firestorestore provides a Firestore based storage for
+// martini-contrib sessions.
+//
+//	store := firestorestore.New(client, "sessions")
+//	m.Use(sessions.Sessions(store))
+package firestorestore
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/martini-contrib/sessions"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store is an interface that represents a Firestore based storage for
+// Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// New returns a new Store keeping a document per session in collection.
+// Clients created while FIRESTORE_EMULATOR_HOST is set talk to the local
+// emulator.
+//
+// Documents carry their expiry, derived from Options.MaxAge, in the
+// expires_at field. Expired documents are never read; to have Firestore
+// delete them, enable a TTL policy on that field:
+//
+//	gcloud firestore fields ttls update expires_at --collection-group=sessions
+func New(client *firestore.Client, collection string) Store {
+	return sessions.NewServerStore(&firestoreBackend{client.Collection(collection)})
+}
+
+type firestoreBackend struct {
+	coll *firestore.CollectionRef
+}
+
+type firestoreSession struct {
+	Data    []byte    `firestore:"data"`
+	Expires time.Time `firestore:"expires_at"`
+}
+
+func (f *firestoreBackend) Load(id string) ([]byte, error) {
+	snap, err := f.coll.Doc(id).Get(context.Background())
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var doc firestoreSession
+	if err := snap.DataTo(&doc); err != nil {
+		return nil, err
+	}
+	// TTL policies delete documents up to a day after they expire
+	if time.Now().After(doc.Expires) {
+		return nil, nil
+	}
+	return doc.Data, nil
+}
+
+func (f *firestoreBackend) Save(id string, data []byte, ttl time.Duration) error {
+	_, err := f.coll.Doc(id).Set(context.Background(), firestoreSession{Data: data, Expires: time.Now().Add(ttl)})
+	return err
+}
+
+func (f *firestoreBackend) Touch(id string, ttl time.Duration) error {
+	ctx := context.Background()
+	snap, err := f.coll.Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var doc firestoreSession
+	if err := snap.DataTo(&doc); err != nil || time.Now().After(doc.Expires) {
+		return err
+	}
+
+	// a session saved since it was read keeps its new expiry
+	_, err = f.coll.Doc(id).Update(ctx, []firestore.Update{{Path: "expires_at", Value: time.Now().Add(ttl)}},
+		firestore.LastUpdateTime(snap.UpdateTime))
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil
+	}
+	return err
+}
+
+func (f *firestoreBackend) Delete(id string) error {
+	_, err := f.coll.Doc(id).Delete(context.Background())
+	return err
+}
+
+func (f *firestoreBackend) Ping(ctx context.Context) error {
+	_, err := f.coll.Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package firestorestore
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+)
+
+// Test_Conformance runs against the emulator at FIRESTORE_EMULATOR_HOST,
+// e.g.
+//
+//	gcloud emulators firestore start --host-port=localhost:8080
+//	FIRESTORE_EMULATOR_HOST=localhost:8080 go test
+func Test_Conformance(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	client, err := firestore.NewClient(context.Background(), "sessions-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		return New(client, "sessions_test_"+strconv.Itoa(n))
+	}, storetest.Options{})
+}
@@ -0,0 +1,104 @@
+// Package flags keeps feature flag assignments in a martini-contrib session,
+// so a visitor sees the same variant on every request and the flag provider
+// is asked at most once per TTL.
+//
+//	m.Get("/", func(s sessions.Session) string {
+//		f := flags.New(s, "my_session", provider.Evaluate)
+//		if f.Enabled("new_header") {
+//			...
+//		}
+//	})
+package flags
+
+import (
+	"encoding/gob"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+)
+
+const key = "_flags"
+
+func init() {
+	gob.Register(map[string]assignment{})
+}
+
+// Provider evaluates a flag for the current visitor.
+type Provider func(flag string) (interface{}, error)
+
+// Options configures Flags.
+type Options struct {
+	// TTL is how long an assignment is kept before the provider is asked
+	// again. Defaults to five minutes.
+	TTL time.Duration
+	// Refresh, when set, is called every time a flag is evaluated by the
+	// provider, with the previous value (nil if there was none).
+	Refresh func(flag string, old, new interface{})
+}
+
+type assignment struct {
+	Value   interface{}
+	Expires time.Time
+}
+
+// Flags reads feature flags through a session cache.
+type Flags struct {
+	session  sessions.Session
+	name     string
+	provider Provider
+	opts     Options
+}
+
+// New returns Flags cached in the named session and evaluated by provider.
+func New(s sessions.Session, name string, provider Provider, options ...Options) *Flags {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.TTL == 0 {
+		opts.TTL = 5 * time.Minute
+	}
+	return &Flags{session: s, name: name, provider: provider, opts: opts}
+}
+
+// Get returns the value of flag, evaluating it if no unexpired assignment
+// is stored in the session.
+func (f *Flags) Get(flag string) (interface{}, error) {
+	assignments, _ := f.session.Get(f.name, key).(map[string]assignment)
+	a, ok := assignments[flag]
+	if ok && time.Now().Before(a.Expires) {
+		return a.Value, nil
+	}
+
+	val, err := f.provider(flag)
+	if err != nil {
+		return nil, err
+	}
+	if f.opts.Refresh != nil {
+		f.opts.Refresh(flag, a.Value, val)
+	}
+
+	if assignments == nil {
+		assignments = make(map[string]assignment)
+	}
+	assignments[flag] = assignment{Value: val, Expires: time.Now().Add(f.opts.TTL)}
+	f.session.Set(f.name, key, assignments)
+	return val, nil
+}
+
+// Enabled reports whether flag evaluates to true. Evaluation errors count
+// as disabled.
+func (f *Flags) Enabled(flag string) bool {
+	val, err := f.Get(flag)
+	return err == nil && val == true
+}
+
+// Invalidate drops the stored assignment of flag, so the next Get asks the
+// provider again.
+func (f *Flags) Invalidate(flag string) {
+	assignments, _ := f.session.Get(f.name, key).(map[string]assignment)
+	if _, ok := assignments[flag]; ok {
+		delete(assignments, flag)
+		f.session.Set(f.name, key, assignments)
+	}
+}
@@ -0,0 +1,46 @@
+package flags
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+func Test_Flags(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+
+	calls := 0
+	refreshed := 0
+	provider := func(flag string) (interface{}, error) {
+		calls++
+		return true, nil
+	}
+	m.Get("/", func(s sessions.Session) string {
+		f := New(s, "my_session", provider, Options{
+			TTL:     time.Hour,
+			Refresh: func(string, interface{}, interface{}) { refreshed++ },
+		})
+		if !f.Enabled("beta") {
+			t.Error("Flag should be enabled")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+
+	if calls != 1 || refreshed != 1 {
+		t.Error("Provider should be called once per TTL, got", calls, refreshed)
+	}
+}
@@ -0,0 +1,47 @@
+package sessions
+
+import (
+	"html/template"
+)
+
+// defaultFlashKey is the key gorilla stores flashes under when no category
+// is given.
+const defaultFlashKey = "_flash"
+
+// FlashFuncs returns template helpers exposing the flash messages of the
+// named session, grouped by category. A category is the optional flash key
+// accepted by AddFlash and Flashes.
+//
+//	{{if hasFlashes "error"}}
+//	  <ul class="errors">{{range flashes "error"}}<li>{{.}}</li>{{end}}</ul>
+//	{{end}}
+//
+// flashes consumes the messages just like Session.Flashes, while hasFlashes
+// only checks for them and leaves the session untouched. flashesByLevel
+// consumes the messages added with Session.AddFlashLevel:
+//
+//	{{range $level, $msgs := flashesByLevel}}
+//	  {{range $msgs}}<p class="alert-{{$level}}">{{.}}</p>{{end}}
+//	{{end}}
+func FlashFuncs(s Session, name string) template.FuncMap {
+	return template.FuncMap{
+		"flashes": func(category ...string) []interface{} {
+			return s.Flashes(name, category...)
+		},
+		"flashesByLevel": func() map[FlashLevel][]string {
+			return s.FlashesByLevel(name)
+		},
+		"hasFlashes": func(category ...string) bool {
+			return s.HasFlashes(name, category...)
+		},
+	}
+}
+
+// flashKey returns the key flashes of the optional category are stored
+// under.
+func flashKey(category []string) string {
+	if len(category) > 0 {
+		return category[0]
+	}
+	return defaultFlashKey
+}
@@ -0,0 +1,42 @@
+package sessions
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_FlashFuncs(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		session.AddFlash("my_session", "oops", "error")
+		session.AddFlash("my_session", "saved")
+
+		tmpl := template.Must(template.New("flashes").Funcs(FlashFuncs(session, "my_session")).Parse(
+			`{{if hasFlashes "error"}}{{range flashes "error"}}{{.}}{{end}}{{end}}|{{hasFlashes "warning"}}|{{range flashes}}{{.}}{{end}}`))
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "oops|false|saved" {
+			t.Error("Unexpected flash rendering:", buf.String())
+		}
+		if len(session.Flashes("my_session", "error")) != 0 {
+			t.Error("Rendered flashes were not consumed")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
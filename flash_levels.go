@@ -0,0 +1,47 @@
+package sessions
+
+import (
+	"encoding/gob"
+)
+
+func init() {
+	gob.Register(leveledFlash{})
+}
+
+// FlashLevel is the severity of a flash message, e.g. to pick its style.
+type FlashLevel string
+
+// Flash levels commonly used by templates. Any other level works as well.
+const (
+	FlashSuccess FlashLevel = "success"
+	FlashInfo    FlashLevel = "info"
+	FlashWarning FlashLevel = "warning"
+	FlashError   FlashLevel = "error"
+)
+
+// flashLevelsKey is the flash key leveled messages are stored under.
+const flashLevelsKey = "_flash_levels"
+
+// leveledFlash is the stored form of a message added with AddFlashLevel.
+type leveledFlash struct {
+	Level   FlashLevel
+	Message string
+}
+
+func (s *session) AddFlashLevel(name string, level FlashLevel, msg string) {
+	s.AddFlash(name, leveledFlash{Level: level, Message: msg}, flashLevelsKey)
+}
+
+func (s *session) FlashesByLevel(name string) map[FlashLevel][]string {
+	flashes := s.Flashes(name, flashLevelsKey)
+	if len(flashes) == 0 {
+		return nil
+	}
+	byLevel := make(map[FlashLevel][]string)
+	for _, f := range flashes {
+		if lf, ok := f.(leveledFlash); ok {
+			byLevel[lf.Level] = append(byLevel[lf.Level], lf.Message)
+		}
+	}
+	return byLevel
+}
@@ -0,0 +1,43 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_FlashLevels(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/set", func(session Session) string {
+		session.AddFlashLevel("my_session", FlashSuccess, "Saved")
+		session.AddFlashLevel("my_session", FlashError, "Name is missing")
+		session.AddFlashLevel("my_session", FlashError, "Email is invalid")
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		byLevel := session.FlashesByLevel("my_session")
+		if len(byLevel[FlashSuccess]) != 1 || len(byLevel[FlashError]) != 2 || byLevel[FlashError][1] != "Email is invalid" {
+			t.Error("Flashes were not grouped by level:", byLevel)
+		}
+		if session.FlashesByLevel("my_session") != nil {
+			t.Error("Flashes were not consumed")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/set", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
@@ -0,0 +1,60 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+const geoKey = "_geo"
+
+func init() {
+	gob.Register(GeoInfo{})
+}
+
+// GeoInfo describes where a client IP is located.
+type GeoInfo struct {
+	IP      string
+	Country string
+	Region  string
+	City    string
+	ASN     uint32
+	Org     string
+	// Resolved is when the lookup was made.
+	Resolved time.Time
+}
+
+// GeoResolver looks up the location of a client IP, typically backed by a
+// GeoIP database or service.
+type GeoResolver interface {
+	Resolve(ip string) (GeoInfo, error)
+}
+
+// Enrich is a Middleware that resolves the client IP with resolver, caches
+// the result in the named session and maps it as a GeoInfo into the Martini
+// handler chain. The resolver is only called again when the client IP
+// changes or the cached result is older than ttl. It must be used after
+// Sessions.
+func Enrich(name string, resolver GeoResolver, ttl time.Duration) martini.Handler {
+	return func(r *http.Request, s Session, c martini.Context, l *log.Logger) {
+		ip := clientIP(r)
+
+		info, ok := s.Get(name, geoKey).(GeoInfo)
+		if !ok || info.IP != ip || time.Since(info.Resolved) > ttl {
+			resolved, err := resolver.Resolve(ip)
+			if err == nil {
+				resolved.IP = ip
+				resolved.Resolved = time.Now()
+				info = resolved
+				s.Set(name, geoKey, info)
+			} else {
+				check(err, l)
+			}
+		}
+
+		c.Map(info)
+	}
+}
@@ -0,0 +1,49 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+type countingResolver int
+
+func (c *countingResolver) Resolve(ip string) (GeoInfo, error) {
+	*c++
+	return GeoInfo{Country: "NZ"}, nil
+}
+
+func Test_Enrich(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	resolver := new(countingResolver)
+	m.Use(Sessions(store))
+	m.Use(Enrich("my_session", resolver, time.Hour))
+
+	m.Get("/", func(geo GeoInfo) string {
+		if geo.Country != "NZ" {
+			t.Error("Unexpected geo info:", geo)
+		}
+		return "OK"
+	})
+
+	cookie := ""
+	for _, ip := range []string{"10.0.0.1:1", "10.0.0.1:2", "10.0.0.2:1"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		if c := res.Header().Get("Set-Cookie"); c != "" {
+			cookie = c
+		}
+	}
+
+	if *resolver != 2 {
+		t.Error("Expected 2 lookups, got", *resolver)
+	}
+}
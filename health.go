@@ -0,0 +1,66 @@
+package sessions
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+// healthTimeout bounds the checks of HealthHandler.
+const healthTimeout = 2 * time.Second
+
+// HealthChecker is implemented by stores that depend on a backend service,
+// such as the Redis, memcached, SQL, MongoDB, etcd, Consul, NATS and
+// Firestore stores.
+type HealthChecker interface {
+	// Ping returns an error if the backend can not be reached.
+	Ping(ctx context.Context) error
+}
+
+// HealthHandler returns a handler replying 200 OK if all checkers can reach
+// their backend and 503 Service Unavailable otherwise, so load balancers
+// stop routing to instances that can not load sessions.
+//
+//	checker, _ := sessions.StoreAs[sessions.HealthChecker](store)
+//	m.Get("/healthz", sessions.HealthHandler(checker))
+func HealthHandler(checkers ...HealthChecker) martini.Handler {
+	return func(res http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+		defer cancel()
+
+		for _, c := range checkers {
+			if err := c.Ping(ctx); err != nil {
+				http.Error(res, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		res.Write([]byte("OK"))
+	}
+}
+
+// Pinger is implemented by backends of server-side stores depending on a
+// backend service.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+func (s *ServerStore) Ping(ctx context.Context) error {
+	if p, ok := s.backend.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// unreachable reports whether err means the backend of a store could not be
+// reached, as opposed to e.g. a cookie or stored value failing to decode.
+func unreachable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,35 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+type pingFunc func(ctx context.Context) error
+
+func (p pingFunc) Ping(ctx context.Context) error { return p(ctx) }
+
+func Test_HealthHandler(t *testing.T) {
+	var down error
+	m := martini.Classic()
+	m.Get("/healthz", HealthHandler(pingFunc(func(context.Context) error { return down })))
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Error("Healthy backend reported as down:", res.Code)
+	}
+
+	down = errors.New("connection refused")
+	res = httptest.NewRecorder()
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusServiceUnavailable {
+		t.Error("Unreachable backend reported as healthy:", res.Code)
+	}
+}
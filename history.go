@@ -0,0 +1,74 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"net/http"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+const historyKey = "_history"
+
+// defaultHistory is the number of visits TrackHistory keeps by default.
+const defaultHistory = 10
+
+func init() {
+	gob.Register([]Visit{})
+}
+
+// Visit is a page recorded by TrackHistory.
+type Visit struct {
+	Path string
+	Time time.Time
+}
+
+// Navigation is the service mapped by TrackHistory.
+type Navigation interface {
+	// History returns the recorded visits, the current request first.
+	History() []Visit
+	// Back returns the path visited before the current request, or an
+	// empty string if there is none.
+	Back() string
+}
+
+// TrackHistory is a Middleware that records the last max GET requests in the
+// named session and maps a Navigation service into the Martini handler chain.
+// A max of 0 or less keeps the last 10. Reloads of the same path are recorded
+// once. It must be used after Sessions.
+func TrackHistory(name string, max int) martini.Handler {
+	if max <= 0 {
+		max = defaultHistory
+	}
+	return func(r *http.Request, s Session, c martini.Context) {
+		visits, _ := s.Get(name, historyKey).([]Visit)
+
+		if r.Method == "GET" {
+			path := r.URL.RequestURI()
+			if len(visits) > 0 && visits[0].Path == path {
+				visits[0].Time = time.Now()
+			} else {
+				visits = append([]Visit{{Path: path, Time: time.Now()}}, visits...)
+			}
+			if len(visits) > max {
+				visits = visits[:max]
+			}
+			s.Set(name, historyKey, visits)
+		}
+
+		c.MapTo(navigation(visits), (*Navigation)(nil))
+	}
+}
+
+type navigation []Visit
+
+func (n navigation) History() []Visit {
+	return n
+}
+
+func (n navigation) Back() string {
+	if len(n) < 2 {
+		return ""
+	}
+	return n[1].Path
+}
@@ -0,0 +1,62 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_TrackHistory(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+	m.Use(TrackHistory("my_session", 2))
+
+	var nav Navigation
+	m.Get("/**", func(n Navigation) string {
+		nav = n
+		return "OK"
+	})
+
+	cookie := ""
+	for _, path := range []string{"/a", "/b", "/b", "/c"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		cookie = res.Header().Get("Set-Cookie")
+	}
+
+	h := nav.History()
+	if len(h) != 2 || h[0].Path != "/c" || h[1].Path != "/b" {
+		t.Error("Unexpected history:", h)
+	}
+	if nav.Back() != "/b" {
+		t.Error("Unexpected back path:", nav.Back())
+	}
+}
+
+func Test_TrackHistoryDefault(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+	m.Use(TrackHistory("my_session", -1))
+
+	var nav Navigation
+	m.Get("/**", func(n Navigation) string {
+		nav = n
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/a", nil)
+	m.ServeHTTP(res, req)
+
+	if h := nav.History(); len(h) != 1 || h[0].Path != "/a" {
+		t.Error("Unexpected history:", h)
+	}
+}
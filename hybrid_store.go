@@ -0,0 +1,168 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// defaultHybridThreshold is the cookie size above which a HybridStore moves
+// values to its backend, leaving room below the 4KB browser limit.
+const defaultHybridThreshold = 3072
+
+func init() {
+	gob.Register(hybridCookie{})
+}
+
+// HybridStore is an interface that represents a storage for Sessions which
+// keeps small sessions in the cookie and large ones in a backend.
+type HybridStore interface {
+	// Store is an embedded interface so that HybridStore can be used
+	// as a session store.
+	Store
+	// Options sets the default options for each session stored in this
+	// HybridStore.
+	Options(Options)
+}
+
+// NewHybridStore returns a new HybridStore. Sessions whose encoded cookie
+// stays within threshold bytes, or 3KB if threshold is 0, are kept in the
+// cookie. Larger ones are saved to backend, which may be the backend of any
+// server-side store, and the cookie only holds their ID; they move back into
+// the cookie once they shrink again.
+//
+// Keys are defined in pairs as for NewCookieStore.
+func NewHybridStore(backend Backend, threshold int, keyPairs ...[]byte) HybridStore {
+	if threshold == 0 {
+		threshold = defaultHybridThreshold
+	}
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, c := range codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			// size is limited by threshold instead
+			sc.MaxLength(0)
+		}
+	}
+	return &hybridStore{
+		backend:   backend,
+		threshold: threshold,
+		codecs:    codecs,
+		options:   &sessions.Options{Path: "/", MaxAge: 86400 * 30},
+	}
+}
+
+// hybridCookie is the content of the cookie, holding either the values of
+// the session or the ID of its values in the backend.
+type hybridCookie struct {
+	ID     string
+	Values map[interface{}]interface{}
+}
+
+type hybridStore struct {
+	backend   Backend
+	threshold int
+	codecs    []securecookie.Codec
+	options   *sessions.Options
+}
+
+func (h *hybridStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(h, name)
+}
+
+func (h *hybridStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(h, name)
+	opts := *h.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var hc hybridCookie
+	if err := securecookie.DecodeMulti(name, c.Value, &hc, h.codecs...); err != nil {
+		return session, err
+	}
+
+	if hc.ID == "" {
+		for k, v := range hc.Values {
+			session.Values[k] = v
+		}
+		session.IsNew = false
+		return session, nil
+	}
+
+	data, err := h.backend.Load(hc.ID)
+	if err != nil || data == nil {
+		return session, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = hc.ID
+	session.IsNew = false
+	return session, nil
+}
+
+func (h *hybridStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options != nil && session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := h.backend.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), hybridCookie{Values: session.Values}, h.codecs...)
+	if err != nil {
+		return err
+	}
+
+	if len(encoded) <= h.threshold {
+		if session.ID != "" {
+			if err := h.backend.Delete(session.ID); err != nil {
+				return err
+			}
+			session.ID = ""
+		}
+	} else {
+		if session.ID == "" {
+			if session.ID, err = newID(); err != nil {
+				return err
+			}
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+			return err
+		}
+		if err := h.backend.Save(session.ID, buf.Bytes(), sessionTTL(session.Options)); err != nil {
+			return err
+		}
+		if encoded, err = securecookie.EncodeMulti(session.Name(), hybridCookie{ID: session.ID}, h.codecs...); err != nil {
+			return err
+		}
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func (h *hybridStore) Options(options Options) {
+	h.options = &sessions.Options{
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+	}
+	for _, c := range h.codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(options.MaxAge)
+		}
+	}
+}
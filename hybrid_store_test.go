@@ -0,0 +1,61 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mapBackend map[string][]byte
+
+func (m mapBackend) Load(id string) ([]byte, error) { return m[id], nil }
+
+func (m mapBackend) Save(id string, data []byte, ttl time.Duration) error {
+	m[id] = data
+	return nil
+}
+
+func (m mapBackend) Delete(id string) error {
+	delete(m, id)
+	return nil
+}
+
+func (m mapBackend) Touch(id string, ttl time.Duration) error { return nil }
+
+func Test_HybridStore(t *testing.T) {
+	backend := mapBackend{}
+	store := NewHybridStore(backend, 512, []byte("secret123"))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	store.Save(req, res, s)
+	if len(backend) != 0 {
+		t.Error("Small session spilled to the backend")
+	}
+
+	s.Values["blob"] = strings.Repeat("x", 1024)
+	res = httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend) != 1 || len(res.Header().Get("Set-Cookie")) > 512 {
+		t.Error("Large session was not moved to the backend")
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, err := store.New(req, "my_session")
+	if err != nil || s.Values["hello"] != "world" || len(s.Values["blob"].(string)) != 1024 {
+		t.Error("Session was not loaded back from the backend:", err)
+	}
+
+	delete(s.Values, "blob")
+	store.Save(req, httptest.NewRecorder(), s)
+	if len(backend) != 0 {
+		t.Error("Shrunk session was not moved back into the cookie")
+	}
+}
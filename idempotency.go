@@ -0,0 +1,200 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const idempotencyKey = "_idempotency"
+
+// inflightTTL is how long a claimed token counts as being processed when
+// neither Complete nor Abort is called, e.g. because the process crashed.
+// completedTTL is how long the result of a completed submission is kept in
+// memory, for replays of a cookie issued before the token was used.
+const (
+	inflightTTL  = time.Minute
+	completedTTL = time.Hour
+)
+
+// ErrUnknownToken is returned by Idempotency.Begin for tokens that were
+// never issued to the session or have been evicted.
+var ErrUnknownToken = errors.New("sessions: unknown idempotency token")
+
+func init() {
+	gob.Register(map[string]formToken{})
+}
+
+// Idempotency issues per-form tokens stored in the session and detects
+// duplicate submissions of the same form, such as double clicks or a
+// refresh reposting it.
+//
+//	m.Post("/orders", func(s sessions.Session, r *http.Request) (int, string) {
+//		ref, dup, err := forms.Do(s, "my_session", r.FormValue("token"), func() (string, error) {
+//			return createOrder(r)
+//		})
+//		if err != nil {
+//			return 400, "invalid form"
+//		}
+//		if dup && ref == "" {
+//			return 409, "still processing"
+//		}
+//		return 303, "/orders/" + ref
+//	})
+type Idempotency struct {
+	max int
+
+	mu       sync.Mutex
+	inflight map[string]*claim
+	swept    time.Time
+}
+
+// claim tracks a token in memory from Begin until it expires, as the
+// session may be replayed from a cookie that doesn't record its use yet.
+type claim struct {
+	done    bool
+	result  string
+	expires time.Time
+}
+
+type formToken struct {
+	Issued time.Time
+	Used   bool
+	Result string
+}
+
+// NewIdempotency returns an Idempotency keeping at most max tokens per
+// session, evicting the oldest ones first.
+func NewIdempotency(max int) *Idempotency {
+	return &Idempotency{max: max, inflight: make(map[string]*claim)}
+}
+
+// Issue stores a new token in the named session and returns it, to be
+// embedded in a form.
+func (i *Idempotency) Issue(s Session, name string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	tokens, _ := s.Get(name, idempotencyKey).(map[string]formToken)
+	if tokens == nil {
+		tokens = make(map[string]formToken)
+	}
+	for len(tokens) >= i.max && len(tokens) > 0 {
+		oldest := ""
+		for t, ft := range tokens {
+			if oldest == "" || ft.Issued.Before(tokens[oldest].Issued) {
+				oldest = t
+			}
+		}
+		delete(tokens, oldest)
+	}
+	tokens[token] = formToken{Issued: time.Now()}
+	s.Set(name, idempotencyKey, tokens)
+	return token
+}
+
+// Begin claims token for processing a submission. When the token was
+// already claimed, duplicate is true and result holds the reference passed
+// to Complete, or is empty while the first submission is still being
+// processed. Claims and results are also kept in memory by this instance,
+// so a session replayed from an older cookie is still detected. Every
+// successful claim must be followed by Complete or Abort; Do takes care of
+// that.
+func (i *Idempotency) Begin(s Session, name, token string) (result string, duplicate bool, err error) {
+	tokens, _ := s.Get(name, idempotencyKey).(map[string]formToken)
+	ft, ok := tokens[token]
+	if !ok {
+		return "", false, ErrUnknownToken
+	}
+	if ft.Used {
+		return ft.Result, true, nil
+	}
+
+	// concurrent submissions carry the same, not yet updated session
+	now := time.Now()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.sweep(now)
+	if c, ok := i.inflight[token]; ok && now.Before(c.expires) {
+		return c.result, true, nil
+	}
+	i.inflight[token] = &claim{expires: now.Add(inflightTTL)}
+
+	ft.Used = true
+	tokens[token] = ft
+	s.Set(name, idempotencyKey, tokens)
+	return "", false, nil
+}
+
+// Complete records the result reference of the submission that claimed
+// token, returned by Begin for later duplicates.
+func (i *Idempotency) Complete(s Session, name, token, result string) {
+	i.mu.Lock()
+	i.inflight[token] = &claim{done: true, result: result, expires: time.Now().Add(completedTTL)}
+	i.mu.Unlock()
+
+	tokens, _ := s.Get(name, idempotencyKey).(map[string]formToken)
+	if ft, ok := tokens[token]; ok {
+		ft.Result = result
+		tokens[token] = ft
+		s.Set(name, idempotencyKey, tokens)
+	}
+}
+
+// Abort releases token after a submission claimed by Begin failed, so the
+// form can be submitted again. Completed tokens can't be released.
+func (i *Idempotency) Abort(s Session, name, token string) {
+	i.mu.Lock()
+	if c, ok := i.inflight[token]; ok && !c.done {
+		delete(i.inflight, token)
+	}
+	i.mu.Unlock()
+
+	tokens, _ := s.Get(name, idempotencyKey).(map[string]formToken)
+	if ft, ok := tokens[token]; ok && ft.Used {
+		ft.Used = false
+		tokens[token] = ft
+		s.Set(name, idempotencyKey, tokens)
+	}
+}
+
+// Do claims token with Begin and runs fn for the first submission,
+// completing the token with the result of fn. The token is aborted if fn
+// fails or panics. Duplicates return the result of the first submission
+// without running fn.
+func (i *Idempotency) Do(s Session, name, token string, fn func() (string, error)) (result string, duplicate bool, err error) {
+	if result, duplicate, err = i.Begin(s, name, token); err != nil || duplicate {
+		return result, duplicate, err
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			i.Abort(s, name, token)
+			panic(v)
+		}
+	}()
+	if result, err = fn(); err != nil {
+		i.Abort(s, name, token)
+		return "", false, err
+	}
+	i.Complete(s, name, token, result)
+	return result, false, nil
+}
+
+// sweep drops expired claims, at most once per inflightTTL. The caller
+// must hold i.mu.
+func (i *Idempotency) sweep(now time.Time) {
+	if now.Sub(i.swept) < inflightTTL {
+		return
+	}
+	i.swept = now
+	for token, c := range i.inflight {
+		if !now.Before(c.expires) {
+			delete(i.inflight, token)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Idempotency(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	forms := NewIdempotency(2)
+	token := ""
+	m.Get("/form", func(session Session) string {
+		token = forms.Issue(session, "my_session")
+		return "OK"
+	})
+	m.Post("/submit", func(session Session) string {
+		ref, dup, err := forms.Begin(session, "my_session", token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dup {
+			return ref
+		}
+		forms.Complete(session, "my_session", token, "order-1")
+		return "created"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/form", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/submit", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Body.String() != "created" {
+		t.Fatal("First submission was not processed:", res.Body.String())
+	}
+	cookie = res.Header().Get("Set-Cookie")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/submit", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Body.String() != "order-1" {
+		t.Error("Duplicate submission was not absorbed:", res.Body.String())
+	}
+}
+
+func Test_IdempotencyEviction(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	forms := NewIdempotency(2)
+	m.Get("/", func(session Session) string {
+		first := forms.Issue(session, "my_session")
+		forms.Issue(session, "my_session")
+		forms.Issue(session, "my_session")
+		if _, _, err := forms.Begin(session, "my_session", first); err != ErrUnknownToken {
+			t.Error("Oldest token was not evicted:", err)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_IdempotencyAbort(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	forms := NewIdempotency(2)
+	m.Get("/", func(session Session) string {
+		token := forms.Issue(session, "my_session")
+
+		_, _, err := forms.Do(session, "my_session", token, func() (string, error) {
+			return "", errors.New("payment declined")
+		})
+		if err == nil {
+			t.Error("Error of the submission was not returned")
+		}
+
+		func() {
+			defer func() { recover() }()
+			forms.Do(session, "my_session", token, func() (string, error) {
+				panic("boom")
+			})
+		}()
+		if len(forms.inflight) != 0 {
+			t.Error("Aborted claims leaked:", forms.inflight)
+		}
+
+		ref, dup, err := forms.Do(session, "my_session", token, func() (string, error) {
+			return "order-1", nil
+		})
+		if err != nil || dup || ref != "order-1" {
+			t.Error("Aborted token could not be submitted again:", ref, dup, err)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_IdempotencyReplayedCookie(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	forms := NewIdempotency(2)
+	token := ""
+	m.Get("/form", func(session Session) string {
+		token = forms.Issue(session, "my_session")
+		return "OK"
+	})
+	m.Post("/submit", func(session Session) string {
+		ref, dup, err := forms.Do(session, "my_session", token, func() (string, error) {
+			return "order-1", nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dup {
+			return "duplicate " + ref
+		}
+		return "created"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/form", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	for _, expected := range []string{"created", "duplicate order-1"} {
+		res = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/submit", nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		if res.Body.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, res.Body.String())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package sessions
+
+import (
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// NewImportingStore returns a Store that serves sessions from store and,
+// for visitors it has no session for yet, imports the values of their
+// session in legacy. legacy is typically the gorilla FilesystemStore or
+// CookieStore the application used before adopting store, configured with
+// the same keys, so adopting a new backend doesn't log everyone out.
+//
+// Imported sessions are written to store, replacing the legacy cookie, the
+// next time they are saved.
+func NewImportingStore(store Store, legacy sessions.Store) Store {
+	return &importingStore{Store: store, legacy: legacy}
+}
+
+type importingStore struct {
+	Store
+	legacy sessions.Store
+}
+
+func (i *importingStore) Unwrap() Store {
+	return i.Store
+}
+
+func (i *importingStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := i.Store.Get(r, name)
+	if s == nil || !s.IsNew {
+		return s, err
+	}
+
+	// New, not Get: gorilla's per-request registry caches sessions by name
+	// only and would hand back the session of the new store
+	old, legacyErr := i.legacy.New(r, name)
+	if legacyErr != nil || old == nil || old.IsNew {
+		// the new store failing to read a legacy cookie is expected,
+		// only report it when there was nothing to import either
+		return s, err
+	}
+
+	for k, v := range old.Values {
+		s.Values[k] = v
+	}
+	return s, nil
+}
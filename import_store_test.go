@@ -0,0 +1,35 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+func Test_ImportingStore(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	legacy := sessions.NewFilesystemStore(dir, []byte("secret123"))
+	req, _ := http.NewRequest("GET", "/", nil)
+	old, _ := legacy.Get(req, "my_session")
+	old.Values["user"] = "bob"
+	res := httptest.NewRecorder()
+	if err := legacy.Save(req, res, old); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewImportingStore(NewCookieStore([]byte("secret456")), legacy)
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, err := store.Get(req, "my_session")
+	if err != nil || s.Values["user"] != "bob" {
+		t.Error("Legacy session was not imported:", s.Values, err)
+	}
+}
@@ -0,0 +1,99 @@
+package sessions
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/sessions"
+)
+
+// StoreStats counts the operations of an InstrumentedStore.
+type StoreStats struct {
+	// Gets, News and Saves count calls to the respective methods, and the
+	// Errors fields count those that failed.
+	Gets       int64
+	GetErrors  int64
+	News       int64
+	NewErrors  int64
+	Saves      int64
+	SaveErrors int64
+	// SavedBytes sums the gob encoded size of all saved sessions, and
+	// MaxPayload is the largest one.
+	SavedBytes int64
+	MaxPayload int
+}
+
+// InstrumentedStore is an interface that represents a Store counting its
+// operations.
+type InstrumentedStore interface {
+	// Store is an embedded interface so that InstrumentedStore can be used
+	// as a session store.
+	Store
+	// Stats returns the counters since the store was created.
+	Stats() StoreStats
+}
+
+// NewInstrumentedStore returns an InstrumentedStore wrapping store. The
+// counters are meant to be exported to a metrics system by the caller.
+func NewInstrumentedStore(store Store) InstrumentedStore {
+	return &instrumentedStore{Store: store}
+}
+
+type instrumentedStore struct {
+	Store
+
+	mu    sync.Mutex
+	stats StoreStats
+}
+
+func (i *instrumentedStore) Unwrap() Store {
+	return i.Store
+}
+
+func (i *instrumentedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := i.Store.Get(r, name)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stats.Gets++
+	if err != nil {
+		i.stats.GetErrors++
+	}
+	return s, err
+}
+
+func (i *instrumentedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := i.Store.New(r, name)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stats.News++
+	if err != nil {
+		i.stats.NewErrors++
+	}
+	return s, err
+}
+
+func (i *instrumentedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	size, _ := encodedSize(s.Values)
+	err := i.Store.Save(r, w, s)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stats.Saves++
+	if err != nil {
+		i.stats.SaveErrors++
+		return err
+	}
+	i.stats.SavedBytes += int64(size)
+	if size > i.stats.MaxPayload {
+		i.stats.MaxPayload = size
+	}
+	return nil
+}
+
+func (i *instrumentedStore) Stats() StoreStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.stats
+}
@@ -0,0 +1,28 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_InstrumentedStore(t *testing.T) {
+	store := NewInstrumentedStore(NewCookieStore([]byte("secret123")))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.Get(req, "my_session")
+	s.Values["hello"] = "world"
+	store.Save(req, httptest.NewRecorder(), s)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", "my_session=garbage")
+	store.New(req, "my_session")
+
+	stats := store.Stats()
+	if stats.Gets != 1 || stats.News != 1 || stats.NewErrors != 1 || stats.Saves != 1 {
+		t.Errorf("Unexpected counters: %+v", stats)
+	}
+	if stats.SavedBytes == 0 || stats.MaxPayload != int(stats.SavedBytes) {
+		t.Errorf("Payload size was not recorded: %+v", stats)
+	}
+}
@@ -0,0 +1,205 @@
+package sessions
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/sessions"
+)
+
+// Invalidator broadcasts the IDs of destroyed or revoked sessions between
+// application instances, so that instances caching sessions locally drop
+// them immediately instead of serving them until they expire.
+type Invalidator interface {
+	// Publish announces that the session with the given ID is invalid.
+	Publish(id string) error
+	// Subscribe calls fn with every published ID until stop is called. It
+	// calls fn with an empty ID when IDs may have been missed, e.g. after
+	// reconnecting, meaning every session may be invalid.
+	Subscribe(fn func(id string)) (stop func(), err error)
+}
+
+// NewInvalidatingStore returns a Store that publishes the ID of every
+// session saved with a negative MaxAge, i.e. destroyed, or deleted through
+// ManagedStore.Delete, through inv.
+func NewInvalidatingStore(store Store, inv Invalidator) Store {
+	return &invalidatingStore{Store: store, inv: inv}
+}
+
+type invalidatingStore struct {
+	Store
+	inv Invalidator
+}
+
+func (i *invalidatingStore) Unwrap() Store {
+	return i.Store
+}
+
+func (i *invalidatingStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if err := i.Store.Save(r, w, s); err != nil {
+		return err
+	}
+	if s.ID != "" && s.Options != nil && s.Options.MaxAge < 0 {
+		return i.inv.Publish(s.ID)
+	}
+	return nil
+}
+
+func (i *invalidatingStore) Delete(id string) error {
+	if err := deleteAll(id, i.Store); err != nil {
+		return err
+	}
+	return i.inv.Publish(id)
+}
+
+func (i *invalidatingStore) Exists(id string) (bool, error) {
+	return existsAny(id, i.Store)
+}
+
+func (i *invalidatingStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, i.Store)
+}
+
+// Reconnection settings of the Redis Invalidator.
+const (
+	invalidatorMinBackoff = 100 * time.Millisecond
+	invalidatorMaxBackoff = 30 * time.Second
+	// invalidatorPing is how often the subscription is checked, so that
+	// dead connections are noticed on quiet channels.
+	invalidatorPing = 30 * time.Second
+)
+
+// NewRedisInvalidator returns an Invalidator using Redis pub/sub on channel.
+// Subscriptions reconnect with exponential backoff when the connection
+// fails, logging the failures to l unless it is nil.
+func NewRedisInvalidator(pool *redis.Pool, channel string, l *log.Logger) Invalidator {
+	return &redisInvalidator{pool: pool, channel: channel, logger: l}
+}
+
+type redisInvalidator struct {
+	pool    *redis.Pool
+	channel string
+	logger  *log.Logger
+}
+
+func (r *redisInvalidator) Publish(id string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("PUBLISH", r.channel, id)
+	return err
+}
+
+func (r *redisInvalidator) Subscribe(fn func(id string)) (func(), error) {
+	psc, err := r.subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	// mu serialises the writes of the pings and of stop to the connection.
+	var mu sync.Mutex
+	current := psc
+	quit := make(chan struct{})
+
+	go func() {
+		for {
+			err := r.receive(psc, fn, &mu)
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			r.logf("invalidation subscription failed: %s", err)
+
+			for backoff := invalidatorMinBackoff; ; backoff *= 2 {
+				if backoff > invalidatorMaxBackoff {
+					backoff = invalidatorMaxBackoff
+				}
+				select {
+				case <-quit:
+					return
+				case <-time.After(backoff):
+				}
+				if psc, err = r.subscribe(); err == nil {
+					break
+				}
+				r.logf("invalidation subscription failed: %s", err)
+			}
+
+			mu.Lock()
+			select {
+			case <-quit:
+				mu.Unlock()
+				psc.Close()
+				return
+			default:
+				current = psc
+			}
+			mu.Unlock()
+			// messages published while disconnected were missed
+			fn("")
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			close(quit)
+			current.Unsubscribe()
+			current.Close()
+		})
+	}, nil
+}
+
+func (r *redisInvalidator) subscribe() (redis.PubSubConn, error) {
+	psc := redis.PubSubConn{Conn: r.pool.Get()}
+	if err := psc.Subscribe(r.channel); err != nil {
+		psc.Close()
+		return psc, err
+	}
+	return psc, nil
+}
+
+// receive calls fn with the messages of psc until its connection fails.
+// Pings are sent holding mu.
+func (r *redisInvalidator) receive(psc redis.PubSubConn, fn func(id string), mu *sync.Mutex) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(invalidatorPing)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				err := psc.Ping("")
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		switch v := psc.ReceiveWithTimeout(2 * invalidatorPing).(type) {
+		case redis.Message:
+			fn(string(v.Data))
+		case error:
+			return v
+		}
+	}
+}
+
+func (r *redisInvalidator) logf(format string, args ...interface{}) {
+	if r.logger != nil {
+		r.logger.Printf(errorFormat, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,112 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/sessions"
+)
+
+type recordingInvalidator []string
+
+func (r *recordingInvalidator) Publish(id string) error {
+	*r = append(*r, id)
+	return nil
+}
+
+func (r *recordingInvalidator) Subscribe(fn func(id string)) (func(), error) {
+	return func() {}, nil
+}
+
+func Test_InvalidatingStore(t *testing.T) {
+	inv := &recordingInvalidator{}
+	store := NewInvalidatingStore(NewCookieStore([]byte("secret123")), inv)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.Get(req, "my_session")
+	s.ID = "abc"
+	store.Save(req, httptest.NewRecorder(), s)
+	if len(*inv) != 0 {
+		t.Error("Live session was published:", *inv)
+	}
+
+	s.Options = &sessions.Options{MaxAge: -1}
+	store.Save(req, httptest.NewRecorder(), s)
+	if len(*inv) != 1 || (*inv)[0] != "abc" {
+		t.Error("Destroyed session was not published:", *inv)
+	}
+}
+
+func Test_InvalidatingStoreDelete(t *testing.T) {
+	inv := &recordingInvalidator{}
+	store := NewInvalidatingStore(NewMemoryStore(0), inv)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, ok := store.(ManagedStore)
+	if !ok {
+		t.Fatal("InvalidatingStore is not a ManagedStore")
+	}
+	if err := ms.Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := ms.Exists(s.ID); ok {
+		t.Error("Deleted session still exists")
+	}
+	if len(*inv) != 1 || (*inv)[0] != s.ID {
+		t.Error("Deleted session was not published:", *inv)
+	}
+}
+
+// Test_RedisInvalidatorReconnect runs against the server at
+// SESSIONS_TEST_REDIS, e.g.
+//
+//	SESSIONS_TEST_REDIS=localhost:6379 go test
+func Test_RedisInvalidatorReconnect(t *testing.T) {
+	addr := os.Getenv("SESSIONS_TEST_REDIS")
+	if addr == "" {
+		t.Skip("SESSIONS_TEST_REDIS is not set")
+	}
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", addr) }}
+	inv := NewRedisInvalidator(pool, "sessions_test_invalidation", nil)
+
+	ids := make(chan string, 10)
+	stop, err := inv.Subscribe(func(id string) { ids <- id })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	conn := pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("CLIENT", "KILL", "TYPE", "pubsub"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case id := <-ids:
+		if id != "" {
+			t.Error("Reconnect was not reported, got", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscription did not reconnect")
+	}
+
+	inv.Publish("abc")
+	select {
+	case id := <-ids:
+		if id != "abc" {
+			t.Error("Unexpected ID after reconnecting:", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No message after reconnecting")
+	}
+}
@@ -0,0 +1,269 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/gorilla/sessions"
+)
+
+// JWTOptions configures the tokens of a JWTStore.
+type JWTOptions struct {
+	// Algorithm signs the tokens, e.g. jose.HS256 or jose.ES256.
+	Algorithm jose.SignatureAlgorithm
+	// SigningKey signs the tokens, e.g. a []byte for HMAC or a private key.
+	SigningKey interface{}
+	// VerificationKey verifies the tokens, e.g. a public key. Defaults to
+	// SigningKey.
+	VerificationKey interface{}
+	// EncryptionKey, if set, is a 32 byte key encrypting the signed tokens
+	// with A256GCM, so only holders of the key can read the values.
+	EncryptionKey []byte
+	// Issuer and Audience are set in every token and required in the
+	// tokens read.
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated when checking expiry. Defaults to
+	// a minute.
+	Leeway time.Duration
+}
+
+// JWTStore is an interface that represents a storage for Sessions keeping
+// the values as claims of a JSON Web Token in the cookie.
+type JWTStore interface {
+	// Store is an embedded interface so that JWTStore can be used
+	// as a session store.
+	Store
+	// Options sets the default options for each session stored in this
+	// JWTStore.
+	Options(Options)
+}
+
+// sessionClaims carries the session values in the "sess" claim. Values set
+// through a Bucket go in "sess_buckets", the timestamps of the middleware in
+// "sess_meta", and "sess_exp" lists the values set with SetWithTTL, which
+// are stored unwrapped.
+type sessionClaims struct {
+	Values   map[string]interface{}            `json:"sess"`
+	Buckets  map[string]map[string]interface{} `json:"sess_buckets,omitempty"`
+	Meta     map[string]time.Time              `json:"sess_meta,omitempty"`
+	Expiring []jwtExpiring                     `json:"sess_exp,omitempty"`
+}
+
+// jwtExpiring marks the value at Key, in Bucket if set, as expiring.
+type jwtExpiring struct {
+	Bucket  string `json:"b,omitempty"`
+	Key     string `json:"k"`
+	Expires int64  `json:"exp"`
+}
+
+// encodeClaims maps session values to claims.
+func encodeClaims(values map[interface{}]interface{}) (sessionClaims, error) {
+	claims := sessionClaims{Values: make(map[string]interface{}, len(values))}
+	for k, v := range values {
+		if mk, ok := k.(metadataKey); ok {
+			t, ok := v.(time.Time)
+			if !ok {
+				return claims, fmt.Errorf("sessions: JWT metadata %s holds %T", mk, v)
+			}
+			if claims.Meta == nil {
+				claims.Meta = make(map[string]time.Time)
+			}
+			claims.Meta[string(mk)] = t
+			continue
+		}
+
+		target := claims.Values
+		bucket, key := "", k
+		if bk, ok := k.(bucketKey); ok {
+			bucket, key = bk.Bucket, bk.Key
+			if claims.Buckets == nil {
+				claims.Buckets = make(map[string]map[string]interface{})
+			}
+			if claims.Buckets[bucket] == nil {
+				claims.Buckets[bucket] = make(map[string]interface{})
+			}
+			target = claims.Buckets[bucket]
+		}
+		skey, ok := key.(string)
+		if !ok {
+			return claims, fmt.Errorf("sessions: JWT claims need string keys, got %T", key)
+		}
+		if e, ok := v.(expiringValue); ok {
+			claims.Expiring = append(claims.Expiring, jwtExpiring{Bucket: bucket, Key: skey, Expires: e.Expires.Unix()})
+			v = e.Value
+		}
+		target[skey] = v
+	}
+	return claims, nil
+}
+
+// decodeClaims maps claims back to the session values.
+func decodeClaims(claims sessionClaims, values map[interface{}]interface{}) {
+	for k, v := range claims.Values {
+		values[k] = v
+	}
+	for bucket, bv := range claims.Buckets {
+		for k, v := range bv {
+			values[bucketKey{Bucket: bucket, Key: k}] = v
+		}
+	}
+	for k, t := range claims.Meta {
+		values[metadataKey(k)] = t
+	}
+	for _, e := range claims.Expiring {
+		var key interface{} = e.Key
+		if e.Bucket != "" {
+			key = bucketKey{Bucket: e.Bucket, Key: e.Key}
+		}
+		if v, ok := values[key]; ok {
+			values[key] = expiringValue{Value: v, Expires: time.Unix(e.Expires, 0)}
+		}
+	}
+}
+
+// NewJWTStore returns a new JWTStore. Since other services can verify and
+// read the tokens without calling the app, values are encoded as JSON
+// rather than gob: keys must be strings and numbers are read back as
+// float64. The token expires after Options.MaxAge.
+func NewJWTStore(opts JWTOptions) (JWTStore, error) {
+	if opts.VerificationKey == nil {
+		opts.VerificationKey = opts.SigningKey
+	}
+	if opts.Leeway == 0 {
+		opts.Leeway = jwt.DefaultLeeway
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: opts.Algorithm, Key: opts.SigningKey}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, err
+	}
+	var encrypter jose.Encrypter
+	if opts.EncryptionKey != nil {
+		encrypter, err = jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: jose.DIRECT, Key: opts.EncryptionKey},
+			(&jose.EncrypterOptions{}).WithContentType("JWT"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &jwtStore{
+		opts:      opts,
+		signer:    signer,
+		encrypter: encrypter,
+		options:   &sessions.Options{Path: "/", MaxAge: 86400 * 30},
+	}, nil
+}
+
+type jwtStore struct {
+	opts      JWTOptions
+	signer    jose.Signer
+	encrypter jose.Encrypter
+	options   *sessions.Options
+}
+
+func (j *jwtStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(j, name)
+}
+
+func (j *jwtStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(j, name)
+	opts := *j.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var std jwt.Claims
+	var claims sessionClaims
+	if err := j.parse(c.Value, &std, &claims); err != nil {
+		return session, err
+	}
+	expected := jwt.Expected{Issuer: j.opts.Issuer}
+	if j.opts.Audience != "" {
+		expected.AnyAudience = jwt.Audience{j.opts.Audience}
+	}
+	if err := std.ValidateWithLeeway(expected, j.opts.Leeway); err != nil {
+		return session, err
+	}
+
+	decodeClaims(claims, session.Values)
+	session.IsNew = false
+	return session, nil
+}
+
+func (j *jwtStore) parse(token string, dest ...interface{}) error {
+	algs := []jose.SignatureAlgorithm{j.opts.Algorithm}
+	if j.encrypter == nil {
+		tok, err := jwt.ParseSigned(token, algs)
+		if err != nil {
+			return err
+		}
+		return tok.Claims(j.opts.VerificationKey, dest...)
+	}
+
+	nested, err := jwt.ParseSignedAndEncrypted(token, []jose.KeyAlgorithm{jose.DIRECT}, []jose.ContentEncryption{jose.A256GCM}, algs)
+	if err != nil {
+		return err
+	}
+	tok, err := nested.Decrypt(j.opts.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	return tok.Claims(j.opts.VerificationKey, dest...)
+}
+
+func (j *jwtStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options != nil && session.Options.MaxAge < 0 {
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	claims, err := encodeClaims(session.Values)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	std := jwt.Claims{
+		Issuer:   j.opts.Issuer,
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(sessionTTL(session.Options))),
+	}
+	if j.opts.Audience != "" {
+		std.Audience = jwt.Audience{j.opts.Audience}
+	}
+
+	var token string
+	if j.encrypter == nil {
+		token, err = jwt.Signed(j.signer).Claims(std).Claims(claims).Serialize()
+	} else {
+		token, err = jwt.SignedAndEncrypted(j.signer, j.encrypter).Claims(std).Claims(claims).Serialize()
+	}
+	if err != nil {
+		return err
+	}
+	if len(token) > 4096 {
+		return errors.New("sessions: JWT exceeds the cookie size limit")
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), token, session.Options))
+	return nil
+}
+
+func (j *jwtStore) Options(options Options) {
+	j.options = &sessions.Options{
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+	}
+}
@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/go-martini/martini"
+)
+
+func Test_JWTStore(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	for _, encrypt := range [][]byte{nil, key} {
+		store, err := NewJWTStore(JWTOptions{
+			Algorithm:     jose.HS256,
+			SigningKey:    key,
+			EncryptionKey: encrypt,
+			Issuer:        "app",
+			Audience:      "gateway",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["user"] = "bob"
+		res := httptest.NewRecorder()
+		if err := store.Save(req, res, s); err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+		s, err = store.New(req, "my_session")
+		if err != nil || s.IsNew || s.Values["user"] != "bob" {
+			t.Error("Session was not read back from the token:", s.Values, err)
+		}
+
+		other, _ := NewJWTStore(JWTOptions{Algorithm: jose.HS256, SigningKey: key, EncryptionKey: encrypt, Issuer: "other"})
+		if _, err := other.New(req, "my_session"); err == nil {
+			t.Error("Token of another issuer was accepted")
+		}
+	}
+}
+
+func Test_JWTStoreMiddleware(t *testing.T) {
+	store, err := NewJWTStore(JWTOptions{Algorithm: jose.HS256, SigningKey: []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := martini.Classic()
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		session.Bucket("cart").Set("my_session", "item", "apples")
+		session.SetWithTTL("my_session", "otp", "123456", time.Hour)
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "user") != "bob" || session.Bucket("cart").Get("my_session", "item") != "apples" ||
+			session.Get("my_session", "otp") != "123456" {
+			t.Error("Values were not read back from the token:", session.GetAll("my_session"))
+		}
+		if session.CreatedAt("my_session").IsZero() {
+			t.Error("Metadata was not read back from the token")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+	if res.Header().Get("Set-Cookie") == "" {
+		t.Fatal("Token was not set")
+	}
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
@@ -0,0 +1,142 @@
+// Package leveldbstore provides a LevelDB based storage for martini-contrib
+// sessions, sharing a database with other application data.
+//
+//	store, err := leveldbstore.New(db, "sessions")
+//	m.Use(sessions.Sessions(store))
+package leveldbstore
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Store is an interface that represents a LevelDB based storage for
+// Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// New returns a new Store keeping sessions in db under keys starting with
+// prefix and a colon, e.g. "sessions:", so they can live alongside other
+// application data. The caller stays in charge of closing db.
+//
+// LevelDB has no expiration, so expired sessions are deleted when they are
+// read.
+func New(db *leveldb.DB, prefix string) (Store, error) {
+	if prefix == "" {
+		return nil, errors.New("leveldbstore: key prefix is empty")
+	}
+	return sessions.NewServerStore(&levelDBBackend{db, prefix + ":"}), nil
+}
+
+type levelDBBackend struct {
+	db     *leveldb.DB
+	prefix string
+}
+
+func (l *levelDBBackend) Load(id string) ([]byte, error) {
+	v, err := l.db.Get([]byte(l.prefix+id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	data := sessions.UnlessExpired(v)
+	if data == nil {
+		return nil, l.Delete(id)
+	}
+	return data, nil
+}
+
+func (l *levelDBBackend) Save(id string, data []byte, ttl time.Duration) error {
+	return l.db.Put([]byte(l.prefix+id), sessions.WithExpiry(data, ttl), nil)
+}
+
+func (l *levelDBBackend) Touch(id string, ttl time.Duration) error {
+	// the transaction blocks saves until it is done
+	tr, err := l.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+	defer tr.Discard()
+
+	v, err := tr.Get([]byte(l.prefix+id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	data := sessions.UnlessExpired(v)
+	if data == nil {
+		return nil
+	}
+	if err := tr.Put([]byte(l.prefix+id), sessions.WithExpiry(data, ttl), nil); err != nil {
+		return err
+	}
+	return tr.Commit()
+}
+
+func (l *levelDBBackend) Delete(id string) error {
+	return l.db.Delete([]byte(l.prefix+id), nil)
+}
+
+func (l *levelDBBackend) DeletePrefix(prefix string) error {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(l.prefix+prefix)), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(iter.Key())
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return l.db.Write(batch, nil)
+}
+
+func (l *levelDBBackend) Scan(fn func(id string, data []byte, expires time.Time) error) error {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(l.prefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if data := sessions.UnlessExpired(iter.Value()); data != nil {
+			id := strings.TrimPrefix(string(iter.Key()), l.prefix)
+			if err := fn(id, data, sessions.ExpiresAt(iter.Value())); err != nil {
+				return err
+			}
+		}
+	}
+	return iter.Error()
+}
+
+func (l *levelDBBackend) PurgeExpired() ([]string, error) {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(l.prefix)), nil)
+	defer iter.Release()
+
+	var ids []string
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		if sessions.UnlessExpired(iter.Value()) == nil {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+			ids = append(ids, string(iter.Key()[len(l.prefix):]))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	if err := l.db.Write(batch, nil); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,58 @@
+package leveldbstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func Test_LevelDBStore(t *testing.T) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Put([]byte("app/config"), []byte("unrelated"), nil)
+	db.Put([]byte("sessions-archive"), []byte("unrelated"), nil)
+
+	if _, err := New(db, ""); err == nil {
+		t.Error("Empty prefix was accepted")
+	}
+	store, err := New(db, "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+
+	iter := db.NewIterator(util.BytesPrefix([]byte("sessions:")), nil)
+	if !iter.Next() || !strings.HasSuffix(string(iter.Key()), s.ID) {
+		t.Error("Session was not stored under the prefix")
+	}
+	iter.Release()
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, err = store.New(req, "my_session")
+	if err != nil || s.IsNew || s.Values["hello"] != "world" {
+		t.Error("Session was not loaded back:", s.Values, err)
+	}
+
+	if err := store.(*sessions.ServerStore).Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get([]byte("sessions-archive"), nil); string(v) != "unrelated" {
+		t.Error("Cleanup touched keys outside the prefix")
+	}
+}
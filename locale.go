@@ -0,0 +1,110 @@
+package sessions
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	localeKey   = "_locale"
+	timezoneKey = "_timezone"
+)
+
+// SetLocale stores the preferred locale, such as "en-US", in the named
+// session.
+func SetLocale(s Session, name, locale string) {
+	s.Set(name, localeKey, locale)
+}
+
+// Locale returns the locale stored in the named session. If there is none
+// it is negotiated from the Accept-Language header of r against the
+// supported locales, or taken from the header as is when none are given.
+func Locale(s Session, name string, r *http.Request, supported ...string) string {
+	if locale, ok := s.Get(name, localeKey).(string); ok && locale != "" {
+		return locale
+	}
+	return negotiateLocale(r, supported)
+}
+
+// SetTimezone stores the preferred IANA time zone, such as "Europe/Berlin",
+// in the named session. Unknown zones are rejected.
+func SetTimezone(s Session, name, tz string) error {
+	if _, err := time.LoadLocation(tz); err != nil {
+		return err
+	}
+	s.Set(name, timezoneKey, tz)
+	return nil
+}
+
+// Timezone returns the time zone stored in the named session, or UTC.
+func Timezone(s Session, name string) *time.Location {
+	if tz, ok := s.Get(name, timezoneKey).(string); ok {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// negotiateLocale picks the best match for the request's Accept-Language
+// header among supported. Exact matches win over matches on the primary
+// language subtag only.
+func negotiateLocale(r *http.Request, supported []string) string {
+	accepted := acceptLanguages(r.Header.Get("Accept-Language"))
+	if len(supported) == 0 {
+		if len(accepted) == 0 {
+			return ""
+		}
+		return accepted[0]
+	}
+
+	for _, tag := range accepted {
+		for _, s := range supported {
+			if strings.EqualFold(tag, s) {
+				return s
+			}
+		}
+		base := strings.SplitN(tag, "-", 2)[0]
+		for _, s := range supported {
+			if strings.EqualFold(base, strings.SplitN(s, "-", 2)[0]) {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// acceptLanguages returns the language tags of an Accept-Language header
+// ordered by quality.
+func acceptLanguages(header string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		l := lang{tag: strings.TrimSpace(fields[0]), q: 1}
+		for _, f := range fields[1:] {
+			if f = strings.TrimSpace(f); strings.HasPrefix(f, "q=") {
+				if q, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					l.q = q
+				}
+			}
+		}
+		if l.tag != "" && l.tag != "*" && l.q > 0 {
+			langs = append(langs, l)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
@@ -0,0 +1,64 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Locale(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session, r *http.Request) string {
+		if l := Locale(session, "my_session", r, "en-US", "de"); l != "de" {
+			t.Error("Unexpected negotiated locale:", l)
+		}
+		if l := Locale(session, "my_session", r); l != "fr-CH" {
+			t.Error("Unexpected header locale:", l)
+		}
+
+		SetLocale(session, "my_session", "en-US")
+		if l := Locale(session, "my_session", r, "de"); l != "en-US" {
+			t.Error("Stored locale was not preferred:", l)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr-CH, de-AT;q=0.8, *;q=0.5")
+	m.ServeHTTP(res, req)
+}
+
+func Test_Timezone(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		if tz := Timezone(session, "my_session"); tz != time.UTC {
+			t.Error("Expected UTC fallback, got", tz)
+		}
+		if err := SetTimezone(session, "my_session", "Not/AZone"); err == nil {
+			t.Error("Unknown time zone was accepted")
+		}
+		if err := SetTimezone(session, "my_session", "Europe/Berlin"); err != nil {
+			t.Fatal(err)
+		}
+		if tz := Timezone(session, "my_session"); tz.String() != "Europe/Berlin" {
+			t.Error("Unexpected time zone:", tz)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
@@ -0,0 +1,223 @@
+package sessions
+
+import (
+	"container/list"
+	"encoding/gob"
+	"sync"
+	"time"
+)
+
+const (
+	attemptsKey    = "_login_attempts"
+	lockedUntilKey = "_locked_until"
+)
+
+// maxAttemptEntries caps the identifiers kept by the in-memory AttemptStore
+// of NewLockout, so failures for made up names can't exhaust memory.
+const maxAttemptEntries = 100000
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+// AttemptStore keeps failed login attempts per identifier, such as a user
+// name, so that a lockout can't be dodged by starting a new session.
+type AttemptStore interface {
+	// Attempts returns the failure count and lockout deadline of id.
+	Attempts(id string) (count int, lockedUntil time.Time, err error)
+	// AddFailure atomically increments the failure count of id. Once it
+	// reaches max, the count is reset and id is locked out for duration.
+	// It returns the new lockout deadline, which is zero unless this
+	// failure locked id out.
+	AddFailure(id string, max int, duration time.Duration) (lockedUntil time.Time, err error)
+	// ResetAttempts forgets id.
+	ResetAttempts(id string) error
+}
+
+// Lockout tracks failed logins in the session and per identifier, and locks
+// both out for Duration after MaxAttempts consecutive failures.
+type Lockout struct {
+	MaxAttempts int
+	Duration    time.Duration
+	// Attempts keeps the per-identifier state. Use NewLockout to get an
+	// in-memory one, or plug in a store shared by all instances.
+	Attempts AttemptStore
+}
+
+// NewLockout returns a Lockout keeping per-identifier state in memory.
+// Failures are forgotten Duration after the last one, and the identifiers
+// failing least recently are dropped beyond 100000 of them. Identifiers
+// that are locked out are kept until their lockout ends.
+func NewLockout(maxAttempts int, duration time.Duration) *Lockout {
+	return &Lockout{
+		MaxAttempts: maxAttempts,
+		Duration:    duration,
+		Attempts:    newMemoryAttempts(duration, maxAttemptEntries),
+	}
+}
+
+// Locked reports whether the named session or identifier is locked out and
+// until when.
+func (l *Lockout) Locked(s Session, name, identifier string) (bool, time.Time, error) {
+	until, _ := s.Get(name, lockedUntilKey).(time.Time)
+	if identifier != "" {
+		_, idUntil, err := l.Attempts.Attempts(identifier)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		if idUntil.After(until) {
+			until = idUntil
+		}
+	}
+	return time.Now().Before(until), until, nil
+}
+
+// Fail records a failed login for the named session and identifier. It
+// returns the lockout deadline, which is zero until MaxAttempts is reached.
+func (l *Lockout) Fail(s Session, name, identifier string) (time.Time, error) {
+	var until time.Time
+
+	count, _ := s.Get(name, attemptsKey).(int)
+	if count+1 >= l.MaxAttempts {
+		until = time.Now().Add(l.Duration)
+		s.Set(name, lockedUntilKey, until)
+		s.Delete(name, attemptsKey)
+	} else {
+		s.Set(name, attemptsKey, count+1)
+	}
+
+	if identifier != "" {
+		idUntil, err := l.Attempts.AddFailure(identifier, l.MaxAttempts, l.Duration)
+		if err != nil {
+			return until, err
+		}
+		if !idUntil.IsZero() {
+			until = idUntil
+		}
+	}
+	return until, nil
+}
+
+// Reset clears the failures of the named session and identifier, typically
+// after a successful login.
+func (l *Lockout) Reset(s Session, name, identifier string) error {
+	s.Delete(name, attemptsKey)
+	s.Delete(name, lockedUntilKey)
+	if identifier == "" {
+		return nil
+	}
+	return l.Attempts.ResetAttempts(identifier)
+}
+
+type attempts struct {
+	id      string
+	count   int
+	until   time.Time
+	expires time.Time
+}
+
+// memoryAttempts keeps the attempts of identifiers that aren't locked out
+// in the order they last failed, which is also the order they expire in,
+// and those of locked out identifiers apart until their lockout ends, so
+// that failures for other identifiers can't evict them.
+type memoryAttempts struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	locked  map[string]*attempts
+	swept   time.Time
+}
+
+func newMemoryAttempts(ttl time.Duration, max int) *memoryAttempts {
+	return &memoryAttempts{
+		ttl:     ttl,
+		max:     max,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		locked:  make(map[string]*attempts),
+	}
+}
+
+func (m *memoryAttempts) Attempts(id string) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evict(time.Now())
+
+	if a, ok := m.locked[id]; ok {
+		return a.count, a.until, nil
+	}
+	if el, ok := m.entries[id]; ok {
+		a := el.Value.(*attempts)
+		return a.count, a.until, nil
+	}
+	return 0, time.Time{}, nil
+}
+
+func (m *memoryAttempts) AddFailure(id string, max int, duration time.Duration) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.evict(now)
+
+	a, ok := m.locked[id]
+	if ok {
+		delete(m.locked, id)
+	} else if el, ok := m.entries[id]; ok {
+		a = m.order.Remove(el).(*attempts)
+		delete(m.entries, id)
+	} else {
+		a = &attempts{id: id}
+	}
+
+	var until time.Time
+	if a.count++; a.count >= max {
+		a.count = 0
+		a.until = now.Add(duration)
+		until = a.until
+	}
+	if now.Before(a.until) {
+		m.locked[id] = a
+	} else {
+		a.expires = now.Add(m.ttl)
+		m.entries[id] = m.order.PushBack(a)
+		m.evict(now)
+	}
+	return until, nil
+}
+
+func (m *memoryAttempts) ResetAttempts(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[id]; ok {
+		m.order.Remove(el)
+		delete(m.entries, id)
+	}
+	delete(m.locked, id)
+	return nil
+}
+
+// evict drops the expired entries and the oldest ones beyond max, and the
+// lockouts that ended, at most once per ttl. The caller must hold m.mu.
+func (m *memoryAttempts) evict(now time.Time) {
+	for el := m.order.Front(); el != nil; el = m.order.Front() {
+		a := el.Value.(*attempts)
+		if m.order.Len() <= m.max && now.Before(a.expires) {
+			break
+		}
+		m.order.Remove(el)
+		delete(m.entries, a.id)
+	}
+
+	if now.Sub(m.swept) < m.ttl {
+		return
+	}
+	m.swept = now
+	for id, a := range m.locked {
+		if !now.Before(a.until) {
+			delete(m.locked, id)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Lockout(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	lockout := NewLockout(3, time.Minute)
+	m.Get("/", func(session Session) string {
+		for i := 0; i < 2; i++ {
+			if until, _ := lockout.Fail(session, "my_session", "bob"); !until.IsZero() {
+				t.Error("Locked out too early")
+			}
+		}
+		if locked, _, _ := lockout.Locked(session, "my_session", "bob"); locked {
+			t.Error("Locked out before reaching the limit")
+		}
+		lockout.Fail(session, "my_session", "bob")
+		if locked, _, _ := lockout.Locked(session, "my_session", "bob"); !locked {
+			t.Error("Session was not locked out")
+		}
+		return "OK"
+	})
+
+	m.Get("/fresh", func(session Session) string {
+		if locked, _, _ := lockout.Locked(session, "my_session", "bob"); !locked {
+			t.Error("Identifier lockout was dodged with a new session")
+		}
+		lockout.Reset(session, "my_session", "bob")
+		if locked, _, _ := lockout.Locked(session, "my_session", "bob"); locked {
+			t.Error("Reset did not lift the lockout")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/fresh", nil)
+	m.ServeHTTP(res2, req2)
+}
+
+func Test_MemoryAttempts(t *testing.T) {
+	m := newMemoryAttempts(20*time.Millisecond, 2)
+
+	m.AddFailure("alice", 3, time.Minute)
+	m.AddFailure("bob", 3, time.Minute)
+	m.AddFailure("carol", 3, time.Minute)
+	if count, _, _ := m.Attempts("alice"); count != 0 || len(m.entries) != 2 {
+		t.Error("Oldest entry was not evicted beyond the cap:", len(m.entries))
+	}
+
+	if until, _ := m.AddFailure("dave", 1, time.Minute); until.IsZero() {
+		t.Error("Reaching the limit did not lock out")
+	}
+	for _, id := range []string{"erin", "frank", "grace"} {
+		m.AddFailure(id, 3, time.Minute)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if count, _, _ := m.Attempts("carol"); count != 0 {
+		t.Error("Expired entry was kept")
+	}
+	if _, until, _ := m.Attempts("dave"); until.IsZero() {
+		t.Error("Entry was dropped before its lockout ended")
+	}
+}
+
+func Test_MemoryAttemptsConcurrent(t *testing.T) {
+	m := newMemoryAttempts(time.Minute, 10)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	lockouts := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if until, _ := m.AddFailure("bob", 10, time.Minute); !until.IsZero() {
+				mu.Lock()
+				lockouts++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if lockouts != 2 {
+		t.Error("Expected 2 lockouts for 20 failures, got", lockouts)
+	}
+}
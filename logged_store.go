@@ -0,0 +1,50 @@
+package sessions
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// NewLoggedStore returns a Store logging the duration of every operation on
+// store to l. Operations taking longer than slow are flagged, so they stand
+// out when chasing latency; a slow of 0 flags nothing.
+func NewLoggedStore(store Store, l *log.Logger, slow time.Duration) Store {
+	return &loggedStore{Store: store, logger: l, slow: slow}
+}
+
+type loggedStore struct {
+	Store
+	logger *log.Logger
+	slow   time.Duration
+}
+
+func (l *loggedStore) Unwrap() Store {
+	return l.Store
+}
+
+func (l *loggedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	defer l.log("Get", name, time.Now())
+	return l.Store.Get(r, name)
+}
+
+func (l *loggedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	defer l.log("New", name, time.Now())
+	return l.Store.New(r, name)
+}
+
+func (l *loggedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	defer l.log("Save", s.Name(), time.Now())
+	return l.Store.Save(r, w, s)
+}
+
+func (l *loggedStore) log(op, name string, start time.Time) {
+	took := time.Since(start)
+	if l.slow > 0 && took > l.slow {
+		l.logger.Printf("[sessions] SLOW! %s %s took %v\n", op, name, took)
+		return
+	}
+	l.logger.Printf("[sessions] %s %s took %v\n", op, name, took)
+}
@@ -0,0 +1,37 @@
+package sessions
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+type slowStore struct {
+	CookieStore
+}
+
+func (s slowStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	time.Sleep(20 * time.Millisecond)
+	return s.CookieStore.Save(r, w, session)
+}
+
+func Test_LoggedStore(t *testing.T) {
+	var buf bytes.Buffer
+	store := NewLoggedStore(slowStore{NewCookieStore([]byte("secret123"))}, log.New(&buf, "", 0), 10*time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	store.Save(req, httptest.NewRecorder(), s)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "[sessions] New my_session took") ||
+		!strings.HasPrefix(lines[1], "[sessions] SLOW! Save my_session took") {
+		t.Error("Unexpected log:", lines)
+	}
+}
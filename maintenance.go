@@ -0,0 +1,57 @@
+package sessions
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-martini/martini"
+)
+
+// Maintenance is a switch that, while enabled, refuses requests that would
+// start a new session and lets requests of existing sessions through. It is
+// used to drain traffic before maintenance without kicking active users.
+//
+//	mode := &sessions.Maintenance{}
+//	m.Use(sessions.Sessions(store))
+//	m.Use(mode.Handler("my_session"))
+//	...
+//	mode.Enable()
+type Maintenance struct {
+	// Response writes the reply to refused requests. Defaults to a 503
+	// Service Unavailable with a Retry-After header of five minutes.
+	Response func(w http.ResponseWriter, r *http.Request)
+
+	enabled int32
+}
+
+// Enable starts refusing new sessions.
+func (m *Maintenance) Enable() {
+	atomic.StoreInt32(&m.enabled, 1)
+}
+
+// Disable accepts new sessions again.
+func (m *Maintenance) Disable() {
+	atomic.StoreInt32(&m.enabled, 0)
+}
+
+// Enabled reports whether new sessions are refused.
+func (m *Maintenance) Enabled() bool {
+	return atomic.LoadInt32(&m.enabled) == 1
+}
+
+// Handler returns a Middleware enforcing the switch for the named session.
+// It must be used after Sessions.
+func (m *Maintenance) Handler(name string) martini.Handler {
+	return func(res http.ResponseWriter, r *http.Request, s Session) {
+		if !m.Enabled() || !s.IsNew(name) {
+			return
+		}
+
+		if m.Response != nil {
+			m.Response(res, r)
+			return
+		}
+		res.Header().Set("Retry-After", "300")
+		http.Error(res, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+}
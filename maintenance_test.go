@@ -0,0 +1,45 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Maintenance(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	mode := &Maintenance{}
+	m.Use(Sessions(store))
+	m.Use(mode.Handler("my_session"))
+
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "hello", "world")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	mode.Enable()
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Error("Existing session was refused:", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusServiceUnavailable {
+		t.Error("New session was not refused:", res.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package sessions
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ManagedStore is an interface that represents a Store which can manage
+// sessions by ID, outside of the request that owns them. It is implemented
+// by the Redis store, the server-side stores of this package and the stores
+// combining several of them, and reached through the other decorators with
+// StoreAs.
+//
+// When the store passed to Sessions is a ManagedStore, destroyed sessions
+// are deleted right away.
+type ManagedStore interface {
+	// Store is an embedded interface so that ManagedStore can be used
+	// as a session store.
+	Store
+	// Delete removes the session with the given ID, e.g. to log a user out
+	// of another device.
+	Delete(id string) error
+	// Exists reports whether a session with the given ID is stored.
+	Exists(id string) (bool, error)
+	// Touch extends the expiry of the session with the given ID to ttl
+	// from now.
+	Touch(id string, ttl time.Duration) error
+}
+
+// errInvalidID is returned for IDs that can not have been made by newID.
+var errInvalidID = errors.New("sessions: invalid session ID")
+
+func (s *ServerStore) Delete(id string) error {
+	if !validID(id) {
+		return errInvalidID
+	}
+	return s.backend.Delete(id)
+}
+
+func (s *ServerStore) Exists(id string) (bool, error) {
+	if !validID(id) {
+		return false, errInvalidID
+	}
+	data, err := s.backend.Load(id)
+	return data != nil, err
+}
+
+func (s *ServerStore) Touch(id string, ttl time.Duration) error {
+	if !validID(id) {
+		return errInvalidID
+	}
+	return s.backend.Touch(id, ttl)
+}
+
+func (c *rediStore) Delete(id string) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", c.prefix+id, c.prefix+id+redisCounterSuffix)
+	return err
+}
+
+func (c *rediStore) Exists(id string) (bool, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+	return redis.Bool(conn.Do("EXISTS", c.prefix+id))
+}
+
+func (c *rediStore) Touch(id string, ttl time.Duration) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+	ms := int64(ttl / time.Millisecond)
+	if _, err := conn.Do("PEXPIRE", c.prefix+id, ms); err != nil {
+		return err
+	}
+	_, err := conn.Do("PEXPIRE", c.prefix+id+redisCounterSuffix, ms)
+	return err
+}
@@ -0,0 +1,103 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_ManagedStore(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMemoryStore(0)
+	defer store.Close()
+	m.Use(Sessions(store))
+
+	m.Get("/login", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+	m.Get("/merge", func(session Session) string {
+		session.MergeInto("my_session", "other_session", nil)
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+	cookie := strings.Split(res.Header().Get("Set-Cookie"), ";")[0]
+	id := strings.TrimPrefix(cookie, "my_session=")
+
+	managed := store.(ManagedStore)
+	if ok, _ := managed.Exists(id); !ok {
+		t.Fatal("Saved session does not exist")
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/merge", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if ok, _ := managed.Exists(id); ok {
+		t.Error("Destroyed session was not deleted")
+	}
+}
+
+func Test_ManagedStoreWrapped(t *testing.T) {
+	m := martini.Classic()
+
+	inner := NewMemoryStore(0)
+	defer inner.Close()
+	cached, _ := NewCachedStore(NewLoggedStore(inner, nil, 0), CacheOptions{})
+	defer cached.Close()
+	namespaced, err := NewNamespacedStore(cached, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Use(Sessions(namespaced))
+
+	m.Get("/login", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+	m.Get("/logout", func(session Session) string {
+		session.Destroy("my_session")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+	cookie := strings.Split(res.Header().Get("Set-Cookie"), ";")[0]
+	id := strings.TrimPrefix(cookie, "my_session=")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/logout", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if ok, _ := inner.(ManagedStore).Exists(id); ok {
+		t.Error("Destroyed session was not deleted through the decorators")
+	}
+}
+
+func Test_ManagedStoreInvalidID(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFilesystemStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	managed := store.(ManagedStore)
+	for _, id := range []string{"", "ab", "../../etc/passwd"} {
+		if err := managed.Delete(id); err == nil {
+			t.Error("Invalid ID was accepted:", id)
+		}
+		if err := managed.Touch(id, time.Minute); err == nil {
+			t.Error("Invalid ID was accepted:", id)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package memcachestore provides a memcached based storage for
+// martini-contrib sessions.
+//
+//	store, err := memcachestore.New("10.0.0.1:11211", "10.0.0.2:11211")
+//	m.Use(sessions.Sessions(store))
+//
+// Importing the package registers the memcache:// and memcached:// schemes
+// of sessions.OpenStore.
+package memcachestore
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/martini-contrib/sessions"
+)
+
+func init() {
+	sessions.RegisterScheme("memcache", open)
+	sessions.RegisterScheme("memcached", open)
+}
+
+func open(u *url.URL, keyPairs ...[]byte) (sessions.Store, error) {
+	return New(strings.Split(u.Host, ",")...)
+}
+
+// Store is an interface that represents a memcached based storage for
+// Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// New returns a new Store spreading sessions over the memcached servers in
+// serverList, given as "host:port" or as the path of a Unix socket.
+//
+// Servers are picked by consistent hashing, so adding or removing a server
+// only moves the sessions of its share of the ring. Sessions expire after
+// Options.MaxAge; sessions evicted by memcached start over as new sessions.
+func New(serverList ...string) (Store, error) {
+	ring, err := newHashRing(serverList)
+	if err != nil {
+		return nil, err
+	}
+	return sessions.NewServerStore(&memcacheBackend{memcache.NewFromSelector(ring)}), nil
+}
+
+type memcacheBackend struct {
+	client *memcache.Client
+}
+
+func (m *memcacheBackend) Load(id string) ([]byte, error) {
+	item, err := m.client.Get("session_" + id)
+	if err == memcache.ErrCacheMiss {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+func (m *memcacheBackend) Save(id string, data []byte, ttl time.Duration) error {
+	return m.client.Set(&memcache.Item{
+		Key:        "session_" + id,
+		Value:      data,
+		Expiration: memcacheExpiration(ttl),
+	})
+}
+
+func (m *memcacheBackend) Touch(id string, ttl time.Duration) error {
+	err := m.client.Touch("session_"+id, memcacheExpiration(ttl))
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+func (m *memcacheBackend) Delete(id string) error {
+	err := m.client.Delete("session_" + id)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+// memcacheExpiration converts ttl to memcached's expiration, which is taken
+// as a Unix timestamp instead of relative seconds past 30 days.
+func memcacheExpiration(ttl time.Duration) int32 {
+	secs := int64(ttl / time.Second)
+	if secs > 30*24*60*60 {
+		return int32(time.Now().Unix() + secs)
+	}
+	return int32(secs)
+}
+
+// hashRing is a memcache.ServerSelector using consistent hashing.
+type hashRing struct {
+	addrs []net.Addr
+	ring  *sessions.Ring
+}
+
+func newHashRing(servers []string) (*hashRing, error) {
+	h := &hashRing{}
+	for _, server := range servers {
+		var addr net.Addr
+		var err error
+		if strings.Contains(server, "/") {
+			addr, err = net.ResolveUnixAddr("unix", server)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", server)
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.addrs = append(h.addrs, addr)
+	}
+	h.ring = sessions.NewRing(servers, nil)
+	return h, nil
+}
+
+func (h *hashRing) PickServer(key string) (net.Addr, error) {
+	if len(h.addrs) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	return h.addrs[h.ring.Pick(key)], nil
+}
+
+func (h *hashRing) Each(f func(net.Addr) error) error {
+	for _, addr := range h.addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memcacheBackend) Ping(ctx context.Context) error {
+	return m.client.Ping()
+}
@@ -0,0 +1,27 @@
+package memcachestore
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_MemcacheHashRing(t *testing.T) {
+	before, _ := newHashRing([]string{"127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213"})
+	after, _ := newHashRing([]string{"127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213", "127.0.0.1:11214"})
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := "session_" + strconv.Itoa(i)
+		a, _ := before.PickServer(key)
+		b, _ := after.PickServer(key)
+		if a.String() != b.String() {
+			if b.String() != "127.0.0.1:11214" {
+				t.Fatal("Session moved between existing servers:", key)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 400 {
+		t.Error("Unexpected share of sessions moved to the new server:", moved)
+	}
+}
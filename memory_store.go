@@ -0,0 +1,255 @@
+package sessions
+
+import (
+	"bytes"
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often a MemoryStore drops expired sessions.
+const memorySweepInterval = time.Minute
+
+// MemoryStats counts the activity of a MemoryStore.
+type MemoryStats struct {
+	// Entries is the number of sessions currently held.
+	Entries int
+	// Hits and Misses count loads of sessions that were found or not.
+	Hits   int64
+	Misses int64
+	// Evictions counts sessions dropped to stay within maxEntries.
+	Evictions int64
+}
+
+// MemoryStore is an interface that represents an in-memory storage for
+// Sessions.
+type MemoryStore interface {
+	// Store is an embedded interface so that MemoryStore can be used
+	// as a session store.
+	Store
+	// Options sets the default options for each session stored in this
+	// MemoryStore.
+	Options(Options)
+	// Stats returns the current counters of the store.
+	Stats() MemoryStats
+	// Close stops the background goroutine.
+	Close()
+}
+
+// NewMemoryStore returns a new MemoryStore holding at most maxEntries
+// sessions, evicting the least recently used one when full. A maxEntries
+// of 0 means no limit.
+//
+// Sessions are lost when the process exits, so it suits single instance
+// apps and tests. Expired sessions are dropped every minute.
+func NewMemoryStore(maxEntries int) MemoryStore {
+	backend := &memoryBackend{
+		max:     maxEntries,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	store := &memoryStore{ServerStore: NewServerStore(backend), backend: backend}
+	backend.onEvict = store.expired
+	store.stop = store.StartCleanup(memorySweepInterval)
+	return store
+}
+
+type memoryStore struct {
+	*ServerStore
+	backend *memoryBackend
+	stop    func()
+}
+
+func (s *memoryStore) Stats() MemoryStats {
+	b := s.backend
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.Entries = b.lru.Len()
+	return stats
+}
+
+func (s *memoryStore) Close() {
+	s.stop()
+}
+
+type memoryEntry struct {
+	id       string
+	data     []byte
+	expires  time.Time
+	counters map[string]int64
+}
+
+type memoryBackend struct {
+	max int
+	// onEvict is called with the IDs of sessions evicted by a save.
+	onEvict func(ids []string)
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	stats   MemoryStats
+}
+
+func (m *memoryBackend) Load(id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[id]
+	if !ok || time.Now().After(el.Value.(*memoryEntry).expires) {
+		m.stats.Misses++
+		return nil, nil
+	}
+	m.stats.Hits++
+	m.lru.MoveToFront(el)
+	return el.Value.(*memoryEntry).data, nil
+}
+
+func (m *memoryBackend) Save(id string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	evicted := m.save(id, data, ttl)
+	m.mu.Unlock()
+
+	m.evicted(evicted)
+	return nil
+}
+
+func (m *memoryBackend) Swap(id string, old, data []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	var current []byte
+	if el, ok := m.entries[id]; ok && !time.Now().After(el.Value.(*memoryEntry).expires) {
+		current = el.Value.(*memoryEntry).data
+	}
+	if !bytes.Equal(current, old) {
+		m.mu.Unlock()
+		return false, nil
+	}
+	evicted := m.save(id, data, ttl)
+	m.mu.Unlock()
+
+	m.evicted(evicted)
+	return true, nil
+}
+
+// save stores data under id, evicting the least recently used entries
+// beyond max, and returns the IDs of the evicted ones. The caller holds mu.
+func (m *memoryBackend) save(id string, data []byte, ttl time.Duration) []string {
+	e := &memoryEntry{id: id, data: data, expires: time.Now().Add(ttl)}
+	if el, ok := m.entries[id]; ok {
+		e.counters = el.Value.(*memoryEntry).counters
+		el.Value = e
+		m.lru.MoveToFront(el)
+		return nil
+	}
+
+	m.entries[id] = m.lru.PushFront(e)
+	var evicted []string
+	for m.max > 0 && m.lru.Len() > m.max {
+		oldest := m.lru.Back()
+		m.remove(oldest)
+		m.stats.Evictions++
+		evicted = append(evicted, oldest.Value.(*memoryEntry).id)
+	}
+	return evicted
+}
+
+// evicted reports evicted sessions to onEvict, if set.
+func (m *memoryBackend) evicted(ids []string) {
+	if m.onEvict != nil && len(ids) > 0 {
+		m.onEvict(ids)
+	}
+}
+
+func (m *memoryBackend) Touch(id string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if el, ok := m.entries[id]; ok && !now.After(el.Value.(*memoryEntry).expires) {
+		el.Value.(*memoryEntry).expires = now.Add(ttl)
+		m.lru.MoveToFront(el)
+	}
+	return nil
+}
+
+func (m *memoryBackend) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[id]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+func (m *memoryBackend) remove(el *list.Element) {
+	m.lru.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry).id)
+}
+
+func (m *memoryBackend) DeletePrefix(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, el := range m.entries {
+		if strings.HasPrefix(id, prefix) {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+func (m *memoryBackend) Scan(fn func(id string, data []byte, expires time.Time) error) error {
+	m.mu.Lock()
+	now := time.Now()
+	entries := make([]memoryEntry, 0, len(m.entries))
+	for _, el := range m.entries {
+		if e := el.Value.(*memoryEntry); !now.After(e.expires) {
+			entries = append(entries, *e)
+		}
+	}
+	m.mu.Unlock()
+
+	// fn may call back into the backend, so it runs without holding mu
+	for _, e := range entries {
+		if err := fn(e.id, e.data, e.expires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryBackend) PurgeExpired() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []string
+	now := time.Now()
+	for el := m.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if e := el.Value.(*memoryEntry); now.After(e.expires) {
+			m.remove(el)
+			ids = append(ids, e.id)
+		}
+		el = prev
+	}
+	return ids, nil
+}
+
+func (m *memoryBackend) Increment(id, key string, delta int64) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[id]
+	if !ok || time.Now().After(el.Value.(*memoryEntry).expires) {
+		return 0, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if e.counters == nil {
+		e.counters = make(map[string]int64)
+	}
+	e.counters[key] += delta
+	return e.counters[key], true, nil
+}
@@ -0,0 +1,41 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_MemoryStore(t *testing.T) {
+	store := NewMemoryStore(2)
+	defer store.Close()
+
+	var cookies []string
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["n"] = i
+		res := httptest.NewRecorder()
+		if err := store.Save(req, res, s); err != nil {
+			t.Fatal(err)
+		}
+		cookies = append(cookies, res.Header().Get("Set-Cookie"))
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", cookies[0])
+	if s, _ := store.New(req, "my_session"); !s.IsNew {
+		t.Error("Least recently used session was not evicted")
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", cookies[2])
+	if s, _ := store.New(req, "my_session"); s.Values["n"] != 2 {
+		t.Error("Session was not loaded back:", s.Values)
+	}
+
+	stats := store.Stats()
+	if stats.Entries != 2 || stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,17 @@
+package sessions
+
+// MergeStrategy resolves a key present in both sessions passed to
+// Session.MergeInto. It receives the key, the value already held by the
+// target session and the incoming value, and returns the value to keep.
+type MergeStrategy func(key, existing, incoming interface{}) interface{}
+
+// KeepExisting is a MergeStrategy that keeps the target session's value.
+func KeepExisting(key, existing, incoming interface{}) interface{} {
+	return existing
+}
+
+// Overwrite is a MergeStrategy that replaces the target session's value
+// with the incoming one.
+func Overwrite(key, existing, incoming interface{}) interface{} {
+	return incoming
+}
@@ -0,0 +1,56 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"time"
+)
+
+func init() {
+	gob.Register(metadataKey(""))
+	gob.Register(time.Time{})
+}
+
+// metadataKey is the type of the keys the middleware keeps its bookkeeping
+// under. They are hidden from Keys, GetAll and the like.
+type metadataKey string
+
+const (
+	createdAtKey    metadataKey = "created_at"
+	lastAccessedKey metadataKey = "last_accessed"
+)
+
+// lastAccessedGranularity is how stale the last access time of a session may
+// get before it is saved just to update it.
+const lastAccessedGranularity = time.Minute
+
+func (s *session) CreatedAt(name string) time.Time {
+	t, _ := s.Session(name).Values[createdAtKey].(time.Time)
+	return t
+}
+
+func (s *session) LastAccessed(name string) time.Time {
+	t, _ := s.Session(name).Values[lastAccessedKey].(time.Time)
+	return t
+}
+
+// stamp records the creation and last access time of the named session
+// before it is saved. Sessions that would not be saved otherwise are only
+// stamped once their last access time is older than
+// lastAccessedGranularity, so reading a session does not cause a save on
+// every request, and fresh sessions nobody wrote to are not saved at all.
+func (s *session) stamp(name string) {
+	session := s.ss[name]
+	if session.Options != nil && session.Options.MaxAge < 0 {
+		return
+	}
+	now := time.Now()
+	last, _ := session.Values[lastAccessedKey].(time.Time)
+	if !s.Written(name) && (last.IsZero() || now.Sub(last) < lastAccessedGranularity) {
+		return
+	}
+	if _, ok := session.Values[createdAtKey]; !ok {
+		session.Values[createdAtKey] = now
+	}
+	session.Values[lastAccessedKey] = now
+	s.markDirty(name, lastAccessedKey)
+}
@@ -0,0 +1,50 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_SessionsMetadata(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	start := time.Now()
+	m.Get("/testsession", func(session Session) string {
+		if !session.CreatedAt("my_session").IsZero() {
+			t.Error("Unsaved session has a creation time")
+		}
+		session.Set("my_session", "hello", "world")
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		created := session.CreatedAt("my_session")
+		if created.Before(start) || !session.LastAccessed("my_session").Equal(created) {
+			t.Error("Timestamps were not recorded:", created, session.LastAccessed("my_session"))
+		}
+		if session.Len("my_session") != 1 {
+			t.Error("Metadata is listed with the values:", session.Keys("my_session"))
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+
+	if res2.Header().Get("Set-Cookie") != "" {
+		t.Error("Reading a recently accessed session saved it")
+	}
+}
@@ -0,0 +1,148 @@
+package sessions
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// MigrationStore is an interface that represents a Store moving sessions
+// from one store to another as users return.
+type MigrationStore interface {
+	// Store is an embedded interface so that MigrationStore can be used
+	// as a session store.
+	Store
+	// Cutover stops writing to the old store.
+	Cutover()
+}
+
+// NewMigrationStore returns a MigrationStore reading sessions from to and
+// falling back to from for sessions not migrated yet, e.g. to move from a
+// CookieStore to the Redis store without logging anyone out.
+//
+// Until Cutover is called every save goes to both stores. As both want the
+// cookie of the session, the one of from is kept under the session name
+// suffixed with "_old"; it is removed again once a session is saved after
+// the cutover.
+func NewMigrationStore(from, to Store) MigrationStore {
+	return &migrationStore{from: from, to: to}
+}
+
+type migrationStore struct {
+	from    Store
+	to      Store
+	cutover int32
+}
+
+func (m *migrationStore) Cutover() {
+	atomic.StoreInt32(&m.cutover, 1)
+}
+
+func (m *migrationStore) Delete(id string) error {
+	return deleteAll(id, m.from, m.to)
+}
+
+func (m *migrationStore) Exists(id string) (bool, error) {
+	return existsAny(id, m.to, m.from)
+}
+
+func (m *migrationStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, m.from, m.to)
+}
+
+func (m *migrationStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(m, name)
+}
+
+func (m *migrationStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := m.to.New(r, name)
+	if err == nil && !s.IsNew {
+		return s, nil
+	}
+
+	// not migrated yet
+	old, oldErr := m.from.New(oldRequest(r, name), name)
+	if oldErr != nil || old.IsNew {
+		return s, err
+	}
+
+	// carry the values over to a session of the new store
+	s, _ = m.to.New(withCookie(r, name, ""), name)
+	s.Values = old.Values
+	s.IsNew = false
+	return s, nil
+}
+
+func (m *migrationStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if atomic.LoadInt32(&m.cutover) == 1 {
+		if _, err := r.Cookie(s.Name() + "_old"); err == nil {
+			http.SetCookie(w, &http.Cookie{Name: s.Name() + "_old", Path: "/", MaxAge: -1})
+		}
+		return m.to.Save(r, w, s)
+	}
+
+	if err := m.to.Save(r, w, s); err != nil {
+		return err
+	}
+
+	// keep the ID the old store assigned, if any
+	old, _ := m.from.New(oldRequest(r, s.Name()), s.Name())
+	if old == nil {
+		old = sessions.NewSession(m.from, s.Name())
+	}
+	old.Values = s.Values
+	old.Options = s.Options
+	hw := headerWriter{http.Header{}}
+	if err := m.from.Save(r, hw, old); err != nil {
+		return err
+	}
+	for _, c := range (&http.Response{Header: hw.header}).Cookies() {
+		if c.Name == s.Name() {
+			c.Name += "_old"
+			http.SetCookie(w, c)
+		}
+	}
+	return nil
+}
+
+// oldRequest returns r as the old store saw it, with the cookie saved under
+// the "_old" suffix restored if there is one.
+func oldRequest(r *http.Request, name string) *http.Request {
+	if c, err := r.Cookie(name + "_old"); err == nil {
+		return withCookie(r, name, c.Value)
+	}
+	return r
+}
+
+// withCookie returns a copy of r whose cookie name holds value, or which
+// lacks it if value is empty.
+func withCookie(r *http.Request, name, value string) *http.Request {
+	cp := r.Clone(r.Context())
+	cp.Header.Del("Cookie")
+	for _, c := range r.Cookies() {
+		if c.Name != name {
+			cp.AddCookie(c)
+		}
+	}
+	if value != "" {
+		cp.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	return cp
+}
+
+// headerWriter captures the headers written to it.
+type headerWriter struct {
+	header http.Header
+}
+
+func (h headerWriter) Header() http.Header {
+	return h.header
+}
+
+func (h headerWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (h headerWriter) WriteHeader(int) {}
@@ -0,0 +1,59 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_MigrationStore(t *testing.T) {
+	from := NewCookieStore([]byte("secret123"))
+	to := NewMemoryStore(0)
+	defer to.Close()
+
+	// a session saved before the migration started
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := from.New(req, "my_session")
+	s.Values["user"] = "bob"
+	res := httptest.NewRecorder()
+	from.Save(req, res, s)
+
+	store := NewMigrationStore(from, to)
+	cookies := func(res *httptest.ResponseRecorder) *http.Request {
+		req, _ := http.NewRequest("GET", "/", nil)
+		for _, c := range res.Header()["Set-Cookie"] {
+			req.Header.Add("Cookie", strings.Split(c, ";")[0])
+		}
+		return req
+	}
+
+	req = cookies(res)
+	s, err := store.New(req, "my_session")
+	if err != nil || s.Values["user"] != "bob" {
+		t.Fatal("Session was not read from the old store:", s.Values, err)
+	}
+	res = httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Header()["Set-Cookie"]) != 2 {
+		t.Error("Session was not written to both stores:", res.Header()["Set-Cookie"])
+	}
+
+	req = cookies(res)
+	if s, _ := to.New(req, "my_session"); s.Values["user"] != "bob" {
+		t.Error("Session was not migrated to the new store")
+	}
+	if s, _ := from.New(oldRequest(req, "my_session"), "my_session"); s.Values["user"] != "bob" {
+		t.Error("Old store was not kept up to date")
+	}
+
+	store.Cutover()
+	s, _ = store.New(req, "my_session")
+	res = httptest.NewRecorder()
+	store.Save(req, res, s)
+	if c := res.Header()["Set-Cookie"]; len(c) != 2 || !strings.Contains(c[0], "my_session_old=;") {
+		t.Error("Old cookie was not removed after the cutover:", c)
+	}
+}
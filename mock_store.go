@@ -0,0 +1,122 @@
+package sessions
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/sessions"
+)
+
+// MockStore is a Store for unit testing handlers. It ignores cookies and
+// holds a single set of sessions that every request sees, which tests seed
+// before a request and inspect afterwards.
+//
+//	store := sessions.NewMockStore()
+//	store.Seed("my_session", map[interface{}]interface{}{"user": "bob"})
+//	m.Use(sessions.Sessions(store))
+//	...
+//	if store.Values("my_session")["cart"] == nil { ... }
+type MockStore struct {
+	mu        sync.Mutex
+	values    map[string]map[interface{}]interface{}
+	saves     map[string]int
+	destroyed map[string]bool
+	err       error
+}
+
+// NewMockStore returns an empty MockStore.
+func NewMockStore() *MockStore {
+	return &MockStore{
+		values:    make(map[string]map[interface{}]interface{}),
+		saves:     make(map[string]int),
+		destroyed: make(map[string]bool),
+	}
+}
+
+// Seed sets the values of the named session.
+func (m *MockStore) Seed(name string, values map[interface{}]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = copyMap(values)
+	delete(m.destroyed, name)
+}
+
+// Values returns a copy of the values last saved for the named session, or
+// nil if there are none.
+func (m *MockStore) Values(name string) map[interface{}]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[name] == nil {
+		return nil
+	}
+	return copyMap(m.values[name])
+}
+
+// Saves returns how often the named session was saved.
+func (m *MockStore) Saves(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saves[name]
+}
+
+// Destroyed reports whether the named session was last saved with a
+// negative MaxAge.
+func (m *MockStore) Destroyed(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.destroyed[name]
+}
+
+// Fail makes every operation return err until it is called with nil.
+func (m *MockStore) Fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+func (m *MockStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(m, name)
+}
+
+func (m *MockStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := sessions.NewSession(m, name)
+	s.Options = &sessions.Options{Path: "/"}
+	s.IsNew = true
+	if m.err != nil {
+		return s, m.err
+	}
+	if values, ok := m.values[name]; ok {
+		s.Values = copyMap(values)
+		s.IsNew = false
+	}
+	return s, nil
+}
+
+func (m *MockStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.saves[s.Name()]++
+	if s.Options != nil && s.Options.MaxAge < 0 {
+		delete(m.values, s.Name())
+		m.destroyed[s.Name()] = true
+		return nil
+	}
+	m.values[s.Name()] = copyMap(s.Values)
+	delete(m.destroyed, s.Name())
+	return nil
+}
+
+func copyMap(values map[interface{}]interface{}) map[interface{}]interface{} {
+	cp := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,35 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_MockStore(t *testing.T) {
+	store := NewMockStore()
+	store.Seed("my_session", map[interface{}]interface{}{"user": "bob"})
+
+	m := martini.Classic()
+	m.Use(Sessions(store))
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "greeted", session.Get("my_session", "user"))
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	if store.Values("my_session")["greeted"] != "bob" || store.Saves("my_session") != 1 {
+		t.Error("Handler side effects were not recorded:", store.Values("my_session"))
+	}
+
+	store.Fail(errors.New("backend down"))
+	req, _ = http.NewRequest("GET", "/", nil)
+	if _, err := store.New(req, "my_session"); err == nil {
+		t.Error("Store did not fail on demand")
+	}
+}
@@ -0,0 +1,142 @@
+// Package mongostore provides a MongoDB based storage for martini-contrib
+// sessions.
+//
+//	store, err := mongostore.New(client, mongostore.Options{Database: "app"})
+//	m.Use(sessions.Sessions(store))
+//
+// Importing the package registers the mongodb:// and mongodb+srv:// schemes
+// of sessions.OpenStore, e.g.
+// mongodb://host:27017/?database=app&collection=sessions.
+package mongostore
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+func init() {
+	sessions.RegisterScheme("mongodb", open)
+	sessions.RegisterScheme("mongodb+srv", open)
+}
+
+func open(u *url.URL, keyPairs ...[]byte) (sessions.Store, error) {
+	q := u.Query()
+	opts := Options{Database: q.Get("database"), Collection: q.Get("collection")}
+	q.Del("database")
+	q.Del("collection")
+	uri := *u
+	uri.RawQuery = q.Encode()
+	client, err := mongo.Connect(options.Client().ApplyURI(uri.String()))
+	if err != nil {
+		return nil, err
+	}
+	store, err := New(client, opts)
+	if err != nil {
+		client.Disconnect(context.Background())
+	}
+	return store, err
+}
+
+// Store is an interface that represents a MongoDB based storage for
+// Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// Options configures where a Store keeps its sessions.
+type Options struct {
+	// Database and Collection default to "sessions".
+	Database   string
+	Collection string
+	// ReadPreference defaults to the one of the client. Reading sessions
+	// from secondaries may return stale values right after a save.
+	ReadPreference *readpref.ReadPref
+}
+
+// New returns a new Store keeping a document per session.
+//
+// A TTL index on the expires_at field, derived from Options.MaxAge, is
+// created so that MongoDB deletes expired sessions by itself.
+func New(client *mongo.Client, opts Options) (Store, error) {
+	if opts.Database == "" {
+		opts.Database = "sessions"
+	}
+	if opts.Collection == "" {
+		opts.Collection = "sessions"
+	}
+	collOpts := options.Collection()
+	if opts.ReadPreference != nil {
+		collOpts.SetReadPreference(opts.ReadPreference)
+	}
+	coll := client.Database(opts.Database).Collection(opts.Collection, collOpts)
+
+	_, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions.NewServerStore(&mongoBackend{coll}), nil
+}
+
+type mongoBackend struct {
+	coll *mongo.Collection
+}
+
+type mongoSession struct {
+	ID      string    `bson:"_id"`
+	Data    []byte    `bson:"data"`
+	Expires time.Time `bson:"expires_at"`
+}
+
+func (m *mongoBackend) Load(id string) ([]byte, error) {
+	var doc mongoSession
+	err := m.coll.FindOne(context.Background(), bson.D{{Key: "_id", Value: id}}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the TTL monitor only runs once a minute
+	if time.Now().After(doc.Expires) {
+		return nil, nil
+	}
+	return doc.Data, nil
+}
+
+func (m *mongoBackend) Save(id string, data []byte, ttl time.Duration) error {
+	doc := mongoSession{ID: id, Data: data, Expires: time.Now().Add(ttl)}
+	_, err := m.coll.ReplaceOne(context.Background(), bson.D{{Key: "_id", Value: id}}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *mongoBackend) Touch(id string, ttl time.Duration) error {
+	now := time.Now()
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "expires_at", Value: bson.D{{Key: "$gte", Value: now}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "expires_at", Value: now.Add(ttl)}}}}
+	_, err := m.coll.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+func (m *mongoBackend) Delete(id string) error {
+	_, err := m.coll.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: id}})
+	return err
+}
+
+func (m *mongoBackend) Ping(ctx context.Context) error {
+	return m.coll.Database().Client().Ping(ctx, nil)
+}
@@ -0,0 +1,39 @@
+package mongostore
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Test_Conformance runs against the server at SESSIONS_TEST_MONGODB, e.g.
+//
+//	SESSIONS_TEST_MONGODB=mongodb://localhost:27017 go test
+func Test_Conformance(t *testing.T) {
+	uri := os.Getenv("SESSIONS_TEST_MONGODB")
+	if uri == "" {
+		t.Skip("SESSIONS_TEST_MONGODB is not set")
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	defer client.Database("sessions_test").Drop(context.Background())
+
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		store, err := New(client, Options{Database: "sessions_test", Collection: "sessions_" + strconv.Itoa(n)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}, storetest.Options{})
+}
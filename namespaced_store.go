@@ -0,0 +1,93 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// ErrFlushUnsupported is returned by NamespacedStore.Flush if the wrapped
+// store can not delete sessions by prefix.
+var ErrFlushUnsupported = errors.New("sessions: store does not support flushing a namespace")
+
+// NamespacedStore is an interface that represents a Store isolating the
+// sessions of one app in a backend shared with others.
+type NamespacedStore interface {
+	// Store is an embedded interface so that NamespacedStore can be used
+	// as a session store.
+	Store
+	// Flush deletes all sessions of the namespace, leaving those of other
+	// apps untouched.
+	Flush() error
+}
+
+// NewNamespacedStore returns a NamespacedStore wrapping store, a
+// server-side store such as the one of NewRedisStore. The IDs, and so the
+// backend keys, of new sessions start with prefix and a colon, e.g.
+// "app1:", and sessions of other namespaces are never loaded. prefix may
+// only hold letters, digits, '_', '-' and '.', so no namespace is a prefix
+// of another.
+//
+// Flush is supported by the Redis, memory, SQL, Bolt, Badger, LevelDB and
+// etcd stores when wrapped directly.
+func NewNamespacedStore(store Store, prefix string) (NamespacedStore, error) {
+	if prefix == "" {
+		return nil, errors.New("sessions: namespace is empty")
+	}
+	for _, c := range prefix {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-.", c)) {
+			return nil, fmt.Errorf("sessions: invalid character %q in namespace %q", c, prefix)
+		}
+	}
+	return &namespacedStore{Store: store, prefix: prefix + ":"}, nil
+}
+
+type namespacedStore struct {
+	Store
+	prefix string
+}
+
+func (n *namespacedStore) Unwrap() Store {
+	return n.Store
+}
+
+// prefixFlusher is implemented by stores able to delete all sessions with
+// IDs starting with a prefix.
+type prefixFlusher interface {
+	flush(prefix string) error
+}
+
+func (n *namespacedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(n, name)
+}
+
+func (n *namespacedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := n.Store.New(r, name)
+	if s != nil && s.ID != "" && !strings.HasPrefix(s.ID, n.prefix) {
+		s.ID = ""
+		s.Values = make(map[interface{}]interface{})
+		s.IsNew = true
+	}
+	return s, err
+}
+
+func (n *namespacedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if s.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		s.ID = n.prefix + id
+	}
+	return n.Store.Save(r, w, s)
+}
+
+func (n *namespacedStore) Flush() error {
+	if f, ok := n.Store.(prefixFlusher); ok {
+		return f.flush(n.prefix)
+	}
+	return ErrFlushUnsupported
+}
@@ -0,0 +1,52 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_NamespacedStore(t *testing.T) {
+	shared := NewMemoryStore(0)
+	defer shared.Close()
+	app1, _ := NewNamespacedStore(shared, "app")
+	app2, _ := NewNamespacedStore(shared, "app2")
+	for _, prefix := range []string{"", "app:", "app/1"} {
+		if _, err := NewNamespacedStore(shared, prefix); err == nil {
+			t.Errorf("Namespace %q was accepted", prefix)
+		}
+	}
+
+	save := func(store Store) string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["hello"] = "world"
+		res := httptest.NewRecorder()
+		if err := store.Save(req, res, s); err != nil {
+			t.Fatal(err)
+		}
+		return res.Header().Get("Set-Cookie")
+	}
+	load := func(store Store, cookie string) bool {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", cookie)
+		s, _ := store.New(req, "my_session")
+		return !s.IsNew
+	}
+
+	c1, c2 := save(app1), save(app2)
+	if !strings.Contains(c1, "my_session=app:") {
+		t.Error("Session ID lacks the namespace:", c1)
+	}
+	if load(app2, c1) || load(app1, c2) {
+		t.Error("Session of another namespace was loaded")
+	}
+
+	if err := app1.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if load(app1, c1) || !load(app2, c2) {
+		t.Error("Flush did not only delete the sessions of its namespace")
+	}
+}
@@ -0,0 +1,101 @@
+// Package natsstore provides a NATS JetStream KV based storage for
+// martini-contrib sessions.
+//
+//	store, err := natsstore.New(js, "sessions", 3)
+//	m.Use(sessions.Sessions(store))
+package natsstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsBucketTTL is how long the bucket keeps a key after its last write,
+// whatever the MaxAge of the session.
+const natsBucketTTL = 30 * 24 * time.Hour
+
+// Store is an interface that represents a NATS JetStream KV based storage
+// for Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+}
+
+// New returns a new Store keeping sessions in the JetStream KV bucket, which
+// is created with the given number of replicas if it does not exist yet.
+//
+// Every save overwrites the key with a value carrying the expiry of
+// Options.MaxAge, and expired sessions are ignored when read. The bucket
+// drops keys 30 days after their last write, which bounds the lifetime of
+// sessions that are not used anymore.
+func New(js jetstream.JetStream, bucket string, replicas int) (Store, error) {
+	kv, err := js.CreateOrUpdateKeyValue(context.Background(), jetstream.KeyValueConfig{
+		Bucket:   bucket,
+		Replicas: replicas,
+		TTL:      natsBucketTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions.NewServerStore(&natsBackend{kv}), nil
+}
+
+type natsBackend struct {
+	kv jetstream.KeyValue
+}
+
+func (n *natsBackend) Load(id string) ([]byte, error) {
+	entry, err := n.kv.Get(context.Background(), id)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return sessions.UnlessExpired(entry.Value()), nil
+}
+
+func (n *natsBackend) Save(id string, data []byte, ttl time.Duration) error {
+	_, err := n.kv.Put(context.Background(), id, sessions.WithExpiry(data, ttl))
+	return err
+}
+
+func (n *natsBackend) Touch(id string, ttl time.Duration) error {
+	ctx := context.Background()
+	entry, err := n.kv.Get(ctx, id)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	data := sessions.UnlessExpired(entry.Value())
+	if data == nil {
+		return nil
+	}
+	// a session saved since it was read keeps its new expiry
+	_, err = n.kv.Update(ctx, id, sessions.WithExpiry(data, ttl), entry.Revision())
+	if errors.Is(err, jetstream.ErrKeyExists) {
+		return nil
+	}
+	return err
+}
+
+func (n *natsBackend) Delete(id string) error {
+	err := n.kv.Purge(context.Background(), id)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (n *natsBackend) Ping(ctx context.Context) error {
+	_, err := n.kv.Status(ctx)
+	return err
+}
@@ -0,0 +1,45 @@
+package natsstore
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Test_Conformance runs against the JetStream enabled server at
+// SESSIONS_TEST_NATS, e.g.
+//
+//	SESSIONS_TEST_NATS=nats://localhost:4222 go test
+func Test_Conformance(t *testing.T) {
+	url := os.Getenv("SESSIONS_TEST_NATS")
+	if url == "" {
+		t.Skip("SESSIONS_TEST_NATS is not set")
+	}
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		bucket := "sessions_test_" + strconv.Itoa(n)
+		t.Cleanup(func() { js.DeleteKeyValue(context.Background(), bucket) })
+		store, err := New(js, bucket, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}, storetest.Options{})
+}
@@ -0,0 +1,234 @@
+// Package notifications keeps a per-visitor queue of notices with read state
+// in a martini-contrib session. Unlike flashes, notifications stay until
+// they are removed, so they suit "you have 3 unread notices" style UIs.
+//
+//	m.Get("/inbox", func(s sessions.Session) string {
+//		q := notifications.New(s, "my_session")
+//		page, total, _ := q.Page(1, 20)
+//		...
+//	})
+//
+// Queues larger than Options.MaxInline bytes are moved to an Overflow backend
+// and only a reference is left in the session.
+package notifications
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/overflow"
+)
+
+const key = "_notifications"
+
+// ErrNoOverflow is returned when the session references an overflowed queue
+// but the Queue was created without an Overflow backend.
+var ErrNoOverflow = errors.New("notifications: queue is in overflow storage but no Overflow is configured")
+
+func init() {
+	gob.Register([]Notification{})
+}
+
+// Level is the severity of a notification.
+type Level int
+
+const (
+	Info Level = iota
+	Success
+	Warning
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Success:
+		return "success"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return "info"
+}
+
+// Notification is a single notice.
+type Notification struct {
+	ID      string
+	Level   Level
+	Message string
+	Created time.Time
+	Read    bool
+}
+
+// Overflow persists queues that are too large to be kept in the session.
+type Overflow = overflow.Store
+
+// Options configures a Queue.
+type Options struct {
+	// Max is the number of notifications kept. The oldest read ones, then
+	// the oldest unread ones are dropped beyond it. Defaults to 50.
+	Max int
+	// Overflow receives queues whose encoding exceeds MaxInline bytes.
+	// Queues are always kept in the session when it is nil.
+	Overflow Overflow
+	// MaxInline is the largest encoded queue kept in the session.
+	// Defaults to 1024.
+	MaxInline int
+}
+
+// Queue is the notification queue of a named session.
+type Queue struct {
+	session sessions.Session
+	name    string
+	max     int
+	value   overflow.Value
+}
+
+// New returns the notification queue stored in the named session.
+func New(s sessions.Session, name string, options ...Options) *Queue {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Max == 0 {
+		opts.Max = 50
+	}
+	if opts.MaxInline == 0 {
+		opts.MaxInline = 1024
+	}
+	return &Queue{
+		session: s,
+		name:    name,
+		max:     opts.Max,
+		value:   overflow.Value{Key: key, Store: opts.Overflow, MaxInline: opts.MaxInline},
+	}
+}
+
+// Add queues an unread notification and returns its ID.
+func (q *Queue) Add(level Level, message string) (string, error) {
+	all, err := q.All()
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	n := Notification{ID: hex.EncodeToString(b), Level: level, Message: message, Created: time.Now()}
+	all = append([]Notification{n}, all...)
+
+	for len(all) > q.max {
+		drop := len(all) - 1
+		for i := len(all) - 1; i >= 0; i-- {
+			if all[i].Read {
+				drop = i
+				break
+			}
+		}
+		all = append(all[:drop], all[drop+1:]...)
+	}
+	return n.ID, q.save(all)
+}
+
+// All returns every notification, newest first.
+func (q *Queue) All() ([]Notification, error) {
+	var all []Notification
+	err := q.value.Load(q.session, q.name, &all)
+	if err == overflow.ErrNoStore {
+		return nil, ErrNoOverflow
+	}
+	return all, err
+}
+
+// Page returns the notifications of the given 1-based page, newest first,
+// along with the total number of notifications.
+func (q *Queue) Page(page, perPage int) ([]Notification, int, error) {
+	all, err := q.All()
+	if err != nil || page < 1 || perPage < 1 {
+		return nil, len(all), err
+	}
+	start := (page - 1) * perPage
+	if start >= len(all) {
+		return nil, len(all), nil
+	}
+	end := start + perPage
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[start:end], len(all), nil
+}
+
+// Unread returns the number of unread notifications.
+func (q *Queue) Unread() (int, error) {
+	all, err := q.All()
+	n := 0
+	for _, notification := range all {
+		if !notification.Read {
+			n++
+		}
+	}
+	return n, err
+}
+
+// MarkRead marks the notification with the given ID as read.
+func (q *Queue) MarkRead(id string) error {
+	return q.update(func(n *Notification) bool {
+		if n.ID == id && !n.Read {
+			n.Read = true
+			return true
+		}
+		return false
+	})
+}
+
+// MarkAllRead marks every notification as read.
+func (q *Queue) MarkAllRead() error {
+	return q.update(func(n *Notification) bool {
+		changed := !n.Read
+		n.Read = true
+		return changed
+	})
+}
+
+// Remove deletes the notification with the given ID.
+func (q *Queue) Remove(id string) error {
+	all, err := q.All()
+	if err != nil {
+		return err
+	}
+	for i, n := range all {
+		if n.ID == id {
+			return q.save(append(all[:i], all[i+1:]...))
+		}
+	}
+	return nil
+}
+
+func (q *Queue) update(fn func(*Notification) bool) error {
+	all, err := q.All()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range all {
+		if fn(&all[i]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return q.save(all)
+}
+
+// save stores all. Overflow entries are written before the session
+// references them and deleted once it no longer does.
+func (q *Queue) save(all []Notification) error {
+	if len(all) == 0 {
+		return q.value.Delete(q.session, q.name)
+	}
+	return q.value.Save(q.session, q.name, all)
+}
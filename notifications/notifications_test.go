@@ -0,0 +1,81 @@
+package notifications
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+type memOverflow map[string][]byte
+
+func (m memOverflow) Load(id string) ([]byte, error) {
+	data, ok := m[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (m memOverflow) Save(id string, data []byte) error {
+	m[id] = data
+	return nil
+}
+
+func (m memOverflow) Delete(id string) error {
+	delete(m, id)
+	return nil
+}
+
+func serve(h martini.Handler) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+	m.Get("/", h, func() string { return "OK" })
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_Queue(t *testing.T) {
+	serve(func(s sessions.Session) {
+		q := New(s, "my_session", Options{Max: 3})
+		first, _ := q.Add(Info, "one")
+		q.MarkRead(first)
+		q.Add(Warning, "two")
+		q.Add(Error, "three")
+		q.Add(Success, "four")
+
+		if n, _ := q.Unread(); n != 3 {
+			t.Error("Unexpected unread count:", n)
+		}
+		page, total, _ := q.Page(2, 2)
+		if total != 3 || len(page) != 1 || page[0].Message != "two" {
+			t.Error("Unexpected page:", page, total)
+		}
+
+		q.MarkAllRead()
+		if n, _ := q.Unread(); n != 0 {
+			t.Error("MarkAllRead left unread notifications:", n)
+		}
+	})
+}
+
+func Test_QueueOverflow(t *testing.T) {
+	overflow := memOverflow{}
+	serve(func(s sessions.Session) {
+		q := New(s, "my_session", Options{Overflow: overflow, MaxInline: 128})
+		for i := 0; i < 5; i++ {
+			q.Add(Info, "a notification long enough to overflow")
+		}
+		if len(overflow) == 0 {
+			t.Fatal("Large queue was not moved to the overflow store")
+		}
+		if all, err := q.All(); len(all) != 5 || err != nil {
+			t.Error("Unexpected queue from overflow:", len(all), err)
+		}
+	})
+}
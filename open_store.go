@@ -0,0 +1,112 @@
+package sessions
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opener returns the store described by u, for OpenStore.
+type Opener func(u *url.URL, keyPairs ...[]byte) (Store, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]Opener{
+		"cookie": openCookie,
+		"memory": openMemory,
+		"redis":  openRedis,
+		"file":   openFile,
+	}
+)
+
+// RegisterScheme makes the stores opened by open available to OpenStore
+// under scheme. The backend packages register their schemes when imported,
+// like database/sql drivers:
+//
+//	import _ "github.com/martini-contrib/sessions/postgresstore"
+//
+// It panics if scheme is already registered.
+func RegisterScheme(scheme string, open Opener) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	if _, dup := schemes[scheme]; dup {
+		panic("sessions: RegisterScheme called twice for scheme " + scheme)
+	}
+	schemes[scheme] = open
+}
+
+// OpenStore returns the store described by dsn, so the backend can be
+// configured with a single environment variable:
+//
+//	cookie://
+//	memory://?max_entries=10000
+//	redis://:password@host:6379/2?prefix=app_
+//	file:///var/lib/app/sessions?gc=1h
+//
+// Other schemes, such as postgres:// or bolt://, are available once their
+// backend package is imported. keyPairs are used by the stores that sign
+// their cookie, as for NewCookieStore.
+func OpenStore(dsn string, keyPairs ...[]byte) (Store, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	schemesMu.RLock()
+	open, ok := schemes[u.Scheme]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("sessions: unknown store %q", u.Scheme)
+	}
+	return open(u, keyPairs...)
+}
+
+func openCookie(u *url.URL, keyPairs ...[]byte) (Store, error) {
+	return NewCookieStore(keyPairs...), nil
+}
+
+func openMemory(u *url.URL, keyPairs ...[]byte) (Store, error) {
+	max, err := intParam(u.Query(), "max_entries", 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemoryStore(max), nil
+}
+
+func openRedis(u *url.URL, keyPairs ...[]byte) (Store, error) {
+	db := 0
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		var err error
+		if db, err = strconv.Atoi(p); err != nil {
+			return nil, fmt.Errorf("sessions: invalid Redis database %q", p)
+		}
+	}
+	password, _ := u.User.Password()
+	return NewRedisStore(u.Host, password, db, u.Query().Get("prefix"), keyPairs...)
+}
+
+func openFile(u *url.URL, keyPairs ...[]byte) (Store, error) {
+	var gc time.Duration
+	if s := u.Query().Get("gc"); s != "" {
+		var err error
+		if gc, err = time.ParseDuration(s); err != nil {
+			return nil, err
+		}
+	}
+	return NewFilesystemStore(u.Host+u.Path, gc)
+}
+
+func intParam(q url.Values, name string, def int) (int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("sessions: invalid %s %q", name, s)
+	}
+	return n, nil
+}
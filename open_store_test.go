@@ -0,0 +1,36 @@
+package sessions
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func Test_OpenStore(t *testing.T) {
+	dir := t.TempDir()
+	for dsn, want := range map[string]bool{
+		"cookie://":                true,
+		"memory://?max_entries=10": true,
+		"file://" + filepath.Join(dir, "files") + "?gc=1h": true,
+		"memory://?max_entries=ten":                        false,
+		"redis://localhost:6379/db":                        false,
+		"sqlite://" + filepath.Join(dir, "s.db"):           false,
+	} {
+		store, err := OpenStore(dsn, []byte("secret123"))
+		if (err == nil) != want {
+			t.Errorf("OpenStore(%q) = %v, %v", dsn, store, err)
+		}
+	}
+
+	store, _ := OpenStore("memory://?max_entries=10")
+	if _, ok := store.(MemoryStore); !ok {
+		t.Errorf("OpenStore returned a %T for a memory DSN", store)
+	}
+
+	RegisterScheme("test", func(u *url.URL, keyPairs ...[]byte) (Store, error) {
+		return NewMemoryStore(0), nil
+	})
+	if _, err := OpenStore("test://"); err != nil {
+		t.Error("Registered scheme was not opened:", err)
+	}
+}
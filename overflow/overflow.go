@@ -0,0 +1,161 @@
+// Package overflow keeps session values that may grow too large for the
+// session, such as carts or notification queues, in a separate Store once
+// their encoding exceeds a size, leaving only a reference in the session.
+//
+//	v := overflow.Value{Key: "_cart", Store: store, MaxInline: 1024}
+//	var items []Item
+//	if err := v.Load(s, "my_session", &items); err != nil {
+//		...
+//	}
+//	err := v.Save(s, "my_session", append(items, item))
+//
+// An entry replaced by a newer value is deleted only once the session
+// referencing the newer value was saved, so a session that fails to save
+// never references a deleted entry.
+package overflow
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/martini-contrib/sessions"
+)
+
+// ErrNoStore is returned when the session references an entry but the Value
+// has no Store.
+var ErrNoStore = errors.New("overflow: value is in overflow storage but no Store is configured")
+
+func init() {
+	gob.Register(ref{})
+	gob.Register(replaced{})
+}
+
+// Store persists values that are too large to be kept in the session.
+type Store interface {
+	Load(id string) ([]byte, error)
+	Save(id string, data []byte) error
+	Delete(id string) error
+}
+
+// Value is a session value kept in the session while its gob encoding fits
+// in MaxInline bytes and in Store beyond that.
+type Value struct {
+	// Key is the session key of the value.
+	Key string
+	// Store receives values whose encoding exceeds MaxInline bytes. Values
+	// are always kept in the session when it is nil.
+	Store Store
+	// MaxInline is the largest encoded value kept in the session.
+	MaxInline int
+}
+
+type ref struct {
+	ID string
+}
+
+// replaced lists the entries superseded during the request that set it,
+// which are deleted by the first change of a later request. set is true
+// until the list is saved, as gob doesn't encode it.
+type replaced struct {
+	IDs []string
+	set bool
+}
+
+// Load decodes the value of the named session into ptr, a pointer to the
+// type of the value. ptr is left untouched when the session holds no value.
+func (v Value) Load(s sessions.Session, name string, ptr interface{}) error {
+	switch val := s.Get(name, v.Key).(type) {
+	case nil:
+		return nil
+	case ref:
+		if v.Store == nil {
+			return ErrNoStore
+		}
+		data, err := v.Store.Load(val.ID)
+		if err != nil {
+			return err
+		}
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(ptr)
+	default:
+		dst := reflect.ValueOf(ptr).Elem()
+		if !reflect.TypeOf(val).AssignableTo(dst.Type()) {
+			return fmt.Errorf("overflow: session holds a %T, not a %s", val, dst.Type())
+		}
+		dst.Set(reflect.ValueOf(val))
+		return nil
+	}
+}
+
+// Save stores val in the named session, or in Store when it is too large.
+// The entry val replaces, if any, is deleted by a later change. An error
+// deleting entries replaced by earlier requests is returned once val is
+// saved.
+func (v Value) Save(s sessions.Session, name string, val interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(val); err != nil {
+		return err
+	}
+	if v.Store == nil || buf.Len() <= v.MaxInline {
+		return v.replace(s, name, val)
+	}
+
+	id := newID()
+	if err := v.Store.Save(id, buf.Bytes()); err != nil {
+		return err
+	}
+	return v.replace(s, name, ref{ID: id})
+}
+
+// Delete removes the value from the named session. Its entry, if any, is
+// deleted by a later change.
+func (v Value) Delete(s sessions.Session, name string) error {
+	return v.replace(s, name, nil)
+}
+
+// replace sets the value of the named session to val, or removes it if val
+// is nil. Entries replaced by an earlier request are deleted, as the saved
+// session no longer references them, while the entry replaced now is kept
+// until then.
+func (v Value) replace(s sessions.Session, name string, val interface{}) error {
+	key := v.Key + ".replaced"
+	prev, _ := s.Get(name, key).(replaced)
+
+	var err error
+	ids := prev.IDs
+	if !prev.set && v.Store != nil {
+		ids = nil
+		for _, id := range prev.IDs {
+			if e := v.Store.Delete(id); e != nil {
+				ids = append(ids, id)
+				err = e
+			}
+		}
+	}
+	if r, ok := s.Get(name, v.Key).(ref); ok {
+		ids = append(ids, r.ID)
+	}
+
+	if val == nil {
+		s.Delete(name, v.Key)
+	} else {
+		s.Set(name, v.Key, val)
+	}
+	if len(ids) == 0 {
+		s.Delete(name, key)
+	} else {
+		s.Set(name, key, replaced{IDs: ids, set: true})
+	}
+	return err
+}
+
+func newID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(b), "=")
+}
@@ -0,0 +1,125 @@
+package overflow
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+type memStore map[string][]byte
+
+func (m memStore) Load(id string) ([]byte, error) {
+	data, ok := m[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (m memStore) Save(id string, data []byte) error {
+	m[id] = data
+	return nil
+}
+
+func (m memStore) Delete(id string) error {
+	delete(m, id)
+	return nil
+}
+
+func Test_Value(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+
+	store := memStore{}
+	v := Value{Key: "_list", Store: store, MaxInline: 64}
+	m.Get("/set/:n", func(s sessions.Session, p martini.Params) string {
+		list := strings.Repeat("x", len(p["n"])*100)
+		if err := v.Save(s, "my_session", list); err != nil {
+			t.Error(err)
+		}
+		return "OK"
+	})
+	m.Get("/clear", func(s sessions.Session) string {
+		if err := v.Delete(s, "my_session"); err != nil {
+			t.Error(err)
+		}
+		return "OK"
+	})
+	m.Get("/get", func(s sessions.Session) string {
+		var list string
+		if err := v.Load(s, "my_session", &list); err != nil {
+			t.Error(err)
+		}
+		return list
+	})
+
+	cookie := ""
+	get := func(path string) string {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		if c := res.Header().Get("Set-Cookie"); c != "" {
+			cookie = c
+		}
+		return res.Body.String()
+	}
+
+	get("/set/a")
+	get("/set/aa")
+	if len(store) != 2 {
+		t.Error("Replaced entry was deleted before the session was saved:", len(store))
+	}
+
+	// the session replacing the first entry was saved by now
+	get("/set/aaa")
+	if len(store) != 2 {
+		t.Error("Replaced entry was not deleted by the next change:", len(store))
+	}
+	if list := get("/get"); len(list) != 300 {
+		t.Error("Unexpected value:", len(list))
+	}
+
+	// a session that failed to save still references a live entry
+	saved := cookie
+	get("/clear")
+	cookie = saved
+	if list := get("/get"); len(list) != 300 {
+		t.Error("Entry of the saved session was deleted:", len(list))
+	}
+
+	get("/clear")
+	get("/clear")
+	if len(store) != 0 {
+		t.Error("Entries were not released:", len(store))
+	}
+}
+
+func Test_ValueInline(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+
+	m.Get("/", func(s sessions.Session) string {
+		v := Value{Key: "_list"}
+		v.Save(s, "my_session", []string{"a", "b"})
+
+		var list []string
+		if err := v.Load(s, "my_session", &list); err != nil || len(list) != 2 {
+			t.Error("Unexpected inline value:", list, err)
+		}
+		var wrong []int
+		if err := v.Load(s, "my_session", &wrong); err == nil {
+			t.Error("Value of another type was loaded")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
@@ -0,0 +1,42 @@
+//go:build postgres
+
+package postgresstore
+
+import (
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+)
+
+// Test_Conformance runs against the database at SESSIONS_TEST_POSTGRES. It
+// needs the postgres build tag, which pulls in the driver:
+//
+//	SESSIONS_TEST_POSTGRES=postgres://localhost/test?sslmode=disable go test -tags postgres
+func Test_Conformance(t *testing.T) {
+	dsn := os.Getenv("SESSIONS_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("SESSIONS_TEST_POSTGRES is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		table := "sessions_test_" + strconv.Itoa(n)
+		t.Cleanup(func() { db.Exec(`DROP TABLE IF EXISTS "` + table + `"`) })
+		store, err := New(db, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}, storetest.Options{})
+}
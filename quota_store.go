@@ -0,0 +1,420 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/sessions"
+)
+
+// ErrQuotaExceeded is returned when saving a session would push its owner
+// past the storage quota of a QuotaStore.
+var ErrQuotaExceeded = errors.New("sessions: storage quota exceeded")
+
+// usageSweep is how often the in-memory UsageStore drops the sessions whose
+// accounting expired.
+const usageSweep = time.Minute
+
+// pendingQuotaKey prefixes the keys accounting new sessions until the
+// store assigns them an ID.
+const pendingQuotaKey = "pending:"
+
+// QuotaStore is an interface that represents a Store enforcing a cap on the
+// total number of bytes stored for a single user, summed across all of
+// their sessions.
+type QuotaStore interface {
+	// Store is an embedded interface so that QuotaStore can be used
+	// as a session store.
+	Store
+	// Usage returns the number of bytes currently accounted to user.
+	Usage(user string) (int, error)
+}
+
+// UsageStore keeps the bytes accounted to the sessions of every user. The
+// Redis store implements it, so that a quota is shared by every application
+// instance and survives restarts.
+type UsageStore interface {
+	// Reserve atomically accounts size bytes to the session key of user
+	// until ttl passes, unless that brings the total of the user's sessions
+	// above max. With evict, the least recently reserved other sessions of
+	// the user are dropped instead until the total fits, and their keys
+	// returned. ok is false if key was not accounted.
+	Reserve(user, key string, size int, ttl time.Duration, max int, evict bool) (evicted []string, ok bool, err error)
+	// Release stops accounting the session key.
+	Release(key string) error
+	// Usage returns the total of the sessions accounted to user.
+	Usage(user string) (int, error)
+}
+
+// QuotaOptions configures a QuotaStore.
+type QuotaOptions struct {
+	// Evict makes a save over the quota delete the least recently saved
+	// other sessions of the user until the session fits, instead of
+	// failing with ErrQuotaExceeded. The wrapped store must be a
+	// ManagedStore.
+	Evict bool
+	// Usage keeps the accounting. Defaults to the memory of this process,
+	// so every process enforces the quota on its own.
+	Usage UsageStore
+}
+
+// NewQuotaStore returns a new QuotaStore wrapping store.
+//
+// The owner func maps a session to the user it belongs to, typically by
+// reading an id stored at login. Sessions for which it returns an empty
+// string are not accounted. Saves that would bring the total encoded size of
+// a user's sessions above maxBytes are rejected with ErrQuotaExceeded and
+// never reach the wrapped store, unless options enable eviction. Sessions
+// stop counting once destroyed, once deleted by the cleanup of store if it
+// is an ExpiryNotifier, and otherwise once their MaxAge passed. It panics if
+// eviction is enabled for a store that is not a ManagedStore.
+func NewQuotaStore(store Store, maxBytes int, owner func(*sessions.Session) string, options ...QuotaOptions) QuotaStore {
+	opts := QuotaOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Usage == nil {
+		opts.Usage = newMemoryUsage()
+	}
+
+	q := &quotaStore{Store: store, max: maxBytes, owner: owner, opts: opts}
+	if opts.Evict {
+		ms, ok := StoreAs[ManagedStore](store)
+		if !ok {
+			panic("sessions: evicting quotas need a ManagedStore")
+		}
+		q.managed = ms
+	}
+	if n, ok := StoreAs[ExpiryNotifier](store); ok {
+		n.OnExpire(func(id string) { opts.Usage.Release(id) })
+	}
+	return q
+}
+
+type quotaStore struct {
+	Store
+	max     int
+	owner   func(*sessions.Session) string
+	opts    QuotaOptions
+	managed ManagedStore
+}
+
+func (q *quotaStore) Unwrap() Store {
+	return q.Store
+}
+
+func (q *quotaStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	user := q.owner(s)
+	if user == "" || s.Options != nil && s.Options.MaxAge < 0 {
+		if err := q.Store.Save(r, w, s); err != nil {
+			return err
+		}
+		return q.opts.Usage.Release(quotaKey(s, user))
+	}
+
+	key := quotaKey(s, user)
+	if s.IsNew && s.ID == "" {
+		// concurrent new sessions of a user must not share a key
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		key = pendingQuotaKey + id
+	}
+	size, err := encodedSize(s.Values)
+	if err != nil {
+		return err
+	}
+
+	// reserve the new size before saving, so concurrent saves of one user
+	// can't each pass the check on their own
+	ttl := sessionTTL(s.Options)
+	evicted, ok, err := q.opts.Usage.Reserve(user, key, size, ttl, q.max, q.opts.Evict)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrQuotaExceeded
+	}
+	for _, id := range evicted {
+		if !strings.HasPrefix(id, pendingQuotaKey) {
+			if err := q.managed.Delete(id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := q.Store.Save(r, w, s); err != nil {
+		if strings.HasPrefix(key, pendingQuotaKey) {
+			q.opts.Usage.Release(key)
+		}
+		return err
+	}
+
+	if saved := quotaKey(s, user); saved != key {
+		// new sessions get their ID when saved, and were checked already
+		if err := q.opts.Usage.Release(key); err != nil {
+			return err
+		}
+		_, _, err := q.opts.Usage.Reserve(user, saved, size, ttl, math.MaxInt, false)
+		return err
+	}
+	return nil
+}
+
+func (q *quotaStore) Usage(user string) (int, error) {
+	return q.opts.Usage.Usage(user)
+}
+
+// quotaKey identifies a session of user for accounting. Stores that keep
+// everything in the cookie never assign an ID, so the session name is used
+// instead, and such sessions can only be released while user is known.
+func quotaKey(s *sessions.Session, user string) string {
+	if s.ID == "" {
+		return user + "/" + s.Name()
+	}
+	return s.ID
+}
+
+// encodedSize returns the length of the gob encoding of values, which is
+// what the bundled stores persist.
+func encodedSize(values map[interface{}]interface{}) (int, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return 0, err
+	}
+	return buf.Len(), nil
+}
+
+type usage struct {
+	size     int
+	reserved time.Time
+	expires  time.Time
+}
+
+// memoryUsage is the UsageStore of a single process.
+type memoryUsage struct {
+	mu sync.Mutex
+	// users holds the usage of every accounted session by user and key,
+	// and owners the user each key is accounted to.
+	users  map[string]map[string]*usage
+	owners map[string]string
+	swept  time.Time
+}
+
+func newMemoryUsage() *memoryUsage {
+	return &memoryUsage{users: make(map[string]map[string]*usage), owners: make(map[string]string)}
+}
+
+func (m *memoryUsage) Reserve(user, key string, size int, ttl time.Duration, max int, evict bool) ([]string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.sweep(now)
+
+	total := size
+	var others []string
+	for k, u := range m.users[user] {
+		if k != key && now.Before(u.expires) {
+			total += u.size
+			others = append(others, k)
+		}
+	}
+
+	var evicted []string
+	if total > max {
+		if !evict || size > max {
+			return nil, false, nil
+		}
+		sessions := m.users[user]
+		sort.Slice(others, func(i, j int) bool {
+			return sessions[others[i]].reserved.Before(sessions[others[j]].reserved)
+		})
+		for _, k := range others {
+			if total <= max {
+				break
+			}
+			total -= sessions[k].size
+			m.release(k)
+			evicted = append(evicted, k)
+		}
+	}
+
+	m.release(key)
+	if m.users[user] == nil {
+		m.users[user] = make(map[string]*usage)
+	}
+	m.users[user][key] = &usage{size: size, reserved: now, expires: now.Add(ttl)}
+	m.owners[key] = user
+	return evicted, true, nil
+}
+
+func (m *memoryUsage) Release(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.release(key)
+	return nil
+}
+
+func (m *memoryUsage) Usage(user string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	total := 0
+	for _, u := range m.users[user] {
+		if now.Before(u.expires) {
+			total += u.size
+		}
+	}
+	return total, nil
+}
+
+// release stops accounting key. The caller must hold m.mu.
+func (m *memoryUsage) release(key string) {
+	user, ok := m.owners[key]
+	if !ok {
+		return
+	}
+	delete(m.owners, key)
+	delete(m.users[user], key)
+	if len(m.users[user]) == 0 {
+		delete(m.users, user)
+	}
+}
+
+// sweep drops the expired sessions, at most once per usageSweep. The caller
+// must hold m.mu.
+func (m *memoryUsage) sweep(now time.Time) {
+	if now.Sub(m.swept) < usageSweep {
+		return
+	}
+	m.swept = now
+	for key, user := range m.owners {
+		if !now.Before(m.users[user][key].expires) {
+			m.release(key)
+		}
+	}
+}
+
+// redisQuotaSuffix is appended to the keys of the hashes holding the usage
+// of a user in the Redis store, and redisOwnerSuffix to those holding the
+// user a session is accounted to, telling them apart from sessions.
+const (
+	redisQuotaSuffix = "#quota"
+	redisOwnerSuffix = "#owner"
+)
+
+// redisReserve accounts a session in the usage hash of a user, whose fields
+// map session keys to "size:reserved:expires" in milliseconds. ARGV holds
+// the session key, its size, the quota, whether to evict, the current time,
+// the ttl in milliseconds and the user. It returns the evicted keys, or nil
+// when the session doesn't fit.
+var redisReserve = redis.NewScript(2, `
+local size = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local now = tonumber(ARGV[5])
+local total = size
+local others = {}
+local entries = redis.call("HGETALL", KEYS[1])
+for i = 1, #entries, 2 do
+	local s, reserved, expires = string.match(entries[i+1], "(%d+):(%d+):(%d+)")
+	if tonumber(expires) <= now then
+		redis.call("HDEL", KEYS[1], entries[i])
+	elseif entries[i] ~= ARGV[1] then
+		total = total + tonumber(s)
+		table.insert(others, {entries[i], tonumber(s), tonumber(reserved)})
+	end
+end
+local evicted = {}
+if total > max then
+	if ARGV[4] ~= "1" or size > max then
+		return false
+	end
+	table.sort(others, function(a, b) return a[3] < b[3] end)
+	for _, o in ipairs(others) do
+		if total <= max then
+			break
+		end
+		redis.call("HDEL", KEYS[1], o[1])
+		total = total - o[2]
+		table.insert(evicted, o[1])
+	end
+end
+local ttl = tonumber(ARGV[6])
+redis.call("HSET", KEYS[1], ARGV[1], size .. ":" .. now .. ":" .. (now + ttl))
+if redis.call("PTTL", KEYS[1]) < ttl then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+redis.call("SET", KEYS[2], ARGV[7], "PX", ttl)
+return evicted
+`)
+
+func (c *rediStore) Reserve(user, key string, size int, ttl time.Duration, max int, evict bool) ([]string, bool, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	flag := 0
+	if evict {
+		flag = 1
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	evicted, err := redis.Strings(redisReserve.Do(conn,
+		c.prefix+user+redisQuotaSuffix, c.prefix+key+redisOwnerSuffix,
+		key, size, max, flag, now, ttl.Milliseconds(), user))
+	if err == redis.ErrNil {
+		return nil, false, nil
+	}
+	return evicted, err == nil, err
+}
+
+func (c *rediStore) Release(key string) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	user, err := redis.String(conn.Do("GET", c.prefix+key+redisOwnerSuffix))
+	if err == redis.ErrNil {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, err := conn.Do("HDEL", c.prefix+user+redisQuotaSuffix, key); err != nil {
+		return err
+	}
+	_, err = conn.Do("DEL", c.prefix+key+redisOwnerSuffix)
+	return err
+}
+
+func (c *rediStore) Usage(user string) (int, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	entries, err := redis.StringMap(conn.Do("HGETALL", c.prefix+user+redisQuotaSuffix))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	total := 0
+	for _, v := range entries {
+		parts := strings.Split(v, ":")
+		if len(parts) != 3 {
+			return 0, fmt.Errorf("sessions: malformed quota entry %q", v)
+		}
+		size, _ := strconv.Atoi(parts[0])
+		expires, _ := strconv.ParseInt(parts[2], 10, 64)
+		if expires > now {
+			total += size
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,162 @@
+package sessions
+
+import (
+	"container/list"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+func Test_QuotaStore(t *testing.T) {
+	store := NewQuotaStore(NewCookieStore([]byte("secret123")), 64, func(s *sessions.Session) string {
+		user, _ := s.Values["user"].(string)
+		return user
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.Get(req, "my_session")
+	s.Values["user"] = "bob"
+	if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+		t.Fatal("Saving a small session failed:", err)
+	}
+	if n, _ := store.Usage("bob"); n == 0 {
+		t.Error("Usage was not accounted after save")
+	}
+
+	s.Values["blob"] = strings.Repeat("x", 128)
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != ErrQuotaExceeded {
+		t.Error("Expected ErrQuotaExceeded, got", err)
+	}
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Error("Rejected session was written to the response")
+	}
+}
+
+func Test_QuotaStoreReleased(t *testing.T) {
+	backend := agedBackend{&memoryBackend{lru: list.New(), entries: make(map[string]*list.Element)}}
+	server := NewServerStore(backend)
+	store := NewQuotaStore(server, 1024, func(s *sessions.Session) string {
+		user, _ := s.Values["user"].(string)
+		return user
+	})
+
+	save := func(user string) *sessions.Session {
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["user"] = user
+		if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := save("bob")
+	save("alice")
+	bob, _ := store.Usage("bob")
+	alice, _ := store.Usage("alice")
+	if bob == 0 || alice == 0 {
+		t.Fatal("Usage was not accounted after save")
+	}
+
+	// destroying wipes the values, the owner is no longer known
+	s.Values = make(map[interface{}]interface{})
+	s.Options.MaxAge = -1
+	req, _ := http.NewRequest("GET", "/", nil)
+	if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.Usage("bob"); n != 0 {
+		t.Error("Destroyed session is still accounted:", n)
+	}
+
+	if err := server.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.Usage("alice"); n != 0 {
+		t.Error("Expired session is still accounted:", n)
+	}
+}
+
+func Test_QuotaStoreConcurrent(t *testing.T) {
+	store := NewQuotaStore(NewMemoryStore(0), 200, func(s *sessions.Session) string {
+		return "bob"
+	})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	saved := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "/", nil)
+			s, _ := store.New(req, "my_session")
+			s.Values["blob"] = strings.Repeat("x", 60)
+			if store.Save(req, httptest.NewRecorder(), s) == nil {
+				mu.Lock()
+				saved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := store.Usage("bob"); n > 200 || saved == 0 || saved == 10 {
+		t.Error("Quota was not enforced across concurrent saves:", n, saved)
+	}
+}
+
+func Test_QuotaStoreExpiry(t *testing.T) {
+	store := NewQuotaStore(NewCookieStore([]byte("secret123")), 1024, func(s *sessions.Session) string {
+		return "bob"
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Options.MaxAge = 1
+	if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.Usage("bob"); n == 0 {
+		t.Fatal("Usage was not accounted after save")
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if n, _ := store.Usage("bob"); n != 0 {
+		t.Error("Session is still accounted after its MaxAge:", n)
+	}
+}
+
+func Test_QuotaStoreEvict(t *testing.T) {
+	store := NewQuotaStore(NewMemoryStore(0), 200, func(s *sessions.Session) string {
+		return "bob"
+	}, QuotaOptions{Evict: true})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["blob"] = strings.Repeat("x", 60)
+		if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+			t.Fatal("Save over the quota was not evicting:", err)
+		}
+		ids = append(ids, s.ID)
+	}
+
+	ms, _ := StoreAs[ManagedStore](store)
+	if ok, _ := ms.Exists(ids[0]); ok {
+		t.Error("Oldest session was not evicted")
+	}
+	if ok, _ := ms.Exists(ids[2]); !ok {
+		t.Error("Newest session was evicted")
+	}
+	if n, _ := store.Usage("bob"); n > 200 {
+		t.Error("Quota was exceeded:", n)
+	}
+}
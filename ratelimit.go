@@ -0,0 +1,186 @@
+package sessions
+
+import (
+	"container/list"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-martini/martini"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Limit describes a token bucket: Burst requests may be made at once and the
+// bucket refills at Rate requests per Per.
+type Limit struct {
+	// Name namespaces the buckets of this limit. Defaults to the request
+	// method and route pattern when RateLimit is a route handler, giving
+	// every route its own buckets, and to a single namespace for all
+	// requests when it is used as a Middleware.
+	Name string
+	Rate int
+	Per  time.Duration
+	// Burst defaults to 1.
+	Burst int
+}
+
+// BucketStore keeps token buckets atomically. The Redis store implements
+// it, so that a limit is shared by every application instance.
+type BucketStore interface {
+	// Take removes a token from the bucket identified by key and reports
+	// whether one was available.
+	Take(key string, limit Limit) (bool, error)
+}
+
+// RateLimit is a Middleware that limits requests per session, using the ID of
+// the named session as the bucket key and falling back to the client IP for
+// anonymous requests or stores that don't assign IDs. Buckets are kept in
+// memory, so every process enforces the limit on its own, unless buckets is
+// given, such as a Redis store. Requests over the limit are answered with 429
+// Too Many Requests. It panics unless limit.Rate and limit.Per are positive
+// and limit.Burst isn't negative.
+//
+//	m.Post("/comments", sessions.RateLimit("my_session", sessions.Limit{Rate: 10, Per: time.Minute, Burst: 5}, store), postComment)
+func RateLimit(name string, limit Limit, buckets ...BucketStore) martini.Handler {
+	if limit.Rate <= 0 || limit.Per <= 0 || limit.Burst < 0 {
+		panic("sessions: RateLimit needs a positive Rate and Per and a Burst of at least 1")
+	}
+	if limit.Burst == 0 {
+		limit.Burst = 1
+	}
+	var store BucketStore = newMemoryBuckets(limit)
+	if len(buckets) > 0 {
+		store = buckets[0]
+	}
+
+	return func(res http.ResponseWriter, r *http.Request, c martini.Context, s Session, l *log.Logger) {
+		ns := limit.Name
+		if ns == "" {
+			// the pattern, unlike the path, can't be chosen by clients to
+			// create buckets at will
+			if route := c.Get(routeType); route.IsValid() {
+				ns = r.Method + " " + route.Interface().(martini.Route).Pattern()
+			}
+		}
+
+		key := ns + ":ip:" + clientIP(r)
+		if id := s.ID(name); id != "" {
+			key = ns + ":sid:" + id
+		}
+
+		ok, err := store.Take(key, limit)
+		if err != nil {
+			// fail open, a broken counter backend must not take the site down
+			check(err, l)
+			return
+		}
+		if !ok {
+			http.Error(res, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+}
+
+var routeType = reflect.TypeOf((*martini.Route)(nil)).Elem()
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// memoryBuckets keeps the buckets of a single limit in the order they were
+// last taken from, which is also the order they fill up again in, so full
+// buckets are dropped from the front without looking at the others.
+type memoryBuckets struct {
+	// full is how long an empty bucket takes to refill.
+	full time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	buckets map[string]*list.Element
+}
+
+func newMemoryBuckets(limit Limit) *memoryBuckets {
+	return &memoryBuckets{full: refillTime(limit), order: list.New(), buckets: make(map[string]*list.Element)}
+}
+
+func (m *memoryBuckets) Take(key string, limit Limit) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for el := m.order.Front(); el != nil && now.Sub(el.Value.(*bucket).last) >= m.full; el = m.order.Front() {
+		m.order.Remove(el)
+		delete(m.buckets, el.Value.(*bucket).key)
+	}
+
+	var b *bucket
+	if el, ok := m.buckets[key]; ok {
+		b = el.Value.(*bucket)
+		b.tokens += now.Sub(b.last).Seconds() * float64(limit.Rate) / limit.Per.Seconds()
+		if b.tokens > float64(limit.Burst) {
+			b.tokens = float64(limit.Burst)
+		}
+		m.order.MoveToBack(el)
+	} else {
+		b = &bucket{key: key, tokens: float64(limit.Burst)}
+		m.buckets[key] = m.order.PushBack(b)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, nil
+	}
+	b.tokens--
+	return true, nil
+}
+
+// redisBucketSuffix is appended to the keys of the token buckets kept by the
+// Redis store, telling them apart from sessions.
+const redisBucketSuffix = "#bucket"
+
+// redisTake refills and takes from a token bucket kept as a hash. ARGV holds
+// the burst, the refill rate per millisecond, the current time in
+// milliseconds and how long an idle bucket is kept.
+var redisTake = redis.NewScript(1, `
+local b = redis.call("HMGET", KEYS[1], "tokens", "last")
+local burst = tonumber(ARGV[1])
+local now = tonumber(ARGV[3])
+local tokens = tonumber(b[1]) or burst
+local last = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * tonumber(ARGV[2]))
+local ok = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	ok = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", ARGV[3])
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return ok
+`)
+
+func (c *rediStore) Take(key string, limit Limit) (bool, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	perMs := float64(limit.Rate) / (float64(limit.Per) / float64(time.Millisecond))
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	keep := refillTime(limit).Milliseconds() + 1
+	return redis.Bool(redisTake.Do(conn, c.prefix+key+redisBucketSuffix, limit.Burst, perMs, now, keep))
+}
+
+// refillTime returns how long an empty bucket of limit takes to fill up.
+func refillTime(limit Limit) time.Duration {
+	return limit.Per * time.Duration(limit.Burst) / time.Duration(limit.Rate)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
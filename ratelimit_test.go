@@ -0,0 +1,88 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_RateLimit(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+	m.Get("/limited", RateLimit("my_session", Limit{Rate: 1, Per: time.Hour, Burst: 2}), func() string {
+		return "OK"
+	})
+
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/limited", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		m.ServeHTTP(res, req)
+		codes = append(codes, res.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Error("Unexpected status codes:", codes)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/limited", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Error("Limit leaked across clients:", res.Code)
+	}
+}
+
+func Test_RateLimitRoutePattern(t *testing.T) {
+	m := martini.Classic()
+
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Get("/items/:id", RateLimit("my_session", Limit{Rate: 1, Per: time.Hour}), func() string {
+		return "OK"
+	})
+
+	codes := []int{}
+	for _, path := range []string{"/items/1", "/items/2"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		m.ServeHTTP(res, req)
+		codes = append(codes, res.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Error("Unexpected status codes:", codes)
+	}
+}
+
+func Test_MemoryBuckets(t *testing.T) {
+	limit := Limit{Rate: 1, Per: 10 * time.Millisecond, Burst: 2}
+	m := newMemoryBuckets(limit)
+
+	for _, key := range []string{"a", "b", "b"} {
+		m.Take(key, limit)
+	}
+	if ok, _ := m.Take("b", limit); ok {
+		t.Error("Empty bucket gave a token")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	m.Take("c", limit)
+	if len(m.buckets) != 1 {
+		t.Error("Full buckets were not dropped:", len(m.buckets))
+	}
+}
+
+func Test_RateLimitInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("A limit without Per was accepted")
+		}
+	}()
+	RateLimit("my_session", Limit{Rate: 1, Burst: 1})
+}
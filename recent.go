@@ -0,0 +1,27 @@
+package sessions
+
+import (
+	"reflect"
+)
+
+// PushRecent adds item to the front of the list stored at key in the named
+// session, moving it there if it is already present, and drops the oldest
+// entries so that at most max remain.
+func PushRecent(s Session, name string, key interface{}, item interface{}, max int) {
+	items := []interface{}{item}
+	for _, old := range Recent(s, name, key) {
+		if len(items) >= max {
+			break
+		}
+		if !reflect.DeepEqual(old, item) {
+			items = append(items, old)
+		}
+	}
+	s.Set(name, key, items)
+}
+
+// Recent returns the list stored at key by PushRecent, newest first.
+func Recent(s Session, name string, key interface{}) []interface{} {
+	items, _ := s.Get(name, key).([]interface{})
+	return items
+}
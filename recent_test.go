@@ -0,0 +1,31 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_PushRecent(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		for _, sku := range []string{"a", "b", "c", "b", "d"} {
+			PushRecent(session, "my_session", "viewed", sku, 3)
+		}
+		r := Recent(session, "my_session", "viewed")
+		if len(r) != 3 || r[0] != "d" || r[1] != "b" || r[2] != "c" {
+			t.Error("Unexpected recent items:", r)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
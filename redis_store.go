@@ -1,7 +1,16 @@
 package sessions
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/boj/redistore"
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 )
 
@@ -11,6 +20,9 @@ type RediStore interface {
 	// Store is an embedded interface so that RedisStore can be used
 	// as a session store.
 	Store
+	// BackupStore is an embedded interface so that the sessions held in
+	// Redis can be backed up and restored.
+	BackupStore
 	// Options sets the default options for each session stored in this
 	// CookieStore.
 	Options(Options)
@@ -32,11 +44,30 @@ func NewRediStore(size int, network, address, password string, keyPairs ...[]byt
 	if err != nil {
 		return nil, err
 	}
-	return &rediStore{store}, nil
+	return &rediStore{store, "session_"}, nil
+}
+
+// NewRedisStore returns a new RediStore connecting to the Redis server at
+// address, selecting database db and storing sessions under keys starting
+// with prefix. An empty prefix keeps the default "session_".
+//
+// Keys are defined in pairs as for NewCookieStore. Sessions expire in Redis
+// after Options.MaxAge seconds, or after 20 minutes if MaxAge is 0.
+func NewRedisStore(address, password string, db int, prefix string, keyPairs ...[]byte) (RediStore, error) {
+	store, err := redistore.NewRediStoreWithDB(10, "tcp", address, password, strconv.Itoa(db), keyPairs...)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = "session_"
+	}
+	store.SetKeyPrefix(prefix)
+	return &rediStore{store, prefix}, nil
 }
 
 type rediStore struct {
 	*redistore.RediStore
+	prefix string
 }
 
 func (c *rediStore) Options(options Options) {
@@ -47,4 +78,159 @@ func (c *rediStore) Options(options Options) {
 		Secure:   options.Secure,
 		HttpOnly: options.HttpOnly,
 	}
+	// keep the cookie codecs in line with the Redis TTL
+	c.RediStore.SetMaxAge(options.MaxAge)
+}
+
+func (c *rediStore) cookieID(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	var id string
+	if securecookie.DecodeMulti(name, cookie.Value, &id, c.Codecs...) != nil {
+		return ""
+	}
+	return id
+}
+
+func (c *rediStore) Backup(w io.Writer, key ...[]byte) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	bw := &backupWriter{w: w, key: backupKey(key)}
+	cursor := 0
+	for {
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", c.prefix+"*", "COUNT", 100))
+		if err != nil {
+			return err
+		}
+		cursor, _ = redis.Int(reply[0], nil)
+		keys, _ := redis.Strings(reply[1], nil)
+
+		for _, k := range keys {
+			if isRedisCounterKey(k) || strings.HasSuffix(k, redisBucketSuffix) ||
+				strings.HasSuffix(k, redisQuotaSuffix) || strings.HasSuffix(k, redisOwnerSuffix) ||
+				strings.HasSuffix(k, redisResourcesSuffix) {
+				continue
+			}
+			data, err := redis.Bytes(conn.Do("GET", k))
+			if err == redis.ErrNil {
+				// expired between SCAN and GET
+				continue
+			} else if err != nil {
+				return err
+			}
+			ttl, err := redis.Int64(conn.Do("PTTL", k))
+			if err != nil {
+				return err
+			}
+
+			rec := backupRecord{ID: k[len(c.prefix):], Data: data}
+			if ttl > 0 {
+				rec.Expires = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+			}
+			if err := bw.write(rec); err != nil {
+				return err
+			}
+		}
+
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// flush deletes all sessions with IDs starting with prefix.
+func (c *rediStore) flush(prefix string) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	cursor := 0
+	for {
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", c.prefix+prefix+"*", "COUNT", 100))
+		if err != nil {
+			return err
+		}
+		cursor, _ = redis.Int(reply[0], nil)
+		keys, _ := redis.Values(reply[1], nil)
+
+		if len(keys) > 0 {
+			if _, err := conn.Do("DEL", keys...); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+func (c *rediStore) Restore(r io.Reader, key ...[]byte) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	br := &backupReader{r: r, key: backupKey(key)}
+	for {
+		rec, err := br.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if rec.Expires.IsZero() {
+			_, err = conn.Do("SET", c.prefix+rec.ID, rec.Data)
+		} else if ttl := time.Until(rec.Expires); ttl > 0 {
+			_, err = conn.Do("SET", c.prefix+rec.ID, rec.Data, "PX", int64(ttl/time.Millisecond))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *rediStore) Ping(ctx context.Context) error {
+	conn, err := c.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = redis.DoContext(conn, ctx, "PING")
+	return err
+}
+
+// redisCounterSuffix is appended to the key of a session to get the key of
+// the hash holding its counters.
+const redisCounterSuffix = "#counters"
+
+// redisIncrement bumps a counter of a session that still exists, and lets
+// the counters expire together with the session.
+var redisIncrement = redis.NewScript(2, `
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl == -2 then
+	return false
+end
+local n = redis.call("HINCRBY", KEYS[2], ARGV[1], ARGV[2])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+end
+return n
+`)
+
+func (c *rediStore) Increment(id, key string, delta int64) (int64, bool, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	n, err := redis.Int64(redisIncrement.Do(conn, c.prefix+id, c.prefix+id+redisCounterSuffix, key, delta))
+	if err == redis.ErrNil {
+		return 0, false, nil
+	}
+	return n, err == nil, err
+}
+
+// isRedisCounterKey reports whether k holds the counters of a session
+// rather than the session itself.
+func isRedisCounterKey(k string) bool {
+	return strings.HasSuffix(k, redisCounterSuffix)
 }
@@ -0,0 +1,308 @@
+package sessions
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+const homeRegionKey = "_home_region"
+
+// replicationQueue is the number of saves waiting to be replicated to a
+// region before further ones are dropped.
+const replicationQueue = 1000
+
+// rehomeTTL is how long a Rehome waits for the next save of its session.
+const rehomeTTL = 24 * time.Hour
+
+// RegionalStore is an interface that represents a Store spanning several
+// regions. Every session is pinned to a home region, where it is written
+// synchronously, and replicated asynchronously to the other regions so it
+// can be read locally anywhere.
+type RegionalStore interface {
+	// Store is an embedded interface so that RegionalStore can be used
+	// as a session store.
+	Store
+	// Rehome moves the home of the session with the given ID to region,
+	// e.g. when its user moved closer to another region. It takes effect on
+	// the next save of the session by this application instance.
+	Rehome(id, region string) error
+	// Home returns the home region of the named session.
+	Home(s Session, name string) string
+	// Close replicates the queued saves and stops replicating.
+	Close()
+}
+
+// NewRegionalStore returns a RegionalStore for the application instance
+// running in region local. stores holds one store per region, including the
+// local one. They must assign session IDs, like the Redis store, and share
+// the same keys. Saves are replicated in order by one goroutine per region,
+// and dropped when a region falls too far behind. Failures are logged to l,
+// if not nil.
+func NewRegionalStore(local string, stores map[string]Store, l *log.Logger) RegionalStore {
+	rs := &regionalStore{
+		local:   local,
+		stores:  stores,
+		logger:  l,
+		queues:  make(map[string]chan replication),
+		rehomes: make(map[string]rehome),
+	}
+	for region, store := range stores {
+		queue := make(chan replication, replicationQueue)
+		rs.queues[region] = queue
+		rs.wg.Add(1)
+		go rs.replicate(region, store, queue)
+	}
+	return rs
+}
+
+type regionalStore struct {
+	local  string
+	stores map[string]Store
+	logger *log.Logger
+
+	mu      sync.RWMutex
+	closed  bool
+	queues  map[string]chan replication
+	wg      sync.WaitGroup
+	rehomes map[string]rehome
+	swept   time.Time
+}
+
+// replication is a save to copy to another region. The values are gob
+// encoded, as the request goes on changing the session. After a rehome, it
+// is the save of session to its new home instead, whose result is sent to
+// done.
+type replication struct {
+	r      *http.Request
+	name   string
+	id     string
+	values []byte
+	opts   *sessions.Options
+
+	session *sessions.Session
+	w       http.ResponseWriter
+	done    chan error
+}
+
+type rehome struct {
+	region  string
+	expires time.Time
+}
+
+// all returns the stores of all regions, the local one first.
+func (rs *regionalStore) all() []Store {
+	stores := []Store{rs.stores[rs.local]}
+	for region, store := range rs.stores {
+		if region != rs.local {
+			stores = append(stores, store)
+		}
+	}
+	return stores
+}
+
+func (rs *regionalStore) Delete(id string) error {
+	return deleteAll(id, rs.all()...)
+}
+
+func (rs *regionalStore) Exists(id string) (bool, error) {
+	return existsAny(id, rs.all()...)
+}
+
+func (rs *regionalStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, rs.all()...)
+}
+
+func (rs *regionalStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := rs.stores[rs.local].Get(r, name)
+	if err == nil && s != nil && !s.IsNew {
+		return s, nil
+	}
+
+	// the local replica may lag behind the home region
+	for region, store := range rs.stores {
+		if region == rs.local {
+			continue
+		}
+		if remote, rerr := store.New(r, name); rerr == nil && !remote.IsNew {
+			return remote, nil
+		}
+	}
+	return s, err
+}
+
+func (rs *regionalStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	return rs.stores[rs.local].New(r, name)
+}
+
+func (rs *regionalStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	prev, _ := s.Values[homeRegionKey].(string)
+	home := prev
+	if region, ok := rs.takeRehome(s.ID); ok {
+		home = region
+	}
+	if _, ok := rs.stores[home]; !ok {
+		home = rs.local
+	}
+	s.Values[homeRegionKey] = home
+	if err := rs.saveHome(r, w, s, home, prev != "" && prev != home); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.Values); err != nil {
+		return err
+	}
+	job := replication{
+		// the request is done by the time the copy is saved
+		r:      r.Clone(context.Background()),
+		name:   s.Name(),
+		id:     s.ID,
+		values: buf.Bytes(),
+	}
+	if s.Options != nil {
+		opts := *s.Options
+		job.opts = &opts
+	}
+
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if rs.closed {
+		return nil
+	}
+	for region, queue := range rs.queues {
+		if region == home {
+			continue
+		}
+		select {
+		case queue <- job:
+		default:
+			rs.logf("replication queue of region %s full, dropping session %s", region, s.ID)
+		}
+	}
+	return nil
+}
+
+// saveHome saves s to its home region. After a rehome, the save is queued
+// behind the copies still pending for the new home, so they can't
+// overwrite it.
+func (rs *regionalStore) saveHome(r *http.Request, w http.ResponseWriter, s *sessions.Session, home string, moved bool) error {
+	if moved {
+		rs.mu.RLock()
+		if !rs.closed {
+			done := make(chan error, 1)
+			rs.queues[home] <- replication{r: r, session: s, w: w, done: done}
+			rs.mu.RUnlock()
+			return <-done
+		}
+		rs.mu.RUnlock()
+	}
+	return rs.stores[home].Save(r, w, s)
+}
+
+// replicate saves the copies queued for region in order.
+func (rs *regionalStore) replicate(region string, store Store, queue <-chan replication) {
+	defer rs.wg.Done()
+
+	for job := range queue {
+		if job.done != nil {
+			job.done <- store.Save(job.r, job.w, job.session)
+			continue
+		}
+		replica := sessions.NewSession(store, job.name)
+		replica.ID = job.id
+		replica.Options = job.opts
+		if err := gob.NewDecoder(bytes.NewReader(job.values)).Decode(&replica.Values); err != nil {
+			rs.logf("decoding session %s for region %s failed: %s", job.id, region, err)
+			continue
+		}
+		if err := store.Save(job.r, discardWriter{}, replica); err != nil {
+			rs.logf("replicating session %s to region %s failed: %s", job.id, region, err)
+		}
+	}
+}
+
+func (rs *regionalStore) Close() {
+	rs.mu.Lock()
+	if !rs.closed {
+		rs.closed = true
+		for _, queue := range rs.queues {
+			close(queue)
+		}
+	}
+	rs.mu.Unlock()
+	rs.wg.Wait()
+}
+
+func (rs *regionalStore) Rehome(id, region string) error {
+	if _, ok := rs.stores[region]; !ok {
+		return fmt.Errorf("sessions: unknown region %q", region)
+	}
+
+	now := time.Now()
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if now.Sub(rs.swept) >= rehomeTTL {
+		rs.swept = now
+		for id, rh := range rs.rehomes {
+			if now.After(rh.expires) {
+				delete(rs.rehomes, id)
+			}
+		}
+	}
+	rs.rehomes[id] = rehome{region: region, expires: now.Add(rehomeTTL)}
+	return nil
+}
+
+// takeRehome returns and forgets the region the session id was rehomed to.
+func (rs *regionalStore) takeRehome(id string) (string, bool) {
+	if id == "" {
+		return "", false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rh, ok := rs.rehomes[id]
+	delete(rs.rehomes, id)
+	return rh.region, ok && time.Now().Before(rh.expires)
+}
+
+func (rs *regionalStore) Home(s Session, name string) string {
+	if id := s.ID(name); id != "" {
+		rs.mu.RLock()
+		rh, ok := rs.rehomes[id]
+		rs.mu.RUnlock()
+		if ok && time.Now().Before(rh.expires) {
+			return rh.region
+		}
+	}
+	if home, ok := s.Get(name, homeRegionKey).(string); ok {
+		return home
+	}
+	return rs.local
+}
+
+func (rs *regionalStore) logf(format string, args ...interface{}) {
+	if rs.logger != nil {
+		rs.logger.Printf(errorFormat, fmt.Sprintf(format, args...))
+	}
+}
+
+// discardWriter swallows the cookies replicas would set.
+type discardWriter struct{}
+
+func (discardWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardWriter) WriteHeader(int) {}
@@ -0,0 +1,46 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RegionalStore(t *testing.T) {
+	eu, us := NewMemoryStore(0), NewMemoryStore(0)
+	store := NewRegionalStore("eu", map[string]Store{"eu": eu, "us": us}, nil)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	for i := 1; i <= 100; i++ {
+		s.Values["n"] = i
+		if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Rehome(s.ID, "mars"); err == nil {
+		t.Error("Unknown region was accepted")
+	}
+	if err := store.Rehome(s.ID, "us"); err != nil {
+		t.Fatal(err)
+	}
+	s.Values["n"] = 101
+	if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	load := func() *http.Request {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: "my_session", Value: s.ID})
+		return req
+	}
+	replica, _ := us.New(load(), "my_session")
+	if replica.Values["n"] != 101 || replica.Values[homeRegionKey] != "us" {
+		t.Error("Unexpected session in the new home region:", replica.Values)
+	}
+	replica, _ = eu.New(load(), "my_session")
+	if replica.Values["n"] != 101 {
+		t.Error("Saves were not replicated in order:", replica.Values)
+	}
+}
@@ -0,0 +1,400 @@
+package sessions
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/sessions"
+)
+
+const resourcesKey = "_resources"
+
+// Resources tracks temporary resources, such as upload files or blob keys,
+// created on behalf of a session so they can be released when the session
+// ends instead of accumulating.
+//
+//	uploads := &sessions.Resources{Release: os.Remove}
+//	m.Use(sessions.Sessions(sessions.NewResourceStore(store, uploads)))
+//
+//	m.Post("/upload", func(s sessions.Session) {
+//		f, _ := os.CreateTemp("", "upload")
+//		uploads.Track(s, "my_session", f.Name())
+//	})
+//
+//	m.Get("/logout", func(s sessions.Session) {
+//		s.Destroy("my_session")
+//	})
+type Resources struct {
+	// Release frees a single resource.
+	Release func(resource string) error
+	// Registry records which session holds which resources. Defaults to the
+	// memory of this process, so resources of sessions that end after a
+	// restart are never released. The Redis store implements a registry
+	// that survives restarts.
+	Registry ResourceRegistry
+
+	once sync.Once
+}
+
+// ResourceRegistry records the resources held by every session ID, so they
+// are found once the session is gone. A resource is held by a single
+// session at a time.
+type ResourceRegistry interface {
+	// RecordResources sets the resources held by the session id, replacing those it
+	// held before and taking them from any other session holding them,
+	// e.g. before RegenerateID.
+	RecordResources(id string, resources []string) error
+	// TakeResources forgets the resources held by the session id and returns them.
+	TakeResources(id string) ([]string, error)
+	// ResourceHolders returns the IDs of all sessions holding resources.
+	ResourceHolders() ([]string, error)
+}
+
+// NewResourceStore returns a Store releasing the resources tracked by r for
+// a session when it is destroyed or deleted through ManagedStore.Delete,
+// and when store reports it expired or evicted if it is an ExpiryNotifier.
+// Sessions the store drops without telling, such as those expired by Redis,
+// are released by Sweep.
+//
+// Resources are found by session ID. Sessions of stores that assign no ID,
+// such as the CookieStore, and release errors are only handled by
+// ReleaseAll.
+func NewResourceStore(store Store, r *Resources) Store {
+	if n, ok := StoreAs[ExpiryNotifier](store); ok {
+		n.OnExpire(func(id string) { r.releaseID(id) })
+	}
+	return &resourceStore{Store: store, resources: r}
+}
+
+type resourceStore struct {
+	Store
+	resources *Resources
+}
+
+func (rs *resourceStore) Unwrap() Store {
+	return rs.Store
+}
+
+func (rs *resourceStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(rs, name)
+}
+
+func (rs *resourceStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := rs.Store.New(r, name)
+	if err == nil && !s.IsNew {
+		// a failure is made up for by the next save
+		rs.resources.record(s.ID, s.Values)
+	}
+	return s, err
+}
+
+func (rs *resourceStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	id := s.ID
+	if err := rs.Store.Save(r, w, s); err != nil {
+		return err
+	}
+
+	if s.Options != nil && s.Options.MaxAge < 0 {
+		return rs.resources.releaseID(id)
+	}
+	return rs.resources.record(s.ID, s.Values)
+}
+
+func (rs *resourceStore) Delete(id string) error {
+	if err := deleteAll(id, rs.Store); err != nil {
+		return err
+	}
+	return rs.resources.releaseID(id)
+}
+
+func (rs *resourceStore) Exists(id string) (bool, error) {
+	return existsAny(id, rs.Store)
+}
+
+func (rs *resourceStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, rs.Store)
+}
+
+func (r *Resources) registry() ResourceRegistry {
+	r.once.Do(func() {
+		if r.Registry == nil {
+			r.Registry = &memoryResources{}
+		}
+	})
+	return r.Registry
+}
+
+// record registers the resources tracked in values as held by the session
+// id.
+func (r *Resources) record(id string, values map[interface{}]interface{}) error {
+	if id == "" {
+		return nil
+	}
+	tracked, _ := values[resourcesKey].([]string)
+	return r.registry().RecordResources(id, tracked)
+}
+
+// releaseID releases the resources held by the session id.
+func (r *Resources) releaseID(id string) error {
+	held, err := r.registry().TakeResources(id)
+	for _, res := range held {
+		r.Release(res)
+	}
+	return err
+}
+
+// Sweep releases the resources of sessions that no longer exist in store,
+// which must be a ManagedStore, e.g. because the store expired or evicted
+// them without telling, or they ended while the application was down.
+func (r *Resources) Sweep(store Store) error {
+	ms, ok := StoreAs[ManagedStore](store)
+	if !ok {
+		return errors.New("sessions: sweeping resources needs a ManagedStore")
+	}
+	ids, err := r.registry().ResourceHolders()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		found, err := ms.Exists(id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if err := r.releaseID(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StartSweep runs Sweep on store every interval until stop is called,
+// logging failures to l unless it is nil.
+func (r *Resources) StartSweep(store Store, interval time.Duration, l *log.Logger) (stop func()) {
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Sweep(store); err != nil && l != nil {
+					l.Printf(errorFormat, err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(quit) }) }
+}
+
+// memoryResources is the ResourceRegistry of a single process.
+type memoryResources struct {
+	mu sync.Mutex
+	// byID holds the resources of each session ID, and owner the ID
+	// holding each resource.
+	byID  map[string][]string
+	owner map[string]string
+}
+
+func (m *memoryResources) RecordResources(id string, tracked []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.byID == nil {
+		m.byID = make(map[string][]string)
+		m.owner = make(map[string]string)
+	}
+
+	for _, res := range m.byID[id] {
+		if m.owner[res] == id {
+			delete(m.owner, res)
+		}
+	}
+	delete(m.byID, id)
+	for _, res := range tracked {
+		if prev, ok := m.owner[res]; ok && prev != id {
+			m.forget(prev, res)
+		}
+		m.owner[res] = id
+	}
+	if len(tracked) > 0 {
+		m.byID[id] = append([]string(nil), tracked...)
+	}
+	return nil
+}
+
+// forget drops res from the resources held by id. The caller must hold m.mu.
+func (m *memoryResources) forget(id, res string) {
+	kept := m.byID[id][:0]
+	for _, other := range m.byID[id] {
+		if other != res {
+			kept = append(kept, other)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.byID, id)
+	} else {
+		m.byID[id] = kept
+	}
+}
+
+func (m *memoryResources) TakeResources(id string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held := m.byID[id]
+	delete(m.byID, id)
+	for _, res := range held {
+		delete(m.owner, res)
+	}
+	return held, nil
+}
+
+func (m *memoryResources) ResourceHolders() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.byID))
+	for id := range m.byID {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// redisResourcesSuffix is appended to the keys of the sets holding the
+// resources of a session in the Redis store, telling them apart from
+// sessions. The hash mapping every resource to the session holding it is
+// the suffix itself under the prefix.
+const redisResourcesSuffix = "#resources"
+
+// redisRecordResources sets the resources of the session ARGV[3] in the set
+// KEYS[2], updating the owner hash KEYS[1]. ARGV[1] and ARGV[2] are the key
+// prefix and suffix of the sets of other sessions, ARGV[4:] the resources.
+var redisRecordResources = redis.NewScript(2, `
+for _, res in ipairs(redis.call("SMEMBERS", KEYS[2])) do
+	if redis.call("HGET", KEYS[1], res) == ARGV[3] then
+		redis.call("HDEL", KEYS[1], res)
+	end
+end
+redis.call("DEL", KEYS[2])
+for i = 4, #ARGV do
+	local prev = redis.call("HGET", KEYS[1], ARGV[i])
+	if prev and prev ~= ARGV[3] then
+		redis.call("SREM", ARGV[1] .. prev .. ARGV[2], ARGV[i])
+	end
+	redis.call("HSET", KEYS[1], ARGV[i], ARGV[3])
+	redis.call("SADD", KEYS[2], ARGV[i])
+end
+`)
+
+// redisTakeResources removes the set KEYS[2] of the session ARGV[1] and its
+// entries of the owner hash KEYS[1], returning the resources.
+var redisTakeResources = redis.NewScript(2, `
+local held = redis.call("SMEMBERS", KEYS[2])
+for _, res in ipairs(held) do
+	if redis.call("HGET", KEYS[1], res) == ARGV[1] then
+		redis.call("HDEL", KEYS[1], res)
+	end
+end
+redis.call("DEL", KEYS[2])
+return held
+`)
+
+func (c *rediStore) RecordResources(id string, resources []string) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{}.Add(c.prefix+redisResourcesSuffix, c.prefix+id+redisResourcesSuffix).
+		Add(c.prefix, redisResourcesSuffix, id).AddFlat(resources)
+	_, err := redisRecordResources.Do(conn, args...)
+	return err
+}
+
+func (c *rediStore) TakeResources(id string) ([]string, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	return redis.Strings(redisTakeResources.Do(conn,
+		c.prefix+redisResourcesSuffix, c.prefix+id+redisResourcesSuffix, id))
+}
+
+func (c *rediStore) ResourceHolders() ([]string, error) {
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	owners, err := redis.Strings(conn.Do("HVALS", c.prefix+redisResourcesSuffix))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range owners {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Track records resource as owned by the named session.
+func (r *Resources) Track(s Session, name, resource string) {
+	for _, res := range r.Tracked(s, name) {
+		if res == resource {
+			return
+		}
+	}
+	s.Set(name, resourcesKey, append(r.Tracked(s, name), resource))
+}
+
+// Untrack forgets resource without releasing it, typically once an upload
+// has been moved to permanent storage.
+func (r *Resources) Untrack(s Session, name, resource string) {
+	tracked := r.Tracked(s, name)
+	kept := make([]string, 0, len(tracked))
+	for _, res := range tracked {
+		if res != resource {
+			kept = append(kept, res)
+		}
+	}
+	if len(kept) != len(tracked) {
+		s.Set(name, resourcesKey, kept)
+	}
+}
+
+// Tracked returns the resources recorded for the named session.
+func (r *Resources) Tracked(s Session, name string) []string {
+	tracked, _ := s.Get(name, resourcesKey).([]string)
+	return tracked
+}
+
+// ReleaseAll releases every resource of the named session. Resources that
+// fail to release stay tracked, and the first error is returned.
+func (r *Resources) ReleaseAll(s Session, name string) error {
+	var first error
+	var failed []string
+	for _, res := range r.Tracked(s, name) {
+		if err := r.Release(res); err != nil {
+			failed = append(failed, res)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		s.Delete(name, resourcesKey)
+	} else {
+		s.Set(name, resourcesKey, failed)
+	}
+	return first
+}
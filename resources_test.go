@@ -0,0 +1,181 @@
+package sessions
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/gorilla/sessions"
+)
+
+func Test_Resources(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	released := []string{}
+	uploads := &Resources{Release: func(res string) error {
+		if res == "busy" {
+			return errors.New("busy")
+		}
+		released = append(released, res)
+		return nil
+	}}
+
+	m.Get("/", func(session Session) string {
+		uploads.Track(session, "my_session", "a")
+		uploads.Track(session, "my_session", "b")
+		uploads.Track(session, "my_session", "a")
+		uploads.Track(session, "my_session", "busy")
+		uploads.Untrack(session, "my_session", "b")
+
+		if err := uploads.ReleaseAll(session, "my_session"); err == nil {
+			t.Error("Release error was not reported")
+		}
+		if len(released) != 1 || released[0] != "a" {
+			t.Error("Unexpected released resources:", released)
+		}
+		if tracked := uploads.Tracked(session, "my_session"); len(tracked) != 1 || tracked[0] != "busy" {
+			t.Error("Failed resource should stay tracked:", tracked)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_ResourcesDestroyed(t *testing.T) {
+	m := martini.Classic()
+
+	released := []string{}
+	uploads := &Resources{Release: func(res string) error {
+		released = append(released, res)
+		return nil
+	}}
+	m.Use(Sessions(NewResourceStore(NewMemoryStore(0), uploads)))
+
+	m.Get("/upload", func(session Session) string {
+		uploads.Track(session, "my_session", "a")
+		uploads.Track(session, "my_session", "b")
+		uploads.Untrack(session, "my_session", "b")
+		return "OK"
+	})
+	m.Get("/logout", func(session Session) string {
+		session.Destroy("my_session")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/upload", nil)
+	m.ServeHTTP(res, req)
+	if len(released) != 0 {
+		t.Error("Resources released too early:", released)
+	}
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/logout", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+	if len(released) != 1 || released[0] != "a" {
+		t.Error("Unexpected released resources:", released)
+	}
+}
+
+func Test_ResourcesExpired(t *testing.T) {
+	m := martini.Classic()
+
+	released := []string{}
+	uploads := &Resources{Release: func(res string) error {
+		released = append(released, res)
+		return nil
+	}}
+	store := NewServerStore(agedBackend{&memoryBackend{lru: list.New(), entries: make(map[string]*list.Element)}})
+	m.Use(Sessions(NewResourceStore(store, uploads)))
+
+	m.Get("/upload", func(session Session) string {
+		uploads.Track(session, "my_session", "a")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/upload", nil)
+	m.ServeHTTP(res, req)
+
+	if err := store.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || released[0] != "a" {
+		t.Error("Unexpected released resources:", released)
+	}
+}
+
+func Test_ResourcesEvicted(t *testing.T) {
+	m := martini.Classic()
+
+	released := []string{}
+	uploads := &Resources{Release: func(res string) error {
+		released = append(released, res)
+		return nil
+	}}
+	m.Use(Sessions(NewResourceStore(NewMemoryStore(1), uploads)))
+
+	m.Get("/upload/:name", func(session Session, p martini.Params) string {
+		uploads.Track(session, "my_session", p["name"])
+		return "OK"
+	})
+
+	for _, name := range []string{"a", "b"} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/upload/"+name, nil)
+		m.ServeHTTP(res, req)
+	}
+	if len(released) != 1 || released[0] != "a" {
+		t.Error("Resources of the evicted session were not released:", released)
+	}
+}
+
+func Test_ResourcesSweep(t *testing.T) {
+	released := []string{}
+	uploads := &Resources{Release: func(res string) error {
+		released = append(released, res)
+		return nil
+	}}
+	store := NewResourceStore(NewMemoryStore(0), uploads)
+
+	save := func(resource string) *sessions.Session {
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values[resourcesKey] = []string{resource}
+		if err := store.Save(req, httptest.NewRecorder(), s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	deleted, dropped, kept := save("a"), save("b"), save("c")
+
+	if err := store.(ManagedStore).Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || released[0] != "a" {
+		t.Error("Resources of the deleted session were not released:", released)
+	}
+
+	// the session is gone without the resource store noticing
+	ms, _ := StoreAs[ManagedStore](store.(Unwrapper).Unwrap())
+	ms.Delete(dropped.ID)
+	if err := uploads.Sweep(store); err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 2 || released[1] != "b" {
+		t.Error("Resources of the dropped session were not swept:", released)
+	}
+	if ok, _ := ms.Exists(kept.ID); !ok {
+		t.Error("Live session is gone")
+	}
+}
@@ -0,0 +1,158 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+const retentionKey = "_retention"
+
+func init() {
+	gob.Register(map[string]time.Time{})
+}
+
+// Retention declares how long session values may be kept, by key class, so
+// that sensitive values are purged well before the session itself expires.
+//
+//	m.Use(sessions.Retention{
+//		Classes: map[string]time.Duration{"ephemeral": time.Minute, "sensitive": 15 * time.Minute},
+//		Keys:    map[interface{}]string{"otp": "ephemeral", "card_last4": "sensitive"},
+//	}.Handler("my_session"))
+//
+// Keys without a class live as long as the session. Values of sessions that
+// see no further request are only purged by Purge, e.g. run periodically
+// with StartPurge.
+type Retention struct {
+	// Classes maps class names to the maximum lifetime of their values.
+	Classes map[string]time.Duration
+	// Keys maps session keys to their class.
+	Keys map[interface{}]string
+}
+
+// Handler returns a Middleware enforcing the retention of the named session.
+// Values are timestamped when the response is written, again whenever they
+// change, and removed on the first request after their lifetime has passed,
+// before any handler can read them. It must be used after Sessions, and
+// panics if a key has a class without a positive lifetime.
+func (ret Retention) Handler(name string) martini.Handler {
+	for key, class := range ret.Keys {
+		if ret.Classes[class] <= 0 {
+			panic(fmt.Sprintf("sessions: retention class %q of key %v has no lifetime", class, key))
+		}
+	}
+
+	return func(res http.ResponseWriter, s Session) {
+		stamps, _ := s.Get(name, retentionKey).(map[string]time.Time)
+		if expired := ret.expire(stamps); len(expired) > 0 {
+			for _, key := range expired {
+				s.Delete(name, key)
+			}
+			s.Set(name, retentionKey, stamps)
+		}
+
+		before := make(map[interface{}]interface{}, len(ret.Keys))
+		for key := range ret.Keys {
+			before[key] = s.GetCopy(name, key)
+		}
+		res.(martini.ResponseWriter).Before(func(martini.ResponseWriter) {
+			ret.stamp(s, name, before)
+		})
+	}
+}
+
+// expire removes the stamps of values whose lifetime has passed and returns
+// their keys.
+func (ret Retention) expire(stamps map[string]time.Time) []interface{} {
+	var expired []interface{}
+	for key, class := range ret.Keys {
+		id := fmt.Sprint(key)
+		if stamp, ok := stamps[id]; ok && time.Since(stamp) > ret.Classes[class] {
+			delete(stamps, id)
+			expired = append(expired, key)
+		}
+	}
+	return expired
+}
+
+// stamp records when classified values were set, given their values before
+// the request, and forgets the stamps of removed ones.
+func (ret Retention) stamp(s Session, name string, before map[interface{}]interface{}) {
+	stamps, _ := s.Get(name, retentionKey).(map[string]time.Time)
+	if stamps == nil {
+		stamps = make(map[string]time.Time)
+	}
+
+	changed := false
+	for key := range ret.Keys {
+		id := fmt.Sprint(key)
+		_, stamped := stamps[id]
+		val := s.Get(name, key)
+		present := val != nil
+		switch {
+		case present && (!stamped || !reflect.DeepEqual(val, before[key])):
+			stamps[id] = time.Now()
+			changed = true
+		case !present && stamped:
+			delete(stamps, id)
+			changed = true
+		}
+	}
+	if changed {
+		s.Set(name, retentionKey, stamps)
+	}
+}
+
+// rewriter is implemented by stores able to change the values of all their
+// sessions, see ServerStore.rewrite.
+type rewriter interface {
+	rewrite(fn func(values map[interface{}]interface{}) bool) error
+}
+
+// Purge removes the values whose lifetime has passed from all sessions of
+// store, which must be or wrap a server-side store whose backend is a
+// Scanner, such as the memory store. Other stores are left alone.
+func (ret Retention) Purge(store Store) error {
+	rw, ok := StoreAs[rewriter](store)
+	if !ok {
+		return nil
+	}
+	return rw.rewrite(func(values map[interface{}]interface{}) bool {
+		stamps, _ := values[retentionKey].(map[string]time.Time)
+		expired := ret.expire(stamps)
+		for _, key := range expired {
+			delete(values, key)
+		}
+		return len(expired) > 0
+	})
+}
+
+// StartPurge runs Purge on store every interval until stop is called,
+// logging failures to l unless it is nil.
+func (ret Retention) StartPurge(store Store, interval time.Duration, l *log.Logger) (stop func()) {
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := ret.Purge(store); err != nil && l != nil {
+					l.Printf(errorFormat, err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(quit) }) }
+}
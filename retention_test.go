@@ -0,0 +1,130 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Retention(t *testing.T) {
+	m := martini.Classic()
+
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(Retention{
+		Classes: map[string]time.Duration{"sensitive": 10 * time.Millisecond},
+		Keys:    map[interface{}]string{"card": "sensitive"},
+	}.Handler("my_session"))
+
+	m.Get("/set", func(session Session) string {
+		session.Set("my_session", "card", "4242")
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "card") != nil {
+			t.Error("Sensitive value outlived its retention")
+		}
+		if session.Get("my_session", "user") != "bob" {
+			t.Error("Unclassified value was purged")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/set", nil)
+	m.ServeHTTP(res, req)
+
+	time.Sleep(20 * time.Millisecond)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
+
+func Test_RetentionRestamp(t *testing.T) {
+	m := martini.Classic()
+
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(Retention{
+		Classes: map[string]time.Duration{"sensitive": 50 * time.Millisecond},
+		Keys:    map[interface{}]string{"card": "sensitive"},
+	}.Handler("my_session"))
+
+	m.Get("/set/:card", func(session Session, params martini.Params) string {
+		session.Set("my_session", "card", params["card"])
+		return "OK"
+	})
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "card") != "1111" {
+			t.Error("Changed value was purged by the stamp of the old one")
+		}
+		return "OK"
+	})
+
+	serve := func(path, cookie string) string {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Cookie", cookie)
+		m.ServeHTTP(res, req)
+		if c := res.Header().Get("Set-Cookie"); c != "" {
+			return c
+		}
+		return cookie
+	}
+
+	cookie := serve("/set/4242", "")
+	time.Sleep(30 * time.Millisecond)
+	cookie = serve("/set/1111", cookie)
+	time.Sleep(30 * time.Millisecond)
+	serve("/show", cookie)
+}
+
+func Test_RetentionUnknownClass(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Key of an undeclared class was accepted")
+		}
+	}()
+	Retention{Keys: map[interface{}]string{"card": "sensitive"}}.Handler("my_session")
+}
+
+func Test_RetentionPurge(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMemoryStore(0)
+	ret := Retention{
+		Classes: map[string]time.Duration{"sensitive": 10 * time.Millisecond},
+		Keys:    map[interface{}]string{"card": "sensitive"},
+	}
+	m.Use(Sessions(store))
+	m.Use(ret.Handler("my_session"))
+
+	m.Get("/set", func(session Session) string {
+		session.Set("my_session", "card", "4242")
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/set", nil)
+	m.ServeHTTP(res, req)
+
+	time.Sleep(20 * time.Millisecond)
+	if err := ret.Purge(store); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, _ := store.New(req, "my_session")
+	if _, ok := s.Values["card"]; ok {
+		t.Error("Sensitive value was not purged from the store")
+	}
+	if s.Values["user"] != "bob" {
+		t.Error("Unclassified value was purged:", s.Values)
+	}
+}
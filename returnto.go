@@ -0,0 +1,61 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const returnToKey = "_return_to"
+
+// ErrUnsafeRedirect is returned by SetReturnTo for URLs that would redirect
+// to a foreign site.
+var ErrUnsafeRedirect = errors.New("sessions: unsafe redirect URL")
+
+// SetReturnTo stores the URL to redirect to after login in the named
+// session. Only relative paths and absolute URLs pointing at the host of r
+// or one of allowedHosts are accepted, anything else fails with
+// ErrUnsafeRedirect.
+func SetReturnTo(s Session, name string, r *http.Request, rawurl string, allowedHosts ...string) error {
+	if !safeRedirect(rawurl, r.Host, allowedHosts) {
+		return ErrUnsafeRedirect
+	}
+	s.Set(name, returnToKey, rawurl)
+	return nil
+}
+
+// ConsumeReturnTo returns the URL stored by SetReturnTo and removes it, or
+// returns an empty string if none is stored.
+func ConsumeReturnTo(s Session, name string) string {
+	u, _ := s.Pop(name, returnToKey).(string)
+	return u
+}
+
+// safeRedirect reports whether rawurl stays on host or one of allowed.
+func safeRedirect(rawurl, host string, allowed []string) bool {
+	// browsers treat backslashes like slashes, "/\evil.com" is protocol relative
+	if rawurl == "" || strings.ContainsAny(rawurl, "\\\r\n\t") {
+		return false
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		return strings.HasPrefix(u.Path, "/") && !strings.HasPrefix(rawurl, "//")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	for _, h := range allowed {
+		if strings.EqualFold(u.Host, h) {
+			return true
+		}
+	}
+	return false
+}
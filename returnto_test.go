@@ -0,0 +1,41 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_ReturnTo(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session, r *http.Request) string {
+		for _, u := range []string{"//evil.com", "/\\evil.com", "https://evil.com/", "javascript:alert(1)", "account"} {
+			if SetReturnTo(session, "my_session", r, u) != ErrUnsafeRedirect {
+				t.Error("Unsafe redirect was accepted:", u)
+			}
+		}
+		for _, u := range []string{"/account?tab=1", "http://example.com/x", "https://login.example.com/"} {
+			if err := SetReturnTo(session, "my_session", r, u, "login.example.com"); err != nil {
+				t.Error("Safe redirect was rejected:", u)
+			}
+		}
+
+		if u := ConsumeReturnTo(session, "my_session"); u != "https://login.example.com/" {
+			t.Error("Unexpected return URL:", u)
+		}
+		if u := ConsumeReturnTo(session, "my_session"); u != "" {
+			t.Error("Return URL was not consumed:", u)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	m.ServeHTTP(res, req)
+}
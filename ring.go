@@ -0,0 +1,50 @@
+package sessions
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas is the number of points each member gets on a hash ring,
+// smoothing the distribution of keys.
+const ringReplicas = 160
+
+// Ring maps keys to members by consistent hashing, so adding or removing a
+// member only moves the keys of its share of the ring.
+type Ring struct {
+	hash   func([]byte) uint32
+	points []uint32
+	owners map[uint32]int
+}
+
+// NewRing returns a ring of the members with the given names, hashing with
+// hash or CRC-32 if it is nil.
+func NewRing(names []string, hash func([]byte) uint32) *Ring {
+	if hash == nil {
+		hash = crc32.ChecksumIEEE
+	}
+	r := &Ring{hash: hash, owners: make(map[uint32]int)}
+	for i, name := range names {
+		for j := 0; j < ringReplicas; j++ {
+			p := hash([]byte(name + "-" + strconv.Itoa(j)))
+			if _, taken := r.owners[p]; !taken {
+				r.owners[p] = i
+				r.points = append(r.points, p)
+			}
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Pick returns the index of the member owning key. The ring must not be
+// empty.
+func (r *Ring) Pick(key string) int {
+	p := r.hash([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= p })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
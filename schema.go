@@ -0,0 +1,110 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-martini/martini"
+)
+
+// SchemaViolation describes a session value that doesn't match a Schema.
+type SchemaViolation struct {
+	Name   string
+	Key    interface{}
+	Reason string
+}
+
+func (v SchemaViolation) Error() string {
+	return fmt.Sprintf("session %q key %v: %s", v.Name, v.Key, v.Reason)
+}
+
+// Schema declares the keys a session may hold and the type of their values.
+//
+//	m.Use(sessions.Schema{
+//		Keys: map[interface{}]interface{}{"user_id": int64(0), "cart": []cart.Item{}},
+//		MaxValueSize: 2048,
+//	}.Handler("my_session"))
+//
+// String keys starting with an underscore are reserved for the helpers of
+// this package and always allowed, as are the values of Buckets, which are
+// namespaced by the code owning them. Values set with SetWithTTL are checked
+// like the value they hold.
+type Schema struct {
+	// Keys maps every allowed key to an example value of its type.
+	Keys map[interface{}]interface{}
+	// MaxValueSize is the largest encoded size of a single value in bytes.
+	// Zero means no limit.
+	MaxValueSize int
+	// Reject skips saving the session altogether when it has violations,
+	// instead of only stripping the offending values.
+	Reject bool
+	// Report receives every violation. Defaults to logging them.
+	Report func(SchemaViolation)
+}
+
+// Handler returns a Middleware validating the named session against the
+// schema before it is saved. It must be used after Sessions.
+func (sc Schema) Handler(name string) martini.Handler {
+	return func(res http.ResponseWriter, s Session, l *log.Logger) {
+		res.(martini.ResponseWriter).Before(func(martini.ResponseWriter) {
+			if !s.Written(name) {
+				return
+			}
+			violations := sc.validate(name, s.Raw(name).Values)
+			for _, v := range violations {
+				if sc.Report != nil {
+					sc.Report(v)
+				} else {
+					check(v, l)
+				}
+				if !sc.Reject {
+					s.Delete(name, v.Key)
+				}
+			}
+			if sc.Reject && len(violations) > 0 {
+				s.Discard(name)
+			}
+		})
+	}
+}
+
+func (sc Schema) validate(name string, values map[interface{}]interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	for key, val := range values {
+		if k, ok := key.(string); ok && strings.HasPrefix(k, "_") {
+			continue
+		}
+		switch key.(type) {
+		case metadataKey, bucketKey:
+			continue
+		}
+		if e, ok := val.(expiringValue); ok {
+			val = e.Value
+		}
+
+		example, known := sc.Keys[key]
+		reason := ""
+		switch {
+		case !known:
+			reason = "unexpected key"
+		case val != nil && reflect.TypeOf(val) != reflect.TypeOf(example):
+			reason = fmt.Sprintf("expected %T, got %T", example, val)
+		default:
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(&val); err != nil {
+				reason = "not serializable: " + err.Error()
+			} else if sc.MaxValueSize > 0 && buf.Len() > sc.MaxValueSize {
+				reason = fmt.Sprintf("value is %d bytes, limit is %d", buf.Len(), sc.MaxValueSize)
+			}
+		}
+		if reason != "" {
+			violations = append(violations, SchemaViolation{Name: name, Key: key, Reason: reason})
+		}
+	}
+	return violations
+}
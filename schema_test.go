@@ -0,0 +1,123 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_Schema(t *testing.T) {
+	m := martini.Classic()
+
+	var violations []SchemaViolation
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(Schema{
+		Keys:         map[interface{}]interface{}{"user": "", "visits": 0, "bio": ""},
+		MaxValueSize: 64,
+		Report:       func(v SchemaViolation) { violations = append(violations, v) },
+	}.Handler("my_session"))
+
+	m.Get("/set", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		session.Set("my_session", "visits", "many")
+		session.Set("my_session", "bio", strings.Repeat("x", 100))
+		session.Set("my_session", "debug", true)
+		session.AddFlash("my_session", "saved")
+		return "OK"
+	})
+	m.Get("/show", func(session Session) string {
+		if session.Get("my_session", "user") != "bob" {
+			t.Error("Valid value was stripped")
+		}
+		for _, key := range []string{"visits", "bio", "debug"} {
+			if session.Get("my_session", key) != nil {
+				t.Error("Invalid value was saved:", key)
+			}
+		}
+		if len(session.Flashes("my_session")) != 1 {
+			t.Error("Reserved keys must be allowed")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/set", nil)
+	m.ServeHTTP(res, req)
+	if len(violations) != 3 {
+		t.Error("Expected 3 violations, got", violations)
+	}
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
+
+func Test_SchemaBucketsAndExpiringValues(t *testing.T) {
+	m := martini.Classic()
+
+	var violations []SchemaViolation
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(Schema{
+		Keys:   map[interface{}]interface{}{"user": "", "visits": 0},
+		Report: func(v SchemaViolation) { violations = append(violations, v) },
+	}.Handler("my_session"))
+
+	m.Get("/set", func(session Session) string {
+		session.Bucket("cart").Set("my_session", "item", "apples")
+		session.SetWithTTL("my_session", "user", "bob", time.Hour)
+		session.SetWithTTL("my_session", "visits", "many", time.Hour)
+		return "OK"
+	})
+	m.Get("/show", func(session Session) string {
+		if session.Bucket("cart").Get("my_session", "item") != "apples" {
+			t.Error("Bucket value was stripped")
+		}
+		if session.Get("my_session", "user") != "bob" {
+			t.Error("Valid expiring value was stripped")
+		}
+		if session.Get("my_session", "visits") != nil {
+			t.Error("Invalid expiring value was saved")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/set", nil)
+	m.ServeHTTP(res, req)
+	if len(violations) != 1 || violations[0].Key != "visits" {
+		t.Error("Expected a violation for visits, got", violations)
+	}
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
+
+func Test_SchemaReject(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+	m.Use(Schema{
+		Keys:   map[interface{}]interface{}{"user": ""},
+		Reject: true,
+		Report: func(SchemaViolation) {},
+	}.Handler("my_session"))
+
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		session.Set("my_session", "debug", true)
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Error("Session with violations was saved")
+	}
+}
@@ -0,0 +1,299 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// defaultTTL is how long server-side stores keep sessions with a MaxAge of
+// 0, which only live as long as the browser is open.
+const defaultTTL = 20 * time.Minute
+
+// Backend persists the encoded values of server-side sessions. Backends may
+// also implement PrefixDeleter, Purger, Pinger, Swapper, Scanner and
+// CounterBackend.
+type Backend interface {
+	// Load returns the data stored for id, or nil if there is none or it
+	// has expired.
+	Load(id string) ([]byte, error)
+	// Save stores data for id, expiring it after ttl.
+	Save(id string, data []byte, ttl time.Duration) error
+	// Delete removes the data stored for id.
+	Delete(id string) error
+	// Touch changes the expiry of the data stored for id to ttl from now,
+	// without rewriting the data. It does nothing if there is none or it
+	// has expired.
+	Touch(id string, ttl time.Duration) error
+}
+
+// ServerStore implements Store on top of a Backend. The cookie only holds a
+// random session ID, the values are gob encoded into the backend. Sessions
+// whose data is gone, e.g. because the backend evicted it, start over as new
+// sessions.
+//
+// The stores of the backend packages, such as postgresstore, embed a
+// ServerStore.
+type ServerStore struct {
+	backend Backend
+	options *sessions.Options
+	cleanup cleanup
+}
+
+// NewServerStore returns a ServerStore keeping sessions in backend.
+func NewServerStore(backend Backend) *ServerStore {
+	return &ServerStore{
+		backend: backend,
+		options: &sessions.Options{Path: "/", MaxAge: 86400 * 30},
+	}
+}
+
+func (s *ServerStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *ServerStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil || !validID(c.Value) {
+		return session, nil
+	}
+	data, err := s.backend.Load(c.Value)
+	if err != nil || data == nil {
+		return session, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = c.Value
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *ServerStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options != nil && session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return err
+	}
+	if err := s.backend.Save(session.ID, buf.Bytes(), sessionTTL(session.Options)); err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), session.ID, session.Options))
+	return nil
+}
+
+// saveVersion saves session only if the stored session still holds version
+// under versionKey, and reports whether it did. With a Swapper backend the
+// check and the write are atomic, otherwise the caller has to serialise
+// them.
+func (s *ServerStore) saveVersion(r *http.Request, w http.ResponseWriter, session *sessions.Session, version interface{}) (bool, error) {
+	old, err := s.backend.Load(session.ID)
+	if err != nil {
+		return false, err
+	}
+	stored := make(map[interface{}]interface{})
+	if old != nil {
+		if err := gob.NewDecoder(bytes.NewReader(old)).Decode(&stored); err != nil {
+			return false, err
+		}
+	}
+	if !reflect.DeepEqual(stored[versionKey], version) {
+		return false, nil
+	}
+
+	sw, ok := s.backend.(Swapper)
+	if !ok {
+		return true, s.Save(r, w, session)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return false, err
+	}
+	swapped, err := sw.Swap(session.ID, old, buf.Bytes(), sessionTTL(session.Options))
+	if err != nil || !swapped {
+		return false, err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), session.ID, session.Options))
+	return true, nil
+}
+
+// rewrite calls fn with the values of every session of a Scanner backend
+// and saves the sessions for which it returns true, keeping their expiry.
+// Sessions that can't be decoded are skipped. With a Swapper backend a
+// session saved concurrently is skipped too, otherwise the concurrent save
+// may be lost.
+func (s *ServerStore) rewrite(fn func(values map[interface{}]interface{}) bool) error {
+	sc, ok := s.backend.(Scanner)
+	if !ok {
+		return nil
+	}
+	sw, swaps := s.backend.(Swapper)
+	return sc.Scan(func(id string, data []byte, expires time.Time) error {
+		values := make(map[interface{}]interface{})
+		if gob.NewDecoder(bytes.NewReader(data)).Decode(&values) != nil || !fn(values) {
+			return nil
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+			return err
+		}
+		ttl := time.Until(expires)
+		if ttl <= 0 {
+			return nil
+		}
+		if swaps {
+			_, err := sw.Swap(id, data, buf.Bytes(), ttl)
+			return err
+		}
+		return s.backend.Save(id, buf.Bytes(), ttl)
+	})
+}
+
+func (s *ServerStore) Options(options Options) {
+	s.options = &sessions.Options{
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+	}
+}
+
+func (s *ServerStore) cookieID(r *http.Request, name string) string {
+	if c, err := r.Cookie(name); err == nil && validID(c.Value) {
+		return c.Value
+	}
+	return ""
+}
+
+// sessionTTL returns how long the backend should keep a session.
+func sessionTTL(opts *sessions.Options) time.Duration {
+	if opts == nil || opts.MaxAge <= 0 {
+		return defaultTTL
+	}
+	return time.Duration(opts.MaxAge) * time.Second
+}
+
+// WithExpiry prefixes data with its expiry in Unix seconds, for backends
+// without native expiration.
+func WithExpiry(data []byte, ttl time.Duration) []byte {
+	v := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(v, uint64(time.Now().Add(ttl).Unix()))
+	copy(v[8:], data)
+	return v
+}
+
+// ExpiresAt returns the expiry stored in v by WithExpiry.
+func ExpiresAt(v []byte) time.Time {
+	if len(v) < 8 {
+		return time.Time{}
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(v)), 0)
+}
+
+// UnlessExpired returns a copy of the data stored in v by WithExpiry, or nil
+// if it has expired.
+func UnlessExpired(v []byte) []byte {
+	if len(v) < 8 || int64(binary.BigEndian.Uint64(v)) < time.Now().Unix() {
+		return nil
+	}
+	return append([]byte(nil), v[8:]...)
+}
+
+// newID returns a random, unguessable session ID.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id has the shape of an ID made by newID,
+// optionally behind a namespace of letters, digits, '_', '-', '.' and ':',
+// so that tampered cookies never reach the backend.
+func validID(id string) bool {
+	if len(id) < 64 {
+		return false
+	}
+	for _, c := range id[:len(id)-64] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-.:", c)) {
+			return false
+		}
+	}
+	_, err := hex.DecodeString(id[len(id)-64:])
+	return err == nil
+}
+
+// flush deletes all sessions whose ID starts with prefix, if the backend
+// supports it.
+func (s *ServerStore) flush(prefix string) error {
+	if d, ok := s.backend.(PrefixDeleter); ok {
+		return d.DeletePrefix(prefix)
+	}
+	return ErrFlushUnsupported
+}
+
+// Increment implements Counter if the backend is a CounterBackend.
+func (s *ServerStore) Increment(id, key string, delta int64) (int64, bool, error) {
+	if cb, ok := s.backend.(CounterBackend); ok {
+		return cb.Increment(id, key, delta)
+	}
+	return 0, false, nil
+}
+
+// CounterBackend is implemented by backends of server-side stores that can
+// keep counters, as for Counter.
+type CounterBackend interface {
+	Increment(id, key string, delta int64) (int64, bool, error)
+}
+
+// Swapper is implemented by backends able to compare-and-swap a session:
+// Swap stores data only if the currently stored data, nil for a missing or
+// expired session, equals old, and reports whether it did.
+type Swapper interface {
+	Swap(id string, old, data []byte, ttl time.Duration) (bool, error)
+}
+
+// Scanner is implemented by backends able to iterate over their sessions.
+type Scanner interface {
+	// Scan calls fn with the ID, data and expiry of every session that
+	// hasn't expired, stopping at the first error fn returns.
+	Scan(fn func(id string, data []byte, expires time.Time) error) error
+}
+
+// PrefixDeleter is implemented by backends able to delete all sessions with
+// IDs starting with a prefix.
+type PrefixDeleter interface {
+	DeletePrefix(prefix string) error
+}
@@ -11,10 +11,10 @@
 // 	  m := martini.Classic()
 //
 // 	  store := sessions.NewCookieStore([]byte("secret123"))
-// 	  m.Use(sessions.Sessions("my_session", store))
+// 	  m.Use(sessions.Sessions(store))
 //
 // 	  m.Get("/", func(session sessions.Session) string {
-// 		  session.Set("hello", "world")
+// 		  session.Set("my_session", "hello", "world")
 // 	  })
 //  }
 package sessions
@@ -22,6 +22,7 @@ package sessions
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/go-martini/martini"
 	"github.com/gorilla/context"
@@ -57,8 +58,64 @@ var _ Session = (*session)(nil)
 type Session interface {
 	// Get returns the session value associated to the given key.
 	Get(name string, key interface{}) interface{}
+	// GetOk returns the session value associated to the given key and
+	// whether the key is present at all.
+	GetOk(name string, key interface{}) (interface{}, bool)
+	// GetCopy returns a deep copy of the session value associated to the
+	// given key, so that changing slices or maps in it does not silently
+	// change the session. Values of custom types must be registered with gob.
+	GetCopy(name string, key interface{}) interface{}
+	// GetDefault returns the session value associated to the given key, or
+	// def if the key is missing.
+	GetDefault(name string, key, def interface{}) interface{}
+	// GetString returns the string value associated to the given key. If the
+	// key is missing or holds another type, it returns "" and false.
+	GetString(name string, key interface{}) (string, bool)
+	// GetInt returns the int value associated to the given key, also
+	// reading the int64 values of Increment. If the key is missing or holds
+	// another type, it returns 0 and false.
+	GetInt(name string, key interface{}) (int, bool)
+	// GetBool returns the bool value associated to the given key. If the key
+	// is missing or holds another type, it returns false and false.
+	GetBool(name string, key interface{}) (bool, bool)
+	// GetTime returns the time.Time value associated to the given key. If the
+	// key is missing or holds another type, it returns the zero time and
+	// false.
+	GetTime(name string, key interface{}) (time.Time, bool)
 	// Set sets the session value associated to the given key.
 	Set(name string, key interface{}, val interface{})
+	// Increment adds delta to the int64 value associated to the given key and
+	// returns the result. If the store is a Counter, the value is changed
+	// atomically in the store and can't be read with Get, see Counter.
+	Increment(name string, key string, delta int64) int64
+	// Decrement subtracts delta from the int64 value associated to the given
+	// key like Increment.
+	Decrement(name string, key string, delta int64) int64
+	// Pop returns the session value associated to the given key and removes
+	// it, e.g. for one-time tokens.
+	Pop(name string, key interface{}) interface{}
+	// SetWithTTL sets the session value associated to the given key like
+	// Set, but the value expires after ttl, independent of the session. Expired
+	// values are removed when they are read.
+	SetWithTTL(name string, key interface{}, val interface{}, ttl time.Duration)
+	// SetAll sets all given values in the session, keeping other keys.
+	SetAll(name string, values map[interface{}]interface{})
+	// GetAll returns a copy of all values in the session.
+	GetAll(name string) map[interface{}]interface{}
+	// Store copies the exported fields of the struct src into the session,
+	// each under the key given by its `session` tag or else its name. Fields
+	// tagged `session:"-"` are skipped. Field values of custom types must be
+	// registered with gob.
+	Store(name string, src interface{}) error
+	// Bind fills the struct pointed to by dst from the session values stored
+	// by Store. Fields without a value are left untouched.
+	Bind(name string, dst interface{}) error
+	// Snapshot returns the gob encoded values of the session, e.g. to stash
+	// them before a multi-step flow the user may cancel. Values of custom
+	// types must be registered with gob.
+	Snapshot(name string) ([]byte, error)
+	// Restore replaces all values of the session with those of a snapshot.
+	Restore(name string, snapshot []byte) error
 	// Delete removes the session value associated to the given key.
 	Delete(name string, key interface{})
 	// Clear deletes all values in the session.
@@ -71,8 +128,81 @@ type Session interface {
 	// A single variadic argument is accepted, and it is optional: it defines the flash key.
 	// If not defined "_flash" is used by default.
 	Flashes(name string, vars ...string) []interface{}
+	// HasFlashes reports whether the session holds flash messages, without
+	// consuming them or causing the session to be saved.
+	HasFlashes(name string, vars ...string) bool
+	// AddFlashLevel adds a flash message of the given level, such as
+	// FlashError, to the session.
+	AddFlashLevel(name string, level FlashLevel, msg string)
+	// FlashesByLevel returns the messages added with AddFlashLevel grouped by
+	// level, in the order they were added, and removes them from the session.
+	FlashesByLevel(name string) map[FlashLevel][]string
+	// PeekFlashes returns the flash messages like Flashes, but leaves them in
+	// the session.
+	PeekFlashes(name string, vars ...string) []interface{}
+	// Bucket returns a view of the sessions whose keys live in a namespace of
+	// their own, so that e.g. different middleware can share a session without
+	// clobbering each other's keys. Methods that act on the whole session,
+	// such as Destroy or Options, are not scoped.
+	Bucket(bucket string) Session
 	// Options sets confuguration for a session.
 	Options(name string, opts Options)
+	// GetOptions returns the current configuration of a session.
+	GetOptions(name string) Options
+	// MergeInto copies all values of the named session into the session into,
+	// resolving keys present in both with strategy, and then destroys the named
+	// session. A nil strategy lets the merged values overwrite existing ones.
+	MergeInto(name, into string, strategy MergeStrategy)
+	// RegenerateID drops the ID of the session while keeping its values, so
+	// the store issues a new one on save, and deletes the old server-side
+	// record if the store supports it. Call it after login to prevent session
+	// fixation.
+	RegenerateID(name string) error
+	// Destroy removes all values from the session and expires its cookie. The
+	// server-side record is deleted as well if the store supports it.
+	Destroy(name string)
+	// ID returns the ID of the session, or an empty string if the store keeps
+	// everything in the cookie or has not assigned one yet.
+	ID(name string) string
+	// IsNew reports whether the session was created during this request
+	// rather than loaded from the store.
+	IsNew(name string) bool
+	// CreatedAt returns when the session was first saved, or the zero time
+	// for sessions that were never saved.
+	CreatedAt(name string) time.Time
+	// LastAccessed returns when the session was last used before the current
+	// request, with a granularity of a minute, or the zero time for sessions
+	// that were never saved.
+	LastAccessed(name string) time.Time
+	// Touch renews the cookie expiry and the expiry in the store without
+	// changing the values, e.g. for a heartbeat endpoint. Stores that are a
+	// ManagedStore only extend the expiry of the stored session, others
+	// save it again.
+	Touch(name string)
+	// Save persists the session right away instead of waiting for the
+	// response to be written, e.g. before hijacking the connection or
+	// streaming a response.
+	Save(name string) error
+	// SaveAll saves all sessions that were modified during the request.
+	SaveAll() error
+	// Discard drops all changes made to the session since it was last saved
+	// or loaded, so they are not saved when the response is written.
+	Discard(name string)
+	// Written reports whether the session was changed since it was last
+	// saved or loaded, so it is saved when the response is written.
+	Written(name string) bool
+	// Raw returns the underlying gorilla session, e.g. to reach features of a
+	// particular store. Changes made through it bypass the change tracking of
+	// this package, call Touch to have the session saved.
+	Raw(name string) *sessions.Session
+	// Keys returns all keys stored in the session, in no particular order.
+	Keys(name string) []interface{}
+	// Len returns the number of values stored in the session.
+	Len(name string) int
+	// Size estimates the size in bytes of the serialized values. Cookie
+	// stores add encryption, signing and encoding overhead on top, so keep
+	// well below the 4KB browsers accept per cookie.
+	Size(name string) int
 }
 
 // Sessions is a Middleware that maps a session.Session service into the Martini handler chain.
@@ -83,7 +213,9 @@ func Sessions(store Store) martini.Handler {
 		s := &session{
 			ss:      make(map[string]*sessions.Session),
 			written: make(map[string]bool),
+			dirty:   make(map[string]map[interface{}]bool),
 			request: r,
+			res:     res,
 			store:   store,
 			logger:  l,
 		}
@@ -93,8 +225,11 @@ func Sessions(store Store) martini.Handler {
 		rw := res.(martini.ResponseWriter)
 		rw.Before(func(martini.ResponseWriter) {
 			for n := range s.ss {
+				// sessions in use are kept alive by saving them
+				// once their last access time got stale
+				s.stamp(n)
 				if s.Written(n) {
-					check(s.Session(n).Save(r, res), l)
+					check(s.Save(n), l)
 				}
 			}
 		})
@@ -109,40 +244,150 @@ func Sessions(store Store) martini.Handler {
 
 type session struct {
 	ss      map[string]*sessions.Session
+	bucket  string
 	written map[string]bool
+	dirty   map[string]map[interface{}]bool
 	request *http.Request
+	res     http.ResponseWriter
 	logger  *log.Logger
 	store   Store
 }
 
 func (s *session) Get(name string, key interface{}) interface{} {
-	return s.Session(name).Values[key]
+	val, _ := s.unwrap(name, key)
+	return val
+}
+
+func (s *session) GetOk(name string, key interface{}) (interface{}, bool) {
+	return s.unwrap(name, key)
+}
+
+func (s *session) GetCopy(name string, key interface{}) interface{} {
+	val, ok := s.GetOk(name, key)
+	if !ok {
+		return nil
+	}
+	return copyValues(map[interface{}]interface{}{key: val})[key]
+}
+
+func (s *session) GetDefault(name string, key, def interface{}) interface{} {
+	if val, ok := s.GetOk(name, key); ok {
+		return val
+	}
+	return def
+}
+
+func (s *session) GetString(name string, key interface{}) (string, bool) {
+	val, ok := s.Get(name, key).(string)
+	return val, ok
+}
+
+func (s *session) GetInt(name string, key interface{}) (int, bool) {
+	switch val := s.Get(name, key).(type) {
+	case int:
+		return val, true
+	case int64:
+		if int64(int(val)) == val {
+			return int(val), true
+		}
+	}
+	return 0, false
+}
+
+func (s *session) GetBool(name string, key interface{}) (bool, bool) {
+	val, ok := s.Get(name, key).(bool)
+	return val, ok
+}
+
+func (s *session) GetTime(name string, key interface{}) (time.Time, bool) {
+	val, ok := s.Get(name, key).(time.Time)
+	return val, ok
 }
 
 func (s *session) Set(name string, key interface{}, val interface{}) {
+	key = s.key(key)
 	s.Session(name).Values[key] = val
-	s.written[name] = true
+	s.markDirty(name, key)
+}
+
+func (s *session) SetAll(name string, values map[interface{}]interface{}) {
+	for key, val := range values {
+		s.Set(name, key, val)
+	}
+}
+
+func (s *session) GetAll(name string) map[interface{}]interface{} {
+	values := make(map[interface{}]interface{})
+	for key := range s.values(name) {
+		if val, ok := s.unwrap(name, key); ok {
+			values[key] = val
+		}
+	}
+	return values
+}
+
+func (s *session) Pop(name string, key interface{}) interface{} {
+	val, ok := s.GetOk(name, key)
+	if ok {
+		s.Delete(name, key)
+	}
+	return val
 }
 
 func (s *session) Delete(name string, key interface{}) {
-	delete(s.Session(name).Values, key)
-	s.written[name] = true
+	key = s.key(key)
+	values := s.Session(name).Values
+	if _, ok := values[key]; ok {
+		delete(values, key)
+		s.markDirty(name, key)
+	}
+}
+
+func (s *session) Keys(name string) []interface{} {
+	values := s.values(name)
+	keys := make([]interface{}, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *session) Len(name string) int {
+	return len(s.values(name))
+}
+
+func (s *session) Size(name string) int {
+	size, err := encodedSize(s.values(name))
+	check(err, s.logger)
+	return size
 }
 
 func (s *session) Clear(name string) {
-	for key := range s.Session(name).Values {
+	for key := range s.values(name) {
 		s.Delete(name, key)
 	}
 }
 
 func (s *session) AddFlash(name string, value interface{}, vars ...string) {
-	s.Session(name).AddFlash(value, vars...)
-	s.written[name] = true
+	flashes, _ := s.Get(name, flashKey(vars)).([]interface{})
+	s.Set(name, flashKey(vars), append(flashes, value))
 }
 
 func (s *session) Flashes(name string, vars ...string) []interface{} {
-	s.written[name] = true
-	return s.Session(name).Flashes(vars...)
+	// only consuming flashes changes the session
+	flashes, _ := s.Get(name, flashKey(vars)).([]interface{})
+	s.Delete(name, flashKey(vars))
+	return flashes
+}
+
+func (s *session) HasFlashes(name string, vars ...string) bool {
+	flashes, _ := s.Get(name, flashKey(vars)).([]interface{})
+	return len(flashes) > 0
+}
+
+func (s *session) PeekFlashes(name string, vars ...string) []interface{} {
+	flashes, _ := s.Get(name, flashKey(vars)).([]interface{})
+	return append([]interface{}(nil), flashes...)
 }
 
 func (s *session) Options(name string, options Options) {
@@ -155,6 +400,102 @@ func (s *session) Options(name string, options Options) {
 	}
 }
 
+func (s *session) GetOptions(name string) Options {
+	opts := s.Session(name).Options
+	if opts == nil {
+		return Options{}
+	}
+	return Options{
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+	}
+}
+
+func (s *session) MergeInto(name, into string, strategy MergeStrategy) {
+	target := s.Session(into).Values
+	for key, val := range s.values(name) {
+		if cur, ok := target[s.key(key)]; ok && strategy != nil {
+			val = strategy(key, cur, val)
+		}
+		target[s.key(key)] = val
+	}
+	s.written[into] = true
+	s.Destroy(name)
+}
+
+func (s *session) RegenerateID(name string) error {
+	session := s.Session(name)
+	if ms, ok := StoreAs[ManagedStore](s.store); ok && session.ID != "" {
+		if err := ms.Delete(session.ID); err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	s.written[name] = true
+	return nil
+}
+
+func (s *session) ID(name string) string {
+	return s.Session(name).ID
+}
+
+func (s *session) IsNew(name string) bool {
+	return s.Session(name).IsNew
+}
+
+func (s *session) Touch(name string) {
+	session := s.Session(name)
+	ms, ok := StoreAs[ManagedStore](s.store)
+	c, err := s.request.Cookie(name)
+	if !ok || session.IsNew || session.ID == "" || err != nil {
+		s.written[name] = true
+		return
+	}
+
+	// extend the expiry in the store without rewriting the values, and
+	// send the cookie of the request back with a fresh expiry
+	check(ms.Touch(session.ID, sessionTTL(session.Options)), s.logger)
+	http.SetCookie(s.res, sessions.NewCookie(name, c.Value, session.Options))
+}
+
+func (s *session) Save(name string) error {
+	if err := s.store.Save(s.request, s.res, s.Session(name)); err != nil {
+		return err
+	}
+	s.clean(name)
+	return nil
+}
+
+func (s *session) SaveAll() error {
+	for n := range s.ss {
+		if s.Written(n) {
+			if err := s.Save(n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *session) Discard(name string) {
+	session := s.Session(name)
+	fresh, err := s.store.New(s.request, name)
+	check(err, s.logger)
+	// update the session in place, the store's registry still holds it
+	session.ID = fresh.ID
+	session.Values = fresh.Values
+	session.Options = fresh.Options
+	session.IsNew = fresh.IsNew
+	s.clean(name)
+}
+
+func (s *session) Raw(name string) *sessions.Session {
+	return s.Session(name)
+}
+
 func (s *session) Session(name string) *sessions.Session {
 	if s.ss[name] == nil {
 		var err error
@@ -166,7 +507,41 @@ func (s *session) Session(name string) *sessions.Session {
 }
 
 func (s *session) Written(name string) bool {
-	return s.written[name]
+	return s.written[name] || len(s.dirty[name]) > 0
+}
+
+// markDirty records that key of the named session changed, so the session
+// gets saved.
+func (s *session) markDirty(name string, key interface{}) {
+	if s.dirty[name] == nil {
+		s.dirty[name] = make(map[interface{}]bool)
+	}
+	s.dirty[name][key] = true
+}
+
+// clean forgets all changes to the named session, e.g. after it was saved.
+func (s *session) clean(name string) {
+	s.written[name] = false
+	delete(s.dirty, name)
+}
+
+func (s *session) Destroy(name string) {
+	session := s.Session(name)
+	for key := range session.Values {
+		delete(session.Values, key)
+	}
+
+	opts := sessions.Options{}
+	if session.Options != nil {
+		opts = *session.Options
+	}
+	opts.MaxAge = -1
+	session.Options = &opts
+	s.written[name] = true
+
+	if ms, ok := StoreAs[ManagedStore](s.store); ok && session.ID != "" {
+		check(ms.Delete(session.ID), s.logger)
+	}
 }
 
 func check(err error, l *log.Logger) {
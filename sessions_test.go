@@ -1,28 +1,36 @@
 package sessions
 
 import (
+	"errors"
 	"github.com/go-martini/martini"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test_Sessions(t *testing.T) {
 	m := martini.Classic()
 
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 
 	m.Get("/testsession", func(session Session) string {
-		session.Set("hello", "world")
+		if !session.IsNew("my_session") {
+			t.Error("Fresh session was not reported as new")
+		}
+		session.Set("my_session", "hello", "world")
 		return "OK"
 	})
 
 	m.Get("/show", func(session Session) string {
-		if session.Get("hello") != "world" {
+		if session.Get("my_session", "hello") != "world" {
 			t.Error("Session writing failed")
 		}
+		if session.IsNew("my_session") {
+			t.Error("Loaded session was reported as new")
+		}
 		return "OK"
 	})
 
@@ -40,18 +48,37 @@ func Test_SessionsDeleteValue(t *testing.T) {
 	m := martini.Classic()
 
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 
 	m.Get("/testsession", func(session Session) string {
-		session.Set("hello", "world")
-		session.Delete("hello")
+		session.Set("my_session", "hello", "world")
+		if keys := session.Keys("my_session"); len(keys) != 1 || keys[0] != "hello" {
+			t.Error("Keys did not list the stored key:", keys)
+		}
+		if val, ok := session.GetOk("my_session", "hello"); !ok || val != "world" {
+			t.Error("GetOk did not find the stored value")
+		}
+		session.Set("my_session", "token", "abc")
+		if session.Pop("my_session", "token") != "abc" || session.Get("my_session", "token") != nil {
+			t.Error("Pop did not return and remove the value")
+		}
+		if session.Len("my_session") != 1 || session.Size("my_session") == 0 {
+			t.Error("Len or Size did not account the stored value")
+		}
+		session.Delete("my_session", "hello")
 		return "OK"
 	})
 
 	m.Get("/show", func(session Session) string {
-		if session.Get("hello") == "world" {
+		if session.Get("my_session", "hello") == "world" {
 			t.Error("Session value deleting failed")
 		}
+		if _, ok := session.GetOk("my_session", "hello"); ok {
+			t.Error("Deleted key is still present")
+		}
+		if len(session.Keys("my_session")) != 0 {
+			t.Error("Deleted key is still listed:", session.Keys("my_session"))
+		}
 		return "OK"
 	})
 
@@ -71,18 +98,24 @@ func Test_Options(t *testing.T) {
 	store.Options(Options{
 		Domain: "martini.codegangsta.io",
 	})
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 
 	m.Get("/", func(session Session) string {
-		session.Set("hello", "world")
-		session.Options(Options{
+		session.Set("my_session", "hello", "world")
+		if session.GetOptions("my_session").Domain != "martini.codegangsta.io" {
+			t.Error("Store options were not returned:", session.GetOptions("my_session"))
+		}
+		session.Options("my_session", Options{
 			Path: "/foo/bar/bat",
 		})
+		if opts := session.GetOptions("my_session"); opts.Path != "/foo/bar/bat" || opts.Domain != "" {
+			t.Error("Session options were not returned:", opts)
+		}
 		return "OK"
 	})
 
 	m.Get("/foo", func(session Session) string {
-		session.Set("hello", "world")
+		session.Set("my_session", "hello", "world")
 		return "OK"
 	})
 
@@ -109,15 +142,21 @@ func Test_Flashes(t *testing.T) {
 	m := martini.Classic()
 
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 
 	m.Get("/set", func(session Session) string {
-		session.AddFlash("hello world")
+		session.AddFlash("my_session", "hello world")
 		return "OK"
 	})
 
 	m.Get("/show", func(session Session) string {
-		l := len(session.Flashes())
+		if !session.HasFlashes("my_session") {
+			t.Error("Flash was not detected")
+		}
+		if len(session.PeekFlashes("my_session")) != 1 {
+			t.Error("Peeking did not return the flash")
+		}
+		l := len(session.Flashes("my_session"))
 		if l != 1 {
 			t.Error("Flashes count does not equal 1. Equals ", l)
 		}
@@ -125,7 +164,7 @@ func Test_Flashes(t *testing.T) {
 	})
 
 	m.Get("/showagain", func(session Session) string {
-		l := len(session.Flashes())
+		l := len(session.Flashes("my_session"))
 		if l != 0 {
 			t.Error("flashes count is not 0 after reading. Equals ", l)
 		}
@@ -156,19 +195,19 @@ func Test_SessionsClear(t *testing.T) {
 	}
 
 	store := NewCookieStore([]byte("secret123"))
-	m.Use(Sessions("my_session", store))
+	m.Use(Sessions(store))
 
 	m.Get("/testsession", func(session Session) string {
 		for k, v := range data {
-			session.Set(k, v)
+			session.Set("my_session", k, v)
 		}
-		session.Clear()
+		session.Clear("my_session")
 		return "OK"
 	})
 
 	m.Get("/show", func(session Session) string {
 		for k, v := range data {
-			if session.Get(k) == v {
+			if session.Get("my_session", k) == v {
 				t.Fatal("Session clear failed")
 			}
 		}
@@ -184,3 +223,399 @@ func Test_SessionsClear(t *testing.T) {
 	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
 	m.ServeHTTP(res2, req2)
 }
+
+func Test_SessionsMergeInto(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/login", func(session Session) string {
+		session.Set("guest", "cart", "apples")
+		session.Set("guest", "theme", "light")
+		session.Set("user", "theme", "dark")
+		session.MergeInto("guest", "user", KeepExisting)
+
+		if session.Get("user", "cart") != "apples" {
+			t.Error("Guest value was not merged")
+		}
+		if session.Get("user", "theme") != "dark" {
+			t.Error("Merge strategy was not applied")
+		}
+		if session.Get("guest", "cart") != nil {
+			t.Error("Guest session was not destroyed")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+
+	for _, c := range res.Header()["Set-Cookie"] {
+		if strings.HasPrefix(c, "guest=") && !strings.Contains(c, "Max-Age=0") {
+			t.Error("Guest cookie was not expired:", c)
+		}
+	}
+}
+
+func Test_SessionsMergeIntoBucket(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/login", func(session Session) string {
+		cart := session.Bucket("cart")
+		cart.Set("guest", "items", "apples")
+		cart.Set("user", "note", "gift")
+		cart.MergeInto("guest", "user", KeepExisting)
+
+		if cart.Get("user", "items") != "apples" || cart.Get("user", "note") != "gift" {
+			t.Error("Guest value was not merged into the bucket")
+		}
+		if session.Get("user", "items") != nil {
+			t.Error("Bucket value leaked out of the bucket")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_SessionsRegenerateID(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMemoryStore(0)
+	defer store.Close()
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		session.Set("my_session", "hello", "world")
+		return "OK"
+	})
+
+	m.Get("/login", func(session Session) string {
+		old := session.ID("my_session")
+		if old == "" {
+			t.Error("Session ID was not exposed")
+		}
+		if err := session.RegenerateID("my_session"); err != nil {
+			t.Error("Regenerating the ID failed:", err)
+		}
+		if session.ID("my_session") == old {
+			t.Error("Old session ID is still in use")
+		}
+		if session.Raw("my_session").ID != session.ID("my_session") {
+			t.Error("Raw did not return the underlying session")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/login", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+
+	old, regenerated := res.Header().Get("Set-Cookie"), res2.Header().Get("Set-Cookie")
+	if regenerated == "" || strings.Split(old, ";")[0] == strings.Split(regenerated, ";")[0] {
+		t.Fatal("Session ID was not regenerated:", regenerated)
+	}
+
+	req3, _ := http.NewRequest("GET", "/", nil)
+	req3.Header.Set("Cookie", regenerated)
+	s, _ := store.Get(req3, "my_session")
+	if s.Values["hello"] != "world" {
+		t.Error("Values were not kept across regeneration")
+	}
+
+	req4, _ := http.NewRequest("GET", "/", nil)
+	req4.Header.Set("Cookie", old)
+	if s, _ := store.Get(req4, "my_session"); !s.IsNew {
+		t.Error("Old session record was not deleted")
+	}
+}
+
+func Test_SessionsDestroy(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMemoryStore(0)
+	defer store.Close()
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		session.Set("my_session", "hello", "world")
+		return "OK"
+	})
+
+	m.Get("/logout", func(session Session) string {
+		session.Destroy("my_session")
+		if session.Get("my_session", "hello") != nil {
+			t.Error("Values were not removed")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/logout", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+
+	if !strings.Contains(res2.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Error("Session cookie was not expired:", res2.Header().Get("Set-Cookie"))
+	}
+
+	req3, _ := http.NewRequest("GET", "/", nil)
+	req3.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	if s, _ := store.Get(req3, "my_session"); !s.IsNew {
+		t.Error("Session record was not deleted")
+	}
+}
+
+func Test_SessionsTypedGetters(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	now := time.Now()
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "name", "bob")
+		session.Set("my_session", "visits", 3)
+		session.Set("my_session", "admin", true)
+		session.Set("my_session", "seen", now)
+
+		if v, ok := session.GetString("my_session", "name"); !ok || v != "bob" {
+			t.Error("GetString failed")
+		}
+		if v, ok := session.GetInt("my_session", "visits"); !ok || v != 3 {
+			t.Error("GetInt failed")
+		}
+		if v, ok := session.GetBool("my_session", "admin"); !ok || !v {
+			t.Error("GetBool failed")
+		}
+		if v, ok := session.GetTime("my_session", "seen"); !ok || !v.Equal(now) {
+			t.Error("GetTime failed")
+		}
+		if v, ok := session.GetInt("my_session", "name"); ok || v != 0 {
+			t.Error("GetInt accepted a string")
+		}
+		if v, ok := session.GetString("my_session", "missing"); ok || v != "" {
+			t.Error("GetString found a missing key")
+		}
+		session.Set("my_session", "tags", []string{"a"})
+		if tags := session.GetCopy("my_session", "tags").([]string); len(tags) == 1 {
+			tags[0] = "changed"
+		}
+		if session.Get("my_session", "tags").([]string)[0] != "a" {
+			t.Error("GetCopy returned a live reference")
+		}
+		if session.GetDefault("my_session", "theme", "light") != "light" {
+			t.Error("GetDefault did not fall back for a missing key")
+		}
+		if session.GetDefault("my_session", "name", "alice") != "bob" {
+			t.Error("GetDefault ignored a stored value")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+func Test_SessionsSetAll(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/testsession", func(session Session) string {
+		session.Set("my_session", "theme", "dark")
+		session.SetAll("my_session", map[interface{}]interface{}{"hello": "world", "lang": "en"})
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		all := session.GetAll("my_session")
+		if len(all) != 3 || all["hello"] != "world" || all["theme"] != "dark" {
+			t.Error("GetAll did not return all values:", all)
+		}
+		all["hello"] = "changed"
+		if session.Get("my_session", "hello") != "world" {
+			t.Error("GetAll did not return a copy")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/testsession", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
+
+func Test_SessionsSave(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMockStore()
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "hello", "world")
+		if err := session.Save("my_session"); err != nil {
+			t.Error("Saving the session failed:", err)
+		}
+		if store.Values("my_session")["hello"] != "world" {
+			t.Error("Session was not saved on demand")
+		}
+
+		store.Fail(errors.New("backend down"))
+		session.Set("my_session", "hello", "again")
+		if err := session.SaveAll(); err == nil {
+			t.Error("Save error was not reported")
+		}
+		store.Fail(nil)
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+
+	if store.Saves("my_session") != 2 {
+		t.Error("Expected one explicit and one deferred save, got", store.Saves("my_session"))
+	}
+}
+
+func Test_SessionsDiscard(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMockStore()
+	store.Seed("my_session", map[interface{}]interface{}{"hello": "world"})
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "hello", "changed")
+		session.Set("my_session", "partial", true)
+		session.Discard("my_session")
+		if session.Get("my_session", "hello") != "world" || session.Get("my_session", "partial") != nil {
+			t.Error("Changes were not discarded")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+
+	if store.Saves("my_session") != 0 {
+		t.Error("Discarded session was saved")
+	}
+}
+
+func Test_SessionsDirtyTracking(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewMockStore()
+	store.Seed("my_session", map[interface{}]interface{}{"hello": "world"})
+	m.Use(Sessions(store))
+
+	m.Get("/read", func(session Session) string {
+		session.Get("my_session", "hello")
+		if session.HasFlashes("my_session") {
+			session.Flashes("my_session")
+		}
+		session.Delete("my_session", "missing")
+		return "OK"
+	})
+
+	m.Get("/write", func(session Session) string {
+		session.AddFlash("my_session", "saved")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/read", nil)
+	m.ServeHTTP(res, req)
+	if store.Saves("my_session") != 0 {
+		t.Error("Unchanged session was saved")
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/write", nil)
+	m.ServeHTTP(res, req)
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/read", nil)
+	m.ServeHTTP(res, req)
+	if store.Saves("my_session") != 2 {
+		t.Error("Adding and consuming flashes did not save the session")
+	}
+}
+
+func Test_SessionsTouch(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/heartbeat", func(session Session) string {
+		session.Touch("my_session")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/heartbeat", nil)
+	m.ServeHTTP(res, req)
+
+	if !strings.HasPrefix(res.Header().Get("Set-Cookie"), "my_session=") {
+		t.Error("Touched session was not saved:", res.Header().Get("Set-Cookie"))
+	}
+}
+
+func Test_SessionsTouchManaged(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewInstrumentedStore(NewMemoryStore(0))
+	m.Use(Sessions(store))
+
+	m.Get("/login", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+	m.Get("/heartbeat", func(session Session) string {
+		session.Touch("my_session")
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/heartbeat", nil)
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+
+	if store.Stats().Saves != 1 {
+		t.Error("Touched session was saved again")
+	}
+	if strings.SplitN(res.Header().Get("Set-Cookie"), ";", 2)[0] != strings.SplitN(cookie, ";", 2)[0] {
+		t.Error("Cookie was not renewed:", res.Header().Get("Set-Cookie"))
+	}
+}
@@ -0,0 +1,142 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+func init() {
+	gob.Register(shadow{})
+}
+
+// shadowVersionKey holds the number of saves of a session, so that a shadow
+// cookie older than the stored session is never applied.
+const shadowVersionKey metadataKey = "shadow_version"
+
+// shadow is the snapshot mirrored into the shadow cookie, taken from version
+// Version of session ID at Saved. Dirty is set while the snapshot holds
+// changes the backing store hasn't accepted.
+type shadow struct {
+	ID      string
+	Version int64
+	Saved   time.Time
+	Dirty   bool
+	Values  map[string]interface{}
+}
+
+// NewShadowStore returns a Store that mirrors the given critical keys of every
+// session into a signed "<name>_shadow" cookie. When the wrapped store can't
+// be reached, sessions are rebuilt from that cookie instead of starting
+// empty, and changes made meanwhile are reconciled into the store once it is
+// back. The cookie is only used for the session and version it was taken
+// from, and for no longer than the MaxAge of the session, so replaying an
+// old one has no effect. Sessions are only rebuilt over server-side stores,
+// which tell the ID of the session cookie.
+//
+// keyPairs sign, and optionally encrypt, the shadow cookie; they follow the
+// same rules as for NewCookieStore. Keep the critical keys small, such as a
+// user ID or cart reference, as they count against the cookie size limit.
+func NewShadowStore(store Store, keys []string, keyPairs ...[]byte) Store {
+	return &shadowStore{
+		Store:  store,
+		keys:   keys,
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+	}
+}
+
+type shadowStore struct {
+	Store
+	keys   []string
+	codecs []securecookie.Codec
+}
+
+func (st *shadowStore) Unwrap() Store {
+	return st.Store
+}
+
+func (st *shadowStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := st.Store.Get(r, name)
+	if s == nil {
+		return s, err
+	}
+
+	snap, ok := st.read(r, name)
+	if !ok || snap.ID == "" || time.Since(snap.Saved) > sessionTTL(s.Options) {
+		return s, err
+	}
+	stored, _ := s.Values[shadowVersionKey].(int64)
+	switch {
+	case err != nil:
+		if !unreachable(err) {
+			return s, err
+		}
+		// without the ID of the session cookie, a snapshot of another
+		// session can't be told apart
+		if reader, ok := StoreAs[cookieIDReader](st.Store); !ok || reader.cookieID(r, name) != snap.ID {
+			return s, err
+		}
+		// the store is unreachable, go on with the snapshot
+		s.ID = snap.ID
+		s.IsNew = false
+	case snap.Dirty && snap.ID == s.ID && snap.Version > stored:
+		// the store missed changes made while it was unreachable
+	default:
+		return s, err
+	}
+
+	for k, v := range snap.Values {
+		s.Values[k] = v
+	}
+	s.Values[shadowVersionKey] = snap.Version
+	return s, nil
+}
+
+func (st *shadowStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	version, _ := s.Values[shadowVersionKey].(int64)
+	s.Values[shadowVersionKey] = version + 1
+	err := st.Store.Save(r, w, s)
+
+	snap := shadow{ID: s.ID, Version: version + 1, Saved: time.Now(), Dirty: err != nil, Values: make(map[string]interface{})}
+	for _, k := range st.keys {
+		if v, ok := s.Values[k]; ok {
+			snap.Values[k] = v
+		}
+	}
+
+	encoded, encErr := securecookie.EncodeMulti(st.cookieName(s.Name()), snap, st.codecs...)
+	if encErr != nil {
+		if err == nil {
+			err = encErr
+		}
+		return err
+	}
+
+	cookie := &http.Cookie{Name: st.cookieName(s.Name()), Value: encoded, Path: "/"}
+	if o := s.Options; o != nil {
+		cookie.Path = o.Path
+		cookie.Domain = o.Domain
+		cookie.MaxAge = o.MaxAge
+		cookie.Secure = o.Secure
+		cookie.HttpOnly = o.HttpOnly
+	}
+	http.SetCookie(w, cookie)
+	return err
+}
+
+func (st *shadowStore) read(r *http.Request, name string) (shadow, bool) {
+	var snap shadow
+	c, err := r.Cookie(st.cookieName(name))
+	if err != nil {
+		return snap, false
+	}
+	err = securecookie.DecodeMulti(st.cookieName(name), c.Value, &snap, st.codecs...)
+	return snap, err == nil
+}
+
+func (st *shadowStore) cookieName(name string) string {
+	return name + "_shadow"
+}
@@ -0,0 +1,146 @@
+package sessions
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+// flakyStore wraps a Store and fails every operation with err while it is
+// set.
+type flakyStore struct {
+	Store
+	err error
+}
+
+func (f *flakyStore) Unwrap() Store {
+	return f.Store
+}
+
+func (f *flakyStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return f.New(r, name)
+}
+
+func (f *flakyStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	if f.err != nil {
+		return sessions.NewSession(f, name), f.err
+	}
+	return f.Store.New(r, name)
+}
+
+func (f *flakyStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.Store.Save(r, w, s)
+}
+
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+// withCookies returns a request carrying the cookies of req updated with
+// those set in res.
+func withCookies(req *http.Request, res *httptest.ResponseRecorder) *http.Request {
+	cookies := make(map[string]string)
+	for _, c := range req.Cookies() {
+		cookies[c.Name] = c.Value
+	}
+	for _, c := range res.Result().Cookies() {
+		cookies[c.Name] = c.Value
+	}
+	next, _ := http.NewRequest("GET", "/", nil)
+	for name, value := range cookies {
+		next.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	return next
+}
+
+func Test_ShadowStore(t *testing.T) {
+	inner := &flakyStore{Store: NewMemoryStore(0)}
+	store := NewShadowStore(inner, []string{"user"}, []byte("secret123"))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.Get(req, "my_session")
+	s.Values["user"] = "bob"
+	s.Values["theme"] = "dark"
+	res := httptest.NewRecorder()
+	store.Save(req, res, s)
+	req = withCookies(req, res)
+
+	inner.err = errRefused
+	s, err := store.Get(req, "my_session")
+	if err != nil || s.Values["user"] != "bob" {
+		t.Error("Session was not rebuilt from the shadow cookie:", s.Values, err)
+	}
+	if s.Values["theme"] != nil {
+		t.Error("Non-critical key leaked into the shadow cookie")
+	}
+
+	inner.err = errors.New("securecookie: the value is not valid")
+	if s, err := store.Get(req, "my_session"); err == nil || s.Values["user"] != nil {
+		t.Error("Shadow cookie was applied on a decode error:", s.Values, err)
+	}
+}
+
+func Test_ShadowStoreReconcile(t *testing.T) {
+	inner := &flakyStore{Store: NewMemoryStore(0)}
+	store := NewShadowStore(inner, []string{"user"}, []byte("secret123"))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.Get(req, "my_session")
+	s.Values["user"] = "bob"
+	res := httptest.NewRecorder()
+	store.Save(req, res, s)
+	req = withCookies(req, res)
+
+	// changed while the store is down
+	inner.err = errRefused
+	s, _ = store.Get(req, "my_session")
+	s.Values["user"] = "alice"
+	res = httptest.NewRecorder()
+	if err := store.Save(req, res, s); err == nil {
+		t.Fatal("Save succeeded while the store was down")
+	}
+	dirty := withCookies(req, res)
+
+	inner.err = nil
+	s, err := store.Get(dirty, "my_session")
+	if err != nil || s.Values["user"] != "alice" {
+		t.Error("Changes were not reconciled:", s.Values, err)
+	}
+	s.Values["user"] = "carol"
+	res = httptest.NewRecorder()
+	store.Save(dirty, res, s)
+
+	// the dirty cookie is older than the stored session now
+	s, _ = store.Get(dirty, "my_session")
+	if s.Values["user"] != "carol" {
+		t.Error("Replayed shadow cookie was applied:", s.Values)
+	}
+	if strings.Contains(res.Header().Get("Set-Cookie"), "carol") {
+		t.Error("Shadow cookie is not encoded")
+	}
+}
+
+func Test_ShadowStoreWithoutIDs(t *testing.T) {
+	inner := &flakyStore{Store: NewCookieStore([]byte("secret123"))}
+	store := NewShadowStore(inner, []string{"user"}, []byte("secret123"))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.Get(req, "my_session")
+	s.ID = "abc"
+	s.Values["user"] = "bob"
+	res := httptest.NewRecorder()
+	store.Save(req, res, s)
+	req = withCookies(req, res)
+
+	inner.err = errRefused
+	if s, err := store.Get(req, "my_session"); err == nil || s.Values["user"] != nil {
+		t.Error("Shadow cookie was applied without checking the session ID:", s.Values, err)
+	}
+}
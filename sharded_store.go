@@ -0,0 +1,89 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// NewShardedStore returns a Store spreading sessions over shards, e.g.
+// several Redis instances, by consistent hashing of their IDs with hash, or
+// CRC-32 if hash is nil. Adding a shard only moves the sessions of its
+// share of the ring; moved sessions start over.
+//
+// The shards must be server-side stores. The Redis store and the stores of
+// this package are routed directly by the ID in the cookie; for other
+// stores the shards are asked in turn.
+func NewShardedStore(shards []Store, hash func([]byte) uint32) (Store, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("sessions: NewShardedStore needs at least one shard")
+	}
+	names := make([]string, len(shards))
+	for i := range shards {
+		names[i] = "shard" + strconv.Itoa(i)
+	}
+	return &shardedStore{shards: shards, ring: NewRing(names, hash)}, nil
+}
+
+type shardedStore struct {
+	shards []Store
+	ring   *Ring
+}
+
+// cookieIDReader is implemented by stores able to tell the session ID held
+// in the cookie of a request without loading the session.
+type cookieIDReader interface {
+	cookieID(r *http.Request, name string) string
+}
+
+func (sh *shardedStore) Delete(id string) error {
+	return deleteAll(id, sh.shards[sh.ring.Pick(id)])
+}
+
+func (sh *shardedStore) Exists(id string) (bool, error) {
+	return existsAny(id, sh.shards[sh.ring.Pick(id)])
+}
+
+func (sh *shardedStore) Touch(id string, ttl time.Duration) error {
+	return touchAll(id, ttl, sh.shards[sh.ring.Pick(id)])
+}
+
+func (sh *shardedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(sh, name)
+}
+
+func (sh *shardedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	if reader, ok := sh.shards[0].(cookieIDReader); ok {
+		if id := reader.cookieID(r, name); id != "" {
+			return sh.shards[sh.ring.Pick(id)].New(r, name)
+		}
+		return sh.shards[0].New(r, name)
+	}
+
+	var first *sessions.Session
+	var firstErr error
+	for i, shard := range sh.shards {
+		s, err := shard.New(r, name)
+		if err == nil && !s.IsNew {
+			return s, nil
+		}
+		if i == 0 {
+			first, firstErr = s, err
+		}
+	}
+	return first, firstErr
+}
+
+func (sh *shardedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if s.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		s.ID = id
+	}
+	return sh.shards[sh.ring.Pick(s.ID)].Save(r, w, s)
+}
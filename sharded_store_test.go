@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ShardedStore(t *testing.T) {
+	shards := []*memoryStore{}
+	stores := []Store{}
+	for i := 0; i < 3; i++ {
+		s := NewMemoryStore(0).(*memoryStore)
+		defer s.Close()
+		shards = append(shards, s)
+		stores = append(stores, s)
+	}
+	store, _ := NewShardedStore(stores, nil)
+
+	for i := 0; i < 30; i++ {
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, _ := store.New(req, "my_session")
+		s.Values["n"] = i
+		res := httptest.NewRecorder()
+		store.Save(req, res, s)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+		if s, _ := store.New(req, "my_session"); s.Values["n"] != i {
+			t.Fatal("Session was not routed back to its shard")
+		}
+	}
+
+	for i, s := range shards {
+		if s.Stats().Entries == 0 {
+			t.Error("Shard received no sessions:", i)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package sessions
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-martini/martini"
+)
+
+// codecVersion is bumped whenever the way session values are serialized
+// changes incompatibly.
+const codecVersion = 1
+
+// SharedConfig bundles everything multiple services behind one domain must
+// agree on to read each other's sessions. Build it from the same source in
+// every service, and compare fingerprints with VerifyShared.
+//
+//	shared := sessions.SharedConfig{
+//		Name:     "my_session",
+//		Options:  sessions.Options{Domain: ".example.com", Path: "/", Secure: true, HttpOnly: true},
+//		KeyPairs: [][]byte{[]byte(os.Getenv("SESSION_KEY"))},
+//		Types:    []interface{}{User{}},
+//	}
+//	m.Use(sessions.Sessions(shared.CookieStore()))
+//	m.Get("/_sessions/fingerprint", shared.FingerprintHandler())
+type SharedConfig struct {
+	// Name is the session name, which is also the cookie name.
+	Name    string
+	Options Options
+	// KeyPairs are the authentication and encryption keys, see
+	// NewCookieStore.
+	KeyPairs [][]byte
+	// Backend describes the shared server-side store, e.g.
+	// "redis://sessions.internal:6379/2". Leave it empty for cookie sessions.
+	Backend string
+	// Types are the custom types stored in sessions. They are registered
+	// with gob so every service decodes them the same way.
+	Types []interface{}
+}
+
+// CookieStore returns a CookieStore using the shared keys and cookie
+// settings, and registers the shared types.
+func (c SharedConfig) CookieStore() CookieStore {
+	c.register()
+	store := NewCookieStore(c.KeyPairs...)
+	store.Options(c.Options)
+	return store
+}
+
+// Configure applies the shared cookie settings to a server-side store, and
+// registers the shared types.
+func (c SharedConfig) Configure(store interface{ Options(Options) }) {
+	c.register()
+	store.Options(c.Options)
+}
+
+// Fingerprint returns a digest of the configuration. Keys only contribute
+// their hash, so the fingerprint can be exposed safely.
+func (c SharedConfig) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "codec=%d\nname=%s\nbackend=%s\n", codecVersion, c.Name, c.Backend)
+	fmt.Fprintf(h, "options=%+v\n", c.Options)
+	for _, k := range c.KeyPairs {
+		sum := sha256.Sum256(k)
+		fmt.Fprintf(h, "key=%x\n", sum)
+	}
+
+	types := make([]string, len(c.Types))
+	for i, t := range c.Types {
+		types[i] = reflect.TypeOf(t).String()
+	}
+	sort.Strings(types)
+	fmt.Fprintf(h, "types=%s\n", strings.Join(types, ","))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FingerprintHandler returns a handler serving the fingerprint as plain
+// text, to be mounted on an internal route and queried by VerifyShared.
+func (c SharedConfig) FingerprintHandler() martini.Handler {
+	fp := c.Fingerprint()
+	return func() string {
+		return fp
+	}
+}
+
+// VerifyShared fetches the fingerprints served by FingerprintHandler at each
+// URL and returns an error naming the first service whose fingerprint
+// differs from the local configuration.
+func (c SharedConfig) VerifyShared(client *http.Client, urls ...string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	want := c.Fingerprint()
+	for _, u := range urls {
+		res, err := client.Get(u)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(io.LimitReader(res.Body, 256))
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+		if got := strings.TrimSpace(string(body)); got != want {
+			return fmt.Errorf("sessions: %s has a different session configuration (fingerprint %s, want %s)", u, got, want)
+		}
+	}
+	return nil
+}
+
+func (c SharedConfig) register() {
+	for _, t := range c.Types {
+		gob.Register(t)
+	}
+}
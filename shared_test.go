@@ -0,0 +1,36 @@
+package sessions
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+type sharedUser struct {
+	Name string
+}
+
+func Test_SharedConfig(t *testing.T) {
+	shared := SharedConfig{
+		Name:     "my_session",
+		Options:  Options{Domain: ".example.com", Path: "/"},
+		KeyPairs: [][]byte{[]byte("secret123")},
+		Types:    []interface{}{sharedUser{}},
+	}
+
+	m := martini.Classic()
+	m.Get("/fingerprint", shared.FingerprintHandler())
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	if err := shared.VerifyShared(nil, server.URL+"/fingerprint"); err != nil {
+		t.Error("Identical configuration failed verification:", err)
+	}
+
+	other := shared
+	other.KeyPairs = [][]byte{[]byte("secret456")}
+	if err := other.VerifyShared(nil, server.URL+"/fingerprint"); err == nil {
+		t.Error("Mismatched keys passed verification")
+	}
+}
@@ -0,0 +1,26 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+func (s *session) Snapshot(name string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.values(name)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *session) Restore(name string, snapshot []byte) error {
+	var values map[interface{}]interface{}
+	if err := gob.NewDecoder(bytes.NewReader(snapshot)).Decode(&values); err != nil {
+		return err
+	}
+	s.Clear(name)
+	for key, val := range values {
+		s.Set(name, key, val)
+	}
+	return nil
+}
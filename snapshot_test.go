@@ -0,0 +1,38 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_SessionsSnapshot(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		session.Set("my_session", "step", 1)
+		snapshot, err := session.Snapshot("my_session")
+		if err != nil {
+			t.Fatal("Taking a snapshot failed:", err)
+		}
+
+		session.Set("my_session", "step", 2)
+		session.Set("my_session", "draft", "half done")
+		if err := session.Restore("my_session", snapshot); err != nil {
+			t.Fatal("Restoring the snapshot failed:", err)
+		}
+		if session.Get("my_session", "step") != 1 || session.Get("my_session", "draft") != nil {
+			t.Error("Session was not restored:", session.GetAll("my_session"))
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
@@ -0,0 +1,214 @@
+// Package sqlitestore provides a SQLite based storage for martini-contrib
+// sessions.
+//
+//	store, err := sqlitestore.New("/var/lib/app/sessions.db")
+//	m.Use(sessions.Sessions(store))
+//
+// Importing the package registers the sqlite:// scheme of
+// sessions.OpenStore, e.g. sqlite:///var/lib/app/sessions.db.
+package sqlitestore
+
+import (
+	"database/sql"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	sessions.RegisterScheme("sqlite", open)
+}
+
+func open(u *url.URL, keyPairs ...[]byte) (sessions.Store, error) {
+	return New(u.Host + u.Path)
+}
+
+// Store is an interface that represents a SQLite based storage for Sessions.
+type Store interface {
+	// Store is an embedded interface so that Store can be used as a
+	// session store.
+	sessions.Store
+	// Options sets the default options for each session stored in this
+	// Store.
+	Options(sessions.Options)
+	// Vacuum deletes expired sessions and compacts the database file.
+	Vacuum() error
+	// Close closes the database.
+	Close() error
+}
+
+// New returns a new Store keeping sessions in the SQLite database at path,
+// which is created if it does not exist yet. The driver is pure Go, so no
+// cgo or external service is needed.
+//
+// The database is opened in WAL mode so reads never wait for writes, and
+// writes are serialized by the store.
+func New(path string) (Store, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+		id         TEXT PRIMARY KEY,
+		data       BLOB NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	backend := &sqliteBackend{db: db}
+	return &sqliteStore{sessions.NewServerStore(backend), backend}, nil
+}
+
+type sqliteStore struct {
+	*sessions.ServerStore
+	backend *sqliteBackend
+}
+
+func (s *sqliteStore) Vacuum() error {
+	if err := s.Purge(); err != nil {
+		return err
+	}
+
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+	_, err := s.backend.db.Exec("VACUUM")
+	return err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.backend.db.Close()
+}
+
+type sqliteBackend struct {
+	db *sql.DB
+	// mu serializes writes, SQLite only allows a single writer.
+	mu sync.Mutex
+}
+
+func (b *sqliteBackend) Load(id string) ([]byte, error) {
+	var data []byte
+	err := b.db.QueryRow("SELECT data FROM sessions WHERE id = ? AND expires_at >= ?", id, time.Now().Unix()).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (b *sqliteBackend) Save(id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err := b.db.Exec("INSERT INTO sessions (id, data, expires_at) VALUES (?, ?, ?) "+
+		"ON CONFLICT (id) DO UPDATE SET data = excluded.data, expires_at = excluded.expires_at",
+		id, data, time.Now().Add(ttl).Unix())
+	return err
+}
+
+func (b *sqliteBackend) Swap(id string, old, data []byte, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var res sql.Result
+	var err error
+	now := time.Now()
+	if old == nil {
+		res, err = b.db.Exec("INSERT INTO sessions (id, data, expires_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET data = excluded.data, expires_at = excluded.expires_at "+
+			"WHERE sessions.expires_at < ?",
+			id, data, now.Add(ttl).Unix(), now.Unix())
+	} else {
+		res, err = b.db.Exec("UPDATE sessions SET data = ?, expires_at = ? WHERE id = ? AND data = ? AND expires_at >= ?",
+			data, now.Add(ttl).Unix(), id, old, now.Unix())
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (b *sqliteBackend) Touch(id string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	_, err := b.db.Exec("UPDATE sessions SET expires_at = ? WHERE id = ? AND expires_at >= ?",
+		now.Add(ttl).Unix(), id, now.Unix())
+	return err
+}
+
+func (b *sqliteBackend) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err := b.db.Exec("DELETE FROM sessions WHERE id = ?", id)
+	return err
+}
+
+func (b *sqliteBackend) DeletePrefix(prefix string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err := b.db.Exec("DELETE FROM sessions WHERE substr(id, 1, length(?)) = ?", prefix, prefix)
+	return err
+}
+
+func (b *sqliteBackend) Scan(fn func(id string, data []byte, expires time.Time) error) error {
+	rows, err := b.db.Query("SELECT id, data, expires_at FROM sessions WHERE expires_at >= ?", time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		id      string
+		data    []byte
+		expires int64
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.data, &e.expires); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// fn may write, which waits for readers, so it runs once rows is closed
+	for _, e := range entries {
+		if err := fn(e.id, e.data, time.Unix(e.expires, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *sqliteBackend) PurgeExpired() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rows, err := b.db.Query("DELETE FROM sessions WHERE expires_at < ? RETURNING id", time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,90 @@
+package sqlitestore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+	"github.com/martini-contrib/sessions/storetest"
+)
+
+func Test_SQLiteStore(t *testing.T) {
+	store, err := New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	res := httptest.NewRecorder()
+	if err := store.Save(req, res, s); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(res.Header().Get("Set-Cookie"), "world") {
+		t.Error("Session values were written to the cookie")
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	s, err = store.New(req, "my_session")
+	if err != nil || s.IsNew || s.Values["hello"] != "world" {
+		t.Error("Session was not loaded back:", s.Values, err)
+	}
+
+	if err := store.Vacuum(); err != nil {
+		t.Error("Vacuum failed:", err)
+	}
+
+	opened, err := sessions.OpenStore("sqlite://" + filepath.Join(t.TempDir(), "open.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := opened.(Store); !ok {
+		t.Errorf("OpenStore returned a %T for a sqlite DSN", opened)
+	}
+	opened.(Store).Close()
+}
+
+func Test_Conformance(t *testing.T) {
+	dir := t.TempDir()
+	n := 0
+	storetest.Run(t, func() sessions.Store {
+		n++
+		store, err := New(filepath.Join(dir, string(rune('a'+n))+".db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}, storetest.Options{})
+}
+
+func Test_Swap(t *testing.T) {
+	store, err := New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	backend := store.(*sqliteStore).backend
+
+	if ok, err := backend.Swap("a", nil, []byte("1"), time.Minute); !ok || err != nil {
+		t.Fatal("Missing session was not created:", err)
+	}
+	if ok, _ := backend.Swap("a", nil, []byte("2"), time.Minute); ok {
+		t.Error("Existing session was replaced as missing")
+	}
+	if ok, _ := backend.Swap("a", []byte("2"), []byte("3"), time.Minute); ok {
+		t.Error("Session was replaced despite other data")
+	}
+	if ok, _ := backend.Swap("a", []byte("1"), []byte("3"), time.Minute); !ok {
+		t.Error("Session was not replaced")
+	}
+	if data, _ := backend.Load("a"); string(data) != "3" {
+		t.Error("Unexpected data:", string(data))
+	}
+}
@@ -0,0 +1,104 @@
+package sessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-martini/martini"
+)
+
+// KeyNotWritableError is returned by StateSync.Patch for keys that are not
+// on the writable allowlist.
+type KeyNotWritableError string
+
+func (e KeyNotWritableError) Error() string {
+	return fmt.Sprintf("sessions: key %q is not writable", string(e))
+}
+
+// StateSync exposes a client-safe subset of a session to frontends and
+// accepts patches to an allowlist of keys, so single page apps can sync
+// preferences without an endpoint per key. Only string keys can be synced.
+//
+//	sync := &sessions.StateSync{
+//		Readable: []string{"theme", "locale", "username"},
+//		Writable: []string{"theme", "locale"},
+//	}
+//	m.Get("/session", sync.Handler("my_session"))
+//	m.Patch("/session", sync.Handler("my_session"))
+type StateSync struct {
+	// Readable lists the keys returned by State. Writable keys are always
+	// readable.
+	Readable []string
+	// Writable lists the keys Patch may set or remove.
+	Writable []string
+}
+
+// State returns the readable keys present in the named session.
+func (sy *StateSync) State(s Session, name string) map[string]interface{} {
+	state := make(map[string]interface{})
+	for _, keys := range [][]string{sy.Readable, sy.Writable} {
+		for _, key := range keys {
+			if val := s.Get(name, key); val != nil {
+				state[key] = val
+			}
+		}
+	}
+	return state
+}
+
+// Patch applies patch to the named session. A null value removes the key.
+// Nothing is applied if any key is not writable.
+func (sy *StateSync) Patch(s Session, name string, patch map[string]interface{}) error {
+	for key := range patch {
+		if !contains(sy.Writable, key) {
+			return KeyNotWritableError(key)
+		}
+	}
+	for key, val := range patch {
+		if val == nil {
+			s.Delete(name, key)
+		} else {
+			s.Set(name, key, val)
+		}
+	}
+	return nil
+}
+
+// Handler returns an endpoint for the named session. Every method responds
+// with the state as a JSON object; PATCH, POST and PUT requests first apply
+// the JSON object in the body as a patch. Patches touching keys that are not
+// writable are refused with 403 Forbidden.
+func (sy *StateSync) Handler(name string) martini.Handler {
+	return func(res http.ResponseWriter, r *http.Request, s Session) {
+		switch r.Method {
+		case "PATCH", "POST", "PUT":
+			var patch map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				http.Error(res, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := sy.Patch(s, name, patch); err != nil {
+				http.Error(res, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
+		body, err := json.Marshal(sy.State(s, name))
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res.Header().Set("Content-Type", "application/json")
+		res.Write(body)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
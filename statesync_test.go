@@ -0,0 +1,47 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_StateSync(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	sync := &StateSync{Readable: []string{"username"}, Writable: []string{"theme"}}
+	m.Get("/login", func(session Session) string {
+		session.Set("my_session", "username", "bob")
+		session.Set("my_session", "password", "hunter2")
+		return "OK"
+	})
+	m.Get("/session", sync.Handler("my_session"))
+	m.Patch("/session", sync.Handler("my_session"))
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+	cookie := res.Header().Get("Set-Cookie")
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/session", strings.NewReader(`{"theme":"dark"}`))
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if body := res.Body.String(); body != `{"theme":"dark","username":"bob"}` {
+		t.Error("Unexpected state after patch:", body)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/session", strings.NewReader(`{"username":"eve"}`))
+	req.Header.Set("Cookie", cookie)
+	m.ServeHTTP(res, req)
+	if res.Code != http.StatusForbidden {
+		t.Error("Patch of a read-only key was not refused:", res.Code)
+	}
+}
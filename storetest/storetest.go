@@ -0,0 +1,276 @@
+// Package storetest contains conformance tests for implementations of
+// sessions.Store, checking they behave like the stores bundled with the
+// sessions package.
+//
+//	func TestMyStore(t *testing.T) {
+//		storetest.Run(t, func() sessions.Store {
+//			return NewMyStore(...)
+//		}, storetest.Options{})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gsessions "github.com/gorilla/sessions"
+	"github.com/martini-contrib/sessions"
+)
+
+// errMixedUp reports a session loaded with the values of another one.
+var errMixedUp = errors.New("concurrently saved sessions got mixed up")
+
+// Options tunes the tests to the capabilities of a store.
+type Options struct {
+	// MaxPayload is the size in bytes of the largest session the store
+	// must hold. Defaults to 64KB; cookie based stores should set it below
+	// the 4KB cookie limit.
+	MaxPayload int
+	// ClientSideExpiry is set for stores relying on the browser to drop
+	// the cookie of expired sessions, like the CookieStore. Otherwise the
+	// store itself must stop returning sessions past their MaxAge.
+	ClientSideExpiry bool
+	// SkipExpiry skips the expiry tests, which take two seconds each.
+	SkipExpiry bool
+}
+
+// Run runs all conformance tests as subtests of t. newStore is called for
+// every test and must return an empty store.
+func Run(t *testing.T, newStore func() sessions.Store, opts Options) {
+	if opts.MaxPayload == 0 {
+		opts.MaxPayload = 64 << 10
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) { testRoundTrip(t, newStore()) })
+	t.Run("NewSession", func(t *testing.T) { testNewSession(t, newStore()) })
+	t.Run("LargePayload", func(t *testing.T) { testLargePayload(t, newStore(), opts.MaxPayload) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newStore(), opts.ClientSideExpiry) })
+	t.Run("ConcurrentSaves", func(t *testing.T) { testConcurrentSaves(t, newStore()) })
+	t.Run("Backup", func(t *testing.T) { testBackup(t, newStore()) })
+	if !opts.SkipExpiry {
+		t.Run("Expiry", func(t *testing.T) { testExpiry(t, newStore(), opts.ClientSideExpiry) })
+		t.Run("Touch", func(t *testing.T) { testTouch(t, newStore()) })
+	}
+}
+
+// save saves s and returns a request carrying the cookies set in response.
+func save(t *testing.T, store sessions.Store, r *http.Request, s *gsessions.Session) (*http.Request, *httptest.ResponseRecorder) {
+	res := httptest.NewRecorder()
+	if err := store.Save(r, res, s); err != nil {
+		t.Fatal("Save failed:", err)
+	}
+	next, _ := http.NewRequest("GET", "/", nil)
+	for _, c := range res.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			next.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return next, res
+}
+
+func load(t *testing.T, store sessions.Store, r *http.Request) *gsessions.Session {
+	s, err := store.New(r, "session")
+	if s == nil {
+		t.Fatal("New returned no session:", err)
+	}
+	return s
+}
+
+func testRoundTrip(t *testing.T, store sessions.Store) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	s := load(t, store, r)
+	s.Values["string"] = "value"
+	s.Values["int"] = 42
+	s.Values[7] = []string{"a", "b"}
+	s.AddFlash("flash")
+
+	r, _ = save(t, store, r, s)
+	s = load(t, store, r)
+	if s.IsNew {
+		t.Fatal("Saved session is new when loaded")
+	}
+	if s.Values["string"] != "value" || s.Values["int"] != 42 {
+		t.Error("Values were not round-tripped:", s.Values)
+	}
+	if v, ok := s.Values[7].([]string); !ok || len(v) != 2 {
+		t.Error("Non-string keys were not round-tripped:", s.Values)
+	}
+	if f := s.Flashes(); len(f) != 1 || f[0] != "flash" {
+		t.Error("Flashes were not round-tripped:", f)
+	}
+
+	s, err := store.Get(r, "session")
+	if err != nil || s.Values["string"] != "value" {
+		t.Error("Get does not return the saved session:", err)
+	}
+}
+
+func testNewSession(t *testing.T, store sessions.Store) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	s, err := store.New(r, "session")
+	if err != nil || s == nil || !s.IsNew || len(s.Values) != 0 {
+		t.Error("Request without cookie did not yield an empty new session:", err)
+	}
+
+	r.AddCookie(&http.Cookie{Name: "session", Value: "forged"})
+	s, _ = store.New(r, "session")
+	if s == nil || !s.IsNew || len(s.Values) != 0 {
+		t.Error("Forged cookie did not yield an empty new session")
+	}
+}
+
+func testLargePayload(t *testing.T, store sessions.Store, size int) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	s := load(t, store, r)
+	// roughly size bytes once encoded
+	blob := strings.Repeat("x", size-256)
+	s.Values["blob"] = blob
+
+	r, _ = save(t, store, r, s)
+	if s := load(t, store, r); s.Values["blob"] != blob {
+		t.Errorf("Session of %d bytes was not round-tripped", size)
+	}
+}
+
+func testDelete(t *testing.T, store sessions.Store, clientSide bool) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	s := load(t, store, r)
+	s.Values["user"] = "bob"
+	saved, _ := save(t, store, r, s)
+
+	s = load(t, store, saved)
+	s.Options.MaxAge = -1
+	_, res := save(t, store, saved, s)
+
+	expired := false
+	for _, c := range res.Result().Cookies() {
+		if c.Name == "session" && c.MaxAge < 0 {
+			expired = true
+		}
+	}
+	if !expired {
+		t.Error("Deleting a session did not expire its cookie")
+	}
+	if clientSide {
+		return
+	}
+	// a stolen cookie must not bring the session back
+	if s := load(t, store, saved); !s.IsNew || s.Values["user"] != nil {
+		t.Error("Deleted session can still be loaded")
+	}
+}
+
+func testConcurrentSaves(t *testing.T, store sessions.Store) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r, _ := http.NewRequest("GET", "/", nil)
+			s, _ := store.New(r, "session")
+			s.Values["n"] = i
+			res := httptest.NewRecorder()
+			if err := store.Save(r, res, s); err != nil {
+				errs <- err
+				return
+			}
+
+			next, _ := http.NewRequest("GET", "/", nil)
+			for _, c := range res.Result().Cookies() {
+				next.AddCookie(c)
+			}
+			if s, _ := store.New(next, "session"); s == nil || s.Values["n"] != i {
+				errs <- errMixedUp
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error("Concurrent saves:", err)
+	}
+}
+
+func testExpiry(t *testing.T, store sessions.Store, clientSide bool) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	s := load(t, store, r)
+	s.Values["user"] = "bob"
+	s.Options.MaxAge = 1
+	r, res := save(t, store, r, s)
+
+	for _, c := range res.Result().Cookies() {
+		if c.Name == "session" && c.MaxAge != 1 {
+			t.Error("Cookie does not carry the MaxAge of the session:", c.MaxAge)
+		}
+	}
+	if clientSide {
+		return
+	}
+
+	time.Sleep(2100 * time.Millisecond)
+	if s := load(t, store, r); !s.IsNew || s.Values["user"] != nil {
+		t.Error("Expired session can still be loaded")
+	}
+}
+
+// testTouch checks that ManagedStore.Touch extends the expiry of a session
+// and keeps its values.
+func testTouch(t *testing.T, store sessions.Store) {
+	ms, ok := sessions.StoreAs[sessions.ManagedStore](store)
+	if !ok {
+		t.Skip("store is not a ManagedStore")
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	s := load(t, store, r)
+	s.Values["user"] = "bob"
+	s.Options.MaxAge = 1
+	r, _ = save(t, store, r, s)
+
+	if err := ms.Touch(s.ID, time.Minute); err != nil {
+		t.Fatal("Touch failed:", err)
+	}
+	time.Sleep(2100 * time.Millisecond)
+	if s := load(t, store, r); s.IsNew || s.Values["user"] != "bob" {
+		t.Error("Touched session expired with its old MaxAge")
+	}
+}
+
+// testBackup checks that a session deleted after a backup is loaded again
+// once the backup is restored.
+func testBackup(t *testing.T, store sessions.Store) {
+	bs, ok := sessions.StoreAs[sessions.BackupStore](store)
+	if !ok {
+		t.Skip("store is not a BackupStore")
+	}
+	ms, ok := sessions.StoreAs[sessions.ManagedStore](store)
+	if !ok {
+		t.Skip("store is not a ManagedStore")
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	s := load(t, store, r)
+	s.Values["user"] = "bob"
+	r, _ = save(t, store, r, s)
+
+	var buf bytes.Buffer
+	if err := bs.Backup(&buf); err != nil {
+		t.Fatal("Backup failed:", err)
+	}
+	if err := ms.Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Restore(&buf); err != nil {
+		t.Fatal("Restore failed:", err)
+	}
+	if s := load(t, store, r); s.IsNew || s.Values["user"] != "bob" {
+		t.Error("Session was not restored:", s.Values)
+	}
+}
@@ -0,0 +1,71 @@
+package storetest
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+)
+
+func TestCookieStore(t *testing.T) {
+	Run(t, func() sessions.Store {
+		return sessions.NewCookieStore([]byte("secret123"))
+	}, Options{MaxPayload: 2048, ClientSideExpiry: true})
+}
+
+func TestMemoryStore(t *testing.T) {
+	Run(t, func() sessions.Store {
+		return sessions.NewMemoryStore(0)
+	}, Options{})
+}
+
+func TestFilesystemStore(t *testing.T) {
+	Run(t, func() sessions.Store {
+		store, err := sessions.NewFilesystemStore(t.TempDir(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}, Options{})
+}
+
+// TestRedisStore runs against the server at SESSIONS_TEST_REDIS, e.g.
+//
+//	SESSIONS_TEST_REDIS=localhost:6379 go test
+func TestRedisStore(t *testing.T) {
+	addr := os.Getenv("SESSIONS_TEST_REDIS")
+	if addr == "" {
+		t.Skip("SESSIONS_TEST_REDIS is not set")
+	}
+	n := 0
+	Run(t, func() sessions.Store {
+		n++
+		store, err := sessions.NewRedisStore(addr, "", 0, "sessions_test_"+strconv.Itoa(n)+"_", []byte("secret123"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}, Options{})
+}
+
+func TestRedisBuckets(t *testing.T) {
+	addr := os.Getenv("SESSIONS_TEST_REDIS")
+	if addr == "" {
+		t.Skip("SESSIONS_TEST_REDIS is not set")
+	}
+	store, err := sessions.NewRedisStore(addr, "", 0, "sessions_test_buckets_", []byte("secret123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buckets := store.(sessions.BucketStore)
+	limit := sessions.Limit{Rate: 1, Per: time.Hour, Burst: 2}
+	key := "test:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	for i, want := range []bool{true, true, false} {
+		if ok, err := buckets.Take(key, limit); err != nil || ok != want {
+			t.Errorf("Take %d = %v, %v, want %v", i, ok, err, want)
+		}
+	}
+}
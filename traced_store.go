@@ -0,0 +1,68 @@
+package sessions
+
+import (
+	"net/http"
+
+	"github.com/gorilla/sessions"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracedStore returns a Store starting a span with tracer for every
+// operation on store, as a child of the span in the request context. Spans
+// carry the session name, the payload size of saves and backend, a name
+// for the kind of store such as "redis".
+func NewTracedStore(store Store, tracer trace.Tracer, backend string) Store {
+	return &tracedStore{Store: store, tracer: tracer, backend: backend}
+}
+
+type tracedStore struct {
+	Store
+	tracer  trace.Tracer
+	backend string
+}
+
+func (t *tracedStore) Unwrap() Store {
+	return t.Store
+}
+
+func (t *tracedStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	span := t.start(r, "sessions.Get", name)
+	s, err := t.Store.Get(r, name)
+	endSpan(span, err)
+	return s, err
+}
+
+func (t *tracedStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	span := t.start(r, "sessions.New", name)
+	s, err := t.Store.New(r, name)
+	endSpan(span, err)
+	return s, err
+}
+
+func (t *tracedStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	span := t.start(r, "sessions.Save", s.Name())
+	if size, err := encodedSize(s.Values); err == nil {
+		span.SetAttributes(attribute.Int("session.payload_size", size))
+	}
+	err := t.Store.Save(r, w, s)
+	endSpan(span, err)
+	return err
+}
+
+func (t *tracedStore) start(r *http.Request, op, name string) trace.Span {
+	_, span := t.tracer.Start(r.Context(), op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("session.name", name),
+		attribute.String("session.backend", t.backend),
+	))
+	return span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
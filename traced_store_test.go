@@ -0,0 +1,35 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_TracedStore(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	store := NewTracedStore(NewCookieStore([]byte("secret123")), provider.Tracer("sessions"), "cookie")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, "my_session")
+	s.Values["hello"] = "world"
+	store.Save(req, httptest.NewRecorder(), s)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "sessions.New" || spans[1].Name() != "sessions.Save" {
+		t.Fatal("Unexpected spans:", spans)
+	}
+	found := false
+	for _, attr := range spans[1].Attributes() {
+		if attr.Key == "session.payload_size" && attr.Value.AsInt64() > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Save span lacks the payload size")
+	}
+}
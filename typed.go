@@ -0,0 +1,34 @@
+package sessions
+
+// Get returns the value of the named session associated to key as a T. If
+// the key is missing or holds another type, it returns the zero T and false.
+//
+//	cart, ok := sessions.Get[[]string](session, "my_session", "cart")
+func Get[T any](s Session, name string, key interface{}) (T, bool) {
+	val, ok := s.Get(name, key).(T)
+	return val, ok
+}
+
+// Set associates val to key in the named session. It only differs from
+// Session.Set in pinning the type of val, so that a later Get with the
+// same T is known to succeed.
+func Set[T any](s Session, name string, key interface{}, val T) {
+	s.Set(name, key, val)
+}
+
+// FlashesAs returns the flash messages of the named session that are of type
+// T and removes all flash messages of the category, like Session.Flashes.
+// Flashing custom types requires registering them with gob.
+//
+//	session.AddFlash("my_session", FormState{Values: form, Errors: errs}, "form")
+//	...
+//	states := sessions.FlashesAs[FormState](session, "my_session", "form")
+func FlashesAs[T any](s Session, name string, vars ...string) []T {
+	var flashes []T
+	for _, f := range s.Flashes(name, vars...) {
+		if v, ok := f.(T); ok {
+			flashes = append(flashes, v)
+		}
+	}
+	return flashes
+}
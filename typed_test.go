@@ -0,0 +1,73 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_TypedAccessors(t *testing.T) {
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/", func(session Session) string {
+		Set(session, "my_session", "cart", []string{"apples"})
+
+		if cart, ok := Get[[]string](session, "my_session", "cart"); !ok || cart[0] != "apples" {
+			t.Error("Typed value was not returned")
+		}
+		if n, ok := Get[int](session, "my_session", "cart"); ok || n != 0 {
+			t.Error("Mismatched type was accepted")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
+
+type typedFormState struct {
+	Name   string
+	Errors []string
+}
+
+func Test_FlashesAs(t *testing.T) {
+	gob.Register(typedFormState{})
+
+	m := martini.Classic()
+
+	store := NewCookieStore([]byte("secret123"))
+	m.Use(Sessions(store))
+
+	m.Get("/set", func(session Session) string {
+		session.AddFlash("my_session", typedFormState{Name: "bob", Errors: []string{"email is missing"}}, "form")
+		session.AddFlash("my_session", "not a form", "form")
+		return "OK"
+	})
+
+	m.Get("/show", func(session Session) string {
+		states := FlashesAs[typedFormState](session, "my_session", "form")
+		if len(states) != 1 || states[0].Name != "bob" || states[0].Errors[0] != "email is missing" {
+			t.Error("Typed flash was not returned:", states)
+		}
+		if len(session.Flashes("my_session", "form")) != 0 {
+			t.Error("Flashes were not consumed")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/set", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/show", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+}
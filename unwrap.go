@@ -0,0 +1,71 @@
+package sessions
+
+import (
+	"time"
+)
+
+// Unwrapper is implemented by stores wrapping another store, such as the
+// one of NewCachedStore, so that the capabilities of the wrapped store stay
+// reachable through StoreAs.
+type Unwrapper interface {
+	// Unwrap returns the wrapped store.
+	Unwrap() Store
+}
+
+// StoreAs returns the first of store and the stores it wraps that
+// implements T, e.g. to reach the Cleaner or HealthChecker of a store
+// wrapped by decorators:
+//
+//	if c, ok := sessions.StoreAs[sessions.Cleaner](store); ok {
+//		defer c.StartCleanup(time.Minute)()
+//	}
+func StoreAs[T any](store Store) (T, bool) {
+	for store != nil {
+		if t, ok := store.(T); ok {
+			return t, true
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// The helpers below implement ManagedStore for stores combining several
+// stores. Stores unable to manage sessions are skipped.
+
+func deleteAll(id string, stores ...Store) error {
+	for _, store := range stores {
+		if ms, ok := StoreAs[ManagedStore](store); ok {
+			if err := ms.Delete(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func existsAny(id string, stores ...Store) (bool, error) {
+	for _, store := range stores {
+		if ms, ok := StoreAs[ManagedStore](store); ok {
+			if found, err := ms.Exists(id); err != nil || found {
+				return found, err
+			}
+		}
+	}
+	return false, nil
+}
+
+func touchAll(id string, ttl time.Duration, stores ...Store) error {
+	for _, store := range stores {
+		if ms, ok := StoreAs[ManagedStore](store); ok {
+			if err := ms.Touch(id, ttl); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
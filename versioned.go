@@ -0,0 +1,128 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+func init() {
+	gob.Register(versioned{})
+}
+
+// Migration upgrades the fields of a stored struct by one version, e.g. by
+// renaming or converting fields. It modifies fields in place.
+type Migration func(fields map[string]interface{}) error
+
+// versioned is the stored form of a struct saved with SetVersioned. Fields
+// hold the exported fields by name, so they can be migrated even after the
+// struct definition changed.
+type versioned struct {
+	Type    string
+	Version int
+	Fields  map[string]interface{}
+}
+
+type versionInfo struct {
+	version    int
+	migrations map[int]Migration
+}
+
+var (
+	versionsMu sync.RWMutex
+	versions   = make(map[reflect.Type]versionInfo)
+)
+
+// RegisterVersion declares the current version of the struct type of value
+// and the migrations bringing older stored versions up to date:
+// migrations[n] upgrades version n to n+1.
+//
+//	sessions.RegisterVersion(Profile{}, 2, map[int]sessions.Migration{
+//		1: func(f map[string]interface{}) error {
+//			f["DisplayName"] = f["Name"]
+//			delete(f, "Name")
+//			return nil
+//		},
+//	})
+func RegisterVersion(value interface{}, version int, migrations map[int]Migration) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	versions[reflect.TypeOf(value)] = versionInfo{version: version, migrations: migrations}
+}
+
+// SetVersioned stores the struct v in the named session, tagged with the
+// version registered for its type. Field values of custom types must be
+// registered with gob.
+func SetVersioned(s Session, name string, key interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Struct {
+		return errors.New("sessions: SetVersioned expects a struct")
+	}
+
+	versionsMu.RLock()
+	info := versions[rv.Type()]
+	versionsMu.RUnlock()
+
+	fields := make(map[string]interface{})
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Type().Field(i); f.PkgPath == "" {
+			fields[f.Name] = rv.Field(i).Interface()
+		}
+	}
+	s.Set(name, key, versioned{Type: rv.Type().String(), Version: info.version, Fields: fields})
+	return nil
+}
+
+// GetVersioned loads the struct stored at key by SetVersioned into dst, a
+// pointer to a struct, running the registered migrations when the stored
+// version is older than the current one. Migrated values are written back to
+// the session. It reports whether a value was found.
+func GetVersioned(s Session, name string, key interface{}, dst interface{}) (bool, error) {
+	stored, ok := s.Get(name, key).(versioned)
+	if !ok {
+		return false, nil
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return false, errors.New("sessions: GetVersioned expects a pointer to a struct")
+	}
+	rv = rv.Elem()
+
+	versionsMu.RLock()
+	info := versions[rv.Type()]
+	versionsMu.RUnlock()
+
+	if stored.Version > info.version {
+		return false, fmt.Errorf("sessions: stored %s is version %d, newer than %d", stored.Type, stored.Version, info.version)
+	}
+	migrated := stored.Version < info.version
+	for v := stored.Version; v < info.version; v++ {
+		migrate, ok := info.migrations[v]
+		if !ok {
+			return false, fmt.Errorf("sessions: no migration for %s from version %d", stored.Type, v)
+		}
+		if err := migrate(stored.Fields); err != nil {
+			return false, err
+		}
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		val, ok := stored.Fields[f.Name]
+		if f.PkgPath != "" || !ok || val == nil {
+			continue
+		}
+		fv, ok := bindValue(reflect.ValueOf(val), f.Type)
+		if !ok {
+			return false, fmt.Errorf("sessions: field %s of %s holds %T", f.Name, stored.Type, val)
+		}
+		rv.Field(i).Set(fv)
+	}
+
+	if migrated {
+		return true, SetVersioned(s, name, key, rv.Interface())
+	}
+	return true, nil
+}
@@ -0,0 +1,69 @@
+package sessions
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+type profileV2 struct {
+	DisplayName string
+	Age         int
+}
+
+func Test_Versioned(t *testing.T) {
+	m := martini.Classic()
+	m.Use(Sessions(NewCookieStore([]byte("secret123"))))
+
+	m.Get("/", func(session Session) string {
+		// a version 1 profile, saved before Name was renamed
+		session.Set("my_session", "profile", versioned{
+			Type:    "sessions.profile",
+			Version: 1,
+			Fields:  map[string]interface{}{"Name": "Bob", "Age": int64(42)},
+		})
+
+		RegisterVersion(profileV2{}, 2, map[int]Migration{
+			1: func(f map[string]interface{}) error {
+				f["DisplayName"] = f["Name"]
+				delete(f, "Name")
+				return nil
+			},
+		})
+
+		var p profileV2
+		ok, err := GetVersioned(session, "my_session", "profile", &p)
+		if !ok || err != nil || p.DisplayName != "Bob" || p.Age != 42 {
+			t.Error("Unexpected migrated profile:", p, ok, err)
+		}
+		if v := session.Get("my_session", "profile").(versioned).Version; v != 2 {
+			t.Error("Migrated value was not written back, version", v)
+		}
+
+		session.Set("my_session", "other", versioned{
+			Type:    "sessions.profileV2",
+			Version: 2,
+			Fields:  map[string]interface{}{"DisplayName": 7},
+		})
+		if _, err := GetVersioned(session, "my_session", "other", &p); err == nil {
+			t.Error("Number was converted to a string:", p.DisplayName)
+		}
+
+		session.Set("my_session", "other", versioned{
+			Type:    "sessions.profileV2",
+			Version: 2,
+			Fields:  map[string]interface{}{"Age": uint64(math.MaxUint64)},
+		})
+		if _, err := GetVersioned(session, "my_session", "other", &p); err == nil {
+			t.Error("Conversion changing the sign was accepted:", p.Age)
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}
@@ -0,0 +1,184 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// webhookTimeout bounds each delivery made with the default client.
+const webhookTimeout = 10 * time.Second
+
+// WebhookOptions configures a WebhookSink.
+type WebhookOptions struct {
+	// Secret signs every delivery. The hex encoded HMAC-SHA256 of the body
+	// is sent in the X-Sessions-Signature header.
+	Secret []byte
+	// BatchSize is the largest number of events per delivery. Defaults to 100.
+	BatchSize int
+	// FlushInterval is how long events are buffered before a partial batch
+	// is delivered. Defaults to one second.
+	FlushInterval time.Duration
+	// Retries is the number of extra attempts for a failed delivery, with
+	// exponential backoff starting at one second. Defaults to 3.
+	Retries int
+	// Client sends the requests. Defaults to a client timing out after ten
+	// seconds.
+	Client *http.Client
+	// Logger receives delivery failures. Defaults to no logging.
+	Logger *log.Logger
+}
+
+// WebhookSink is an EventSink delivering batches of events as JSON arrays
+// in POST requests to a URL.
+type WebhookSink struct {
+	url  string
+	opts WebhookOptions
+
+	mu     sync.RWMutex
+	closed bool
+	events chan Event
+	done   chan struct{}
+}
+
+// NewWebhookSink returns a WebhookSink posting to url. Call Close to deliver
+// buffered events before shutting down.
+func NewWebhookSink(url string, options ...WebhookOptions) *WebhookSink {
+	opts := WebhookOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	w := &WebhookSink{
+		url:    url,
+		opts:   opts,
+		events: make(chan Event, opts.BatchSize*10),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Send queues e for delivery. Events are dropped when the queue is full,
+// so a slow endpoint can't stall requests, and after Close.
+func (w *WebhookSink) Send(e Event) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.events <- e:
+	default:
+		w.logf("webhook queue full, dropping %s event", e.Type)
+	}
+}
+
+// Close delivers buffered events and stops the sink.
+func (w *WebhookSink) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *WebhookSink) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, w.opts.BatchSize)
+	for {
+		select {
+		case e, ok := <-w.events:
+			if !ok {
+				w.deliver(batch)
+				return
+			}
+			if batch = append(batch, e); len(batch) == w.opts.BatchSize {
+				w.deliver(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.deliver(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *WebhookSink) deliver(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		w.logf("webhook encoding failed: %s", err)
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		if err = w.post(body); err == nil {
+			return
+		}
+		if attempt == w.opts.Retries {
+			w.logf("webhook delivery of %d events failed: %s", len(batch), err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *WebhookSink) post(body []byte) error {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.opts.Secret != nil {
+		mac := hmac.New(sha256.New, w.opts.Secret)
+		mac.Write(body)
+		req.Header.Set("X-Sessions-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := w.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+func (w *WebhookSink) logf(format string, args ...interface{}) {
+	if w.opts.Logger != nil {
+		w.opts.Logger.Printf(errorFormat, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,69 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-martini/martini"
+)
+
+func Test_WebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("hook-secret"))
+		mac.Write(body)
+		if r.Header.Get("X-Sessions-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			t.Error("Invalid webhook signature")
+		}
+
+		var events []Event
+		json.Unmarshal(body, &events)
+		mu.Lock()
+		received = append(received, events...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, WebhookOptions{Secret: []byte("hook-secret")})
+
+	m := martini.Classic()
+	m.Use(Sessions(NewEventStore(NewCookieStore([]byte("secret123")), sink)))
+	m.Get("/login", func(session Session) string {
+		session.Set("my_session", "user", "bob")
+		return "OK"
+	})
+	m.Get("/logout", func(session Session) string {
+		session.Options("my_session", Options{MaxAge: -1})
+		session.Set("my_session", "user", nil)
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/login", nil)
+	m.ServeHTTP(res, req)
+
+	res2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/logout", nil)
+	req2.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	m.ServeHTTP(res2, req2)
+
+	sink.Close()
+	// late events, e.g. from a cleanup, are dropped
+	sink.Send(Event{Type: EventExpired})
+	sink.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0].Type != EventCreated || received[1].Type != EventDestroyed {
+		t.Error("Unexpected events:", received)
+	}
+}
@@ -0,0 +1,156 @@
+// Package wizard keeps the state of multi-step flows, such as checkout or
+// onboarding, in a martini-contrib session.
+//
+//	m.Post("/signup/:step", func(s sessions.Session, p martini.Params, r *http.Request) string {
+//		w := wizard.New(s, "my_session", "signup", wizard.Options{Steps: []string{"account", "profile", "plan"}})
+//		if err := w.Save(p["step"], map[string]interface{}{"value": r.FormValue("value")}); err != nil {
+//			return err.Error()
+//		}
+//		if w.Current() == "" {
+//			data, _ := w.Complete()
+//			...
+//		}
+//	})
+//
+// The collected data expires TTL after the last saved step, so abandoned
+// flows don't linger in the session.
+package wizard
+
+import (
+	"encoding/gob"
+	"errors"
+	"time"
+
+	"github.com/martini-contrib/sessions"
+)
+
+var (
+	// ErrUnknownStep is returned when saving a step that isn't part of the
+	// flow.
+	ErrUnknownStep = errors.New("wizard: unknown step")
+	// ErrStepOrder is returned when saving a step before the steps
+	// preceding it.
+	ErrStepOrder = errors.New("wizard: previous steps are incomplete")
+	// ErrIncomplete is returned by Complete while steps are missing.
+	ErrIncomplete = errors.New("wizard: flow is incomplete")
+)
+
+func init() {
+	gob.Register(state{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Options configures a Wizard.
+type Options struct {
+	// Steps lists the steps of the flow in order.
+	Steps []string
+	// TTL is how long collected data is kept after the last saved step.
+	// Defaults to 30 minutes.
+	TTL time.Duration
+	// Validate, when set, checks the data of a step before it is saved.
+	Validate func(step string, data map[string]interface{}) error
+}
+
+type state struct {
+	Steps   map[string]map[string]interface{}
+	Expires time.Time
+}
+
+// Wizard is a multi-step flow stored in a named session.
+type Wizard struct {
+	session sessions.Session
+	name    string
+	key     string
+	opts    Options
+}
+
+// New returns the state of flow stored in the named session.
+func New(s sessions.Session, name, flow string, options ...Options) *Wizard {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.TTL == 0 {
+		opts.TTL = 30 * time.Minute
+	}
+	return &Wizard{session: s, name: name, key: "_wizard:" + flow, opts: opts}
+}
+
+// Save validates and stores the data of step. Saving a step again replaces
+// its data.
+func (w *Wizard) Save(step string, data map[string]interface{}) error {
+	if !w.known(step) {
+		return ErrUnknownStep
+	}
+	st := w.state()
+	for _, s := range w.opts.Steps {
+		if s == step {
+			break
+		}
+		if _, done := st.Steps[s]; !done {
+			return ErrStepOrder
+		}
+	}
+	if w.opts.Validate != nil {
+		if err := w.opts.Validate(step, data); err != nil {
+			return err
+		}
+	}
+
+	st.Steps[step] = data
+	st.Expires = time.Now().Add(w.opts.TTL)
+	w.session.Set(w.name, w.key, st)
+	return nil
+}
+
+// Step returns the data saved for step.
+func (w *Wizard) Step(step string) (map[string]interface{}, bool) {
+	data, ok := w.state().Steps[step]
+	return data, ok
+}
+
+// Current returns the first step without data, or an empty string once all
+// steps are saved.
+func (w *Wizard) Current() string {
+	st := w.state()
+	for _, s := range w.opts.Steps {
+		if _, done := st.Steps[s]; !done {
+			return s
+		}
+	}
+	return ""
+}
+
+// Complete returns the data of all steps and removes the flow from the
+// session. It fails with ErrIncomplete, leaving the flow untouched, while
+// steps are missing.
+func (w *Wizard) Complete() (map[string]map[string]interface{}, error) {
+	if w.Current() != "" {
+		return nil, ErrIncomplete
+	}
+	st := w.state()
+	w.Reset()
+	return st.Steps, nil
+}
+
+// Reset discards all collected data.
+func (w *Wizard) Reset() {
+	w.session.Delete(w.name, w.key)
+}
+
+func (w *Wizard) known(step string) bool {
+	for _, s := range w.opts.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Wizard) state() state {
+	st, ok := w.session.Get(w.name, w.key).(state)
+	if !ok || time.Now().After(st.Expires) {
+		return state{Steps: make(map[string]map[string]interface{})}
+	}
+	return st
+}
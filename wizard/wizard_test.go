@@ -0,0 +1,48 @@
+package wizard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/sessions"
+)
+
+func Test_Wizard(t *testing.T) {
+	m := martini.Classic()
+	m.Use(sessions.Sessions(sessions.NewCookieStore([]byte("secret123"))))
+
+	m.Get("/", func(s sessions.Session) string {
+		w := New(s, "my_session", "signup", Options{Steps: []string{"account", "plan"}})
+
+		if err := w.Save("plan", map[string]interface{}{"plan": "pro"}); err != ErrStepOrder {
+			t.Error("Expected ErrStepOrder, got", err)
+		}
+		if err := w.Save("billing", nil); err != ErrUnknownStep {
+			t.Error("Expected ErrUnknownStep, got", err)
+		}
+		if _, err := w.Complete(); err != ErrIncomplete {
+			t.Error("Expected ErrIncomplete, got", err)
+		}
+
+		w.Save("account", map[string]interface{}{"email": "bob@example.com"})
+		w.Save("plan", map[string]interface{}{"plan": "pro"})
+		if w.Current() != "" {
+			t.Error("Flow should be complete, current step is", w.Current())
+		}
+
+		data, err := w.Complete()
+		if err != nil || data["plan"]["plan"] != "pro" {
+			t.Error("Unexpected completed data:", data, err)
+		}
+		if w.Current() != "account" {
+			t.Error("Complete did not clear the flow")
+		}
+		return "OK"
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(res, req)
+}